@@ -7,46 +7,63 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
 )
 
 // ConfigItem represents a single configuration item
 type ConfigItem struct {
-	Key   string
-	Value string
-	Label string
-	Tags  map[string]string
+	Key          string
+	Value        string
+	Label        string
+	Tags         map[string]string
+	ContentType  string
+	LastModified time.Time
 }
 
+// KeyVaultReferenceContentType is the content type of Key Vault reference settings
+const KeyVaultReferenceContentType = "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8"
+
 // Client wraps the Azure App Configuration client
 type Client struct {
 	client *azappconfig.Client
+	cloud  Cloud
 }
 
 // NewClient creates a new Azure App Configuration client
 // It first tries access key authentication via APP_CONFIG_CONNECTION_STRING environment variable,
 // then falls back to Azure Identity (managed identity, CLI login, etc.)
+// The connection string is only used when it points at the requested endpoint, so that
+// commands working with two stores do not silently talk to the same one twice;
+// IgnoredConnectionString reports when it is skipped.
 func NewClient(endpoint string) (*Client, error) {
+	return NewClientForCloud(endpoint, PublicCloud)
+}
+
+// NewClientForCloud creates a new Azure App Configuration client for a store in the given cloud.
+// Azure Identity authenticates against the cloud's authority and App Configuration audience,
+// and Key Vault references are recognised by the cloud's vault hosts.
+func NewClientForCloud(endpoint string, azureCloud Cloud) (*Client, error) {
 	var client *azappconfig.Client
 	var err error
 
 	// Try connection string authentication first (for access keys)
-	if connStr := os.Getenv("APP_CONFIG_CONNECTION_STRING"); connStr != "" {
-		client, err = azappconfig.NewClientFromConnectionString(connStr, nil)
+	if connStr := os.Getenv("APP_CONFIG_CONNECTION_STRING"); connStr != "" && connectionStringMatches(connStr, endpoint) {
+		client, err = azappconfig.NewClientFromConnectionString(connStr, clientOptions())
 		if err != nil {
 			return nil, fmt.Errorf("failed to create App Config client from connection string: %w", err)
 		}
 	} else {
 		// Fall back to Azure Identity
-		cred, credErr := azidentity.NewDefaultAzureCredential(nil)
+		cred, credErr := azureCloud.appConfigCredential()
 		if credErr != nil {
-			return nil, fmt.Errorf("failed to create Azure credential: %w", credErr)
+			return nil, credErr
 		}
 
-		client, err = azappconfig.NewClient(endpoint, cred, nil)
+		client, err = azappconfig.NewClient(endpoint, cred, clientOptions())
 		if err != nil {
 			return nil, fmt.Errorf("failed to create App Config client: %w", err)
 		}
@@ -54,9 +71,46 @@ func NewClient(endpoint string) (*Client, error) {
 
 	return &Client{
 		client: client,
+		cloud:  azureCloud,
 	}, nil
 }
 
+// connectionStringMatches reports whether a connection string targets the given endpoint
+func connectionStringMatches(connStr, endpoint string) bool {
+	if endpoint == "" {
+		return true
+	}
+
+	for _, segment := range strings.Split(connStr, ";") {
+		if strings.HasPrefix(segment, "Endpoint=") {
+			return normalizeEndpoint(strings.TrimPrefix(segment, "Endpoint=")) == normalizeEndpoint(endpoint)
+		}
+	}
+
+	return false
+}
+
+// IgnoredConnectionString returns the endpoint of APP_CONFIG_CONNECTION_STRING when it is set
+// but targets another store than endpoint, so a client for endpoint uses Azure Identity instead
+func IgnoredConnectionString(endpoint string) (string, bool) {
+	connStr := os.Getenv("APP_CONFIG_CONNECTION_STRING")
+	if connStr == "" || connectionStringMatches(connStr, endpoint) {
+		return "", false
+	}
+
+	for _, segment := range strings.Split(connStr, ";") {
+		if strings.HasPrefix(segment, "Endpoint=") {
+			return strings.TrimPrefix(segment, "Endpoint="), true
+		}
+	}
+	return "", true
+}
+
+// normalizeEndpoint lowercases an endpoint and strips any trailing slash for comparison
+func normalizeEndpoint(endpoint string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(endpoint)), "/")
+}
+
 // FetchAll retrieves all configuration items from Azure App Config
 func (c *Client) FetchAll(ctx context.Context, labelFilter string) ([]ConfigItem, error) {
 	var items []ConfigItem
@@ -92,6 +146,14 @@ func (c *Client) FetchAll(ctx context.Context, labelFilter string) ([]ConfigItem
 				item.Tags = setting.Tags
 			}
 
+			if setting.ContentType != nil {
+				item.ContentType = *setting.ContentType
+			}
+
+			if setting.LastModified != nil {
+				item.LastModified = *setting.LastModified
+			}
+
 			items = append(items, item)
 		}
 	}
@@ -133,6 +195,14 @@ func (c *Client) FetchByKeys(ctx context.Context, keys []string, labelFilter str
 			item.Tags = setting.Tags
 		}
 
+		if setting.ContentType != nil {
+			item.ContentType = *setting.ContentType
+		}
+
+		if setting.LastModified != nil {
+			item.LastModified = *setting.LastModified
+		}
+
 		items = append(items, item)
 	}
 
@@ -146,7 +216,11 @@ func (c *Client) ApplyChanges(ctx context.Context, changes []ChangeOperation) er
 	for _, change := range changes {
 		switch change.Operation {
 		case "add", "update":
+			// A content type carried over from an export is kept; otherwise it follows the value
 			contentType := c.detectContentType(change.Value)
+			if change.ContentType != "" {
+				contentType = &change.ContentType
+			}
 			actualValue := c.formatValueForStorage(change.Value, contentType)
 			err := c.setSetting(ctx, change.Key, actualValue, change.Label, change.Tags, contentType)
 			if err != nil {
@@ -165,11 +239,12 @@ func (c *Client) ApplyChanges(ctx context.Context, changes []ChangeOperation) er
 
 // ChangeOperation represents a single change to apply
 type ChangeOperation struct {
-	Operation string            // "add", "update", "delete"
-	Key       string
-	Value     string
-	Label     string
-	Tags      map[string]string
+	Operation   string // "add", "update", "delete"
+	Key         string
+	Value       string
+	Label       string
+	Tags        map[string]string
+	ContentType string // empty to detect it from the value
 }
 
 // setSetting creates or updates a setting
@@ -184,8 +259,11 @@ func (c *Client) setSetting(ctx context.Context, key, value, label string, tags
 		options.ContentType = contentType
 	}
 
-	// Note: Tags are not supported in SetSettingOptions
-	// Tags would need to be set via a separate API call if required
+	// Tags are not supported in SetSettingOptions, so they travel on the
+	// context and are added to the request body by settingTagsPolicy
+	if len(tags) > 0 {
+		ctx = context.WithValue(ctx, settingTagsKey{}, tags)
+	}
 
 	_, err := c.client.SetSetting(ctx, key, &value, options)
 	return err
@@ -209,7 +287,7 @@ func (c *Client) DetectContentType(value string) *string {
 func (c *Client) detectContentType(value string) *string {
 	// Check for Key Vault references
 	if c.isKeyVaultReference(value) {
-		contentType := "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8"
+		contentType := KeyVaultReferenceContentType
 		return &contentType
 	}
 
@@ -226,7 +304,7 @@ func (c *Client) isKeyVaultReference(value string) bool {
 	}
 
 	// Check for direct Key Vault URI format
-	if strings.HasPrefix(value, "https://") && strings.Contains(value, "vault.azure.net") {
+	if c.cloud.IsVaultURI(value) {
 		return true
 	}
 
@@ -240,32 +318,30 @@ func (c *Client) FormatValueForStorage(value string, contentType *string) string
 
 // formatValueForStorage formats the value for storage based on content type
 func (c *Client) formatValueForStorage(value string, contentType *string) string {
-	if contentType != nil && *contentType == "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8" {
+	if contentType != nil && *contentType == KeyVaultReferenceContentType {
 		return c.formatKeyVaultReference(value)
 	}
 
 	return value
 }
 
-// formatKeyVaultReference converts various Key Vault reference formats to the standard JSON format
+// formatKeyVaultReference converts the documented Key Vault reference formats (SecretUri,
+// VaultName/SecretName/SecretVersion, direct secret and certificate URIs) to the standard JSON format
 func (c *Client) formatKeyVaultReference(value string) string {
 	var uri string
 
-	// Handle Microsoft.KeyVault format
-	if strings.HasPrefix(value, "@Microsoft.KeyVault(") && strings.HasSuffix(value, ")") {
+	kvValidator := validator.NewValidator()
+	kvValidator.SetVaultSuffixes(c.cloud.vaultSuffixes())
+	if ref, err := kvValidator.ParseKeyVaultReference(value); err == nil {
+		uri = ref.SecretURI()
+	} else if strings.HasPrefix(value, "@Microsoft.KeyVault(") && strings.HasSuffix(value, ")") {
+		// Keep whatever SecretUri an unrecognised reference carries
 		content := strings.TrimPrefix(strings.TrimSuffix(value, ")"), "@Microsoft.KeyVault(")
-		params := make(map[string]string)
-
-		// Parse parameters
 		for _, param := range strings.Split(content, ";") {
-			if parts := strings.SplitN(param, "=", 2); len(parts) == 2 {
-				params[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+			if parts := strings.SplitN(param, "=", 2); len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), "SecretUri") {
+				uri = strings.TrimSpace(parts[1])
 			}
 		}
-
-		if secretURI, ok := params["SecretUri"]; ok {
-			uri = secretURI
-		}
 	} else {
 		// Direct URI format
 		uri = value
@@ -298,7 +374,7 @@ func (c *Client) normalizeRetrievedValue(value string) string {
 	if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
 		var kvRef map[string]interface{}
 		if err := json.Unmarshal([]byte(value), &kvRef); err == nil {
-			if uri, ok := kvRef["uri"].(string); ok && strings.Contains(uri, "vault.azure.net") {
+			if uri, ok := kvRef["uri"].(string); ok && c.cloud.IsVaultURI(uri) {
 				// Convert back to Microsoft.KeyVault format for consistent comparison
 				return fmt.Sprintf("@Microsoft.KeyVault(SecretUri=%s)", uri)
 			}
@@ -311,7 +387,7 @@ func (c *Client) normalizeRetrievedValue(value string) string {
 	}
 
 	// Check if it's a direct Key Vault URI (could be from old storage)
-	if strings.HasPrefix(value, "https://") && strings.Contains(value, "vault.azure.net") {
+	if c.cloud.IsVaultURI(value) {
 		return fmt.Sprintf("@Microsoft.KeyVault(SecretUri=%s)", value)
 	}
 
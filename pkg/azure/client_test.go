@@ -0,0 +1,56 @@
+package azure
+
+import "testing"
+
+func TestClient_FormatKeyVaultReference(t *testing.T) {
+	client := &Client{cloud: PublicCloud}
+	contentType := client.DetectContentType("@Microsoft.KeyVault(VaultName=myvault;SecretName=db)")
+
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"secret uri", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db/v1)", `{"uri":"https://myvault.vault.azure.net/secrets/db/v1"}`},
+		{"vault name", "@Microsoft.KeyVault(VaultName=myvault;SecretName=db;SecretVersion=v1)", `{"uri":"https://myvault.vault.azure.net/secrets/db/v1"}`},
+		{"direct uri", "https://myvault.vault.azure.net/secrets/db", `{"uri":"https://myvault.vault.azure.net/secrets/db"}`},
+		{"certificate uri", "https://myvault.vault.azure.net/certificates/tls/v1", `{"uri":"https://myvault.vault.azure.net/secrets/tls/v1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := client.FormatValueForStorage(tt.value, contentType); result != tt.expected {
+				t.Errorf("FormatValueForStorage() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIgnoredConnectionString(t *testing.T) {
+	const connStr = "Endpoint=https://mystore.azconfig.io;Id=abc;Secret=c2VjcmV0"
+
+	tests := []struct {
+		name        string
+		connStr     string
+		endpoint    string
+		expected    string
+		wantIgnored bool
+	}{
+		{"no connection string", "", "https://mystore.azconfig.io", "", false},
+		{"same store", connStr, "https://mystore.azconfig.io", "", false},
+		{"same store with different case and slash", connStr, "https://MyStore.azconfig.io/", "", false},
+		{"no endpoint requested", connStr, "", "", false},
+		{"other store", connStr, "https://other.azconfig.io", "https://mystore.azconfig.io", true},
+		{"connection string without endpoint", "Id=abc;Secret=c2VjcmV0", "https://other.azconfig.io", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_CONFIG_CONNECTION_STRING", tt.connStr)
+			connEndpoint, ignored := IgnoredConnectionString(tt.endpoint)
+			if connEndpoint != tt.expected || ignored != tt.wantIgnored {
+				t.Errorf("IgnoredConnectionString() = %q, %v, expected %q, %v", connEndpoint, ignored, tt.expected, tt.wantIgnored)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+// Cloud describes the Azure cloud that stores, vaults and identities live in
+type Cloud struct {
+	Name string
+	// AuthorityHost is the Microsoft Entra ID authority used to acquire tokens
+	AuthorityHost string
+	// AppConfigAudience is the token audience for App Configuration; empty means the store endpoint
+	AppConfigAudience string
+	// VaultSuffixes are the host suffixes of Key Vaults in this cloud
+	VaultSuffixes []string
+}
+
+var (
+	// PublicCloud is the global Azure cloud
+	PublicCloud = Cloud{
+		Name:          "public",
+		AuthorityHost: cloud.AzurePublic.ActiveDirectoryAuthorityHost,
+		VaultSuffixes: validator.DefaultVaultSuffixes,
+	}
+
+	// ChinaCloud is Azure operated by 21Vianet
+	ChinaCloud = Cloud{
+		Name:              "china",
+		AuthorityHost:     cloud.AzureChina.ActiveDirectoryAuthorityHost,
+		AppConfigAudience: "https://appconfig.azure.cn",
+		VaultSuffixes:     []string{"vault.azure.cn"},
+	}
+
+	// USGovCloud is Azure US Government
+	USGovCloud = Cloud{
+		Name:              "usgov",
+		AuthorityHost:     cloud.AzureGovernment.ActiveDirectoryAuthorityHost,
+		AppConfigAudience: "https://appconfig.azure.us",
+		VaultSuffixes:     []string{"vault.usgovcloudapi.net"},
+	}
+)
+
+// LookupCloud returns a well-known cloud by name; an empty name is the public cloud
+func LookupCloud(name string) (Cloud, error) {
+	switch strings.ToLower(name) {
+	case "", "public":
+		return PublicCloud, nil
+	case "china":
+		return ChinaCloud, nil
+	case "usgov":
+		return USGovCloud, nil
+	case "custom":
+		return Cloud{}, fmt.Errorf("the custom cloud must be described in the settings file")
+	default:
+		return Cloud{}, fmt.Errorf("invalid cloud %q: expected public, china, usgov or custom", name)
+	}
+}
+
+// NewCustomCloud describes a cloud that is not built in, such as Azure Stack
+func NewCustomCloud(authorityHost, appConfigAudience string, vaultSuffixes []string) (Cloud, error) {
+	if parsed, err := url.Parse(authorityHost); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return Cloud{}, fmt.Errorf("custom cloud requires an https authority host, got %q", authorityHost)
+	}
+	if appConfigAudience != "" {
+		if parsed, err := url.Parse(appConfigAudience); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return Cloud{}, fmt.Errorf("invalid App Configuration audience %q", appConfigAudience)
+		}
+	}
+	if len(vaultSuffixes) == 0 {
+		return Cloud{}, fmt.Errorf("custom cloud requires at least one Key Vault host suffix")
+	}
+
+	if !strings.HasSuffix(authorityHost, "/") {
+		authorityHost += "/"
+	}
+
+	return Cloud{
+		Name:              "custom",
+		AuthorityHost:     authorityHost,
+		AppConfigAudience: appConfigAudience,
+		VaultSuffixes:     vaultSuffixes,
+	}, nil
+}
+
+// IsVaultURI reports whether a value is a Key Vault URI in this cloud
+func (c Cloud) IsVaultURI(value string) bool {
+	return validator.IsVaultURI(value, c.vaultSuffixes())
+}
+
+// vaultSuffixes returns the cloud's vault host suffixes, defaulting to the public cloud
+func (c Cloud) vaultSuffixes() []string {
+	if len(c.VaultSuffixes) == 0 {
+		return PublicCloud.VaultSuffixes
+	}
+	return c.VaultSuffixes
+}
+
+// newCredential creates the Azure Identity credential chain against the cloud's authority
+func (c Cloud) newCredential() (azcore.TokenCredential, error) {
+	options := &azidentity.DefaultAzureCredentialOptions{}
+	if c.AuthorityHost != "" {
+		options.ClientOptions.Cloud = cloud.Configuration{
+			ActiveDirectoryAuthorityHost: c.AuthorityHost,
+			Services:                     map[cloud.ServiceName]cloud.ServiceConfiguration{},
+		}
+	}
+
+	cred, err := azidentity.NewDefaultAzureCredential(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+	return cred, nil
+}
+
+// appConfigCredential returns a credential for App Configuration requests. The SDK scopes
+// tokens to the store endpoint, so sovereign clouds need the scope replaced by their audience.
+func (c Cloud) appConfigCredential() (azcore.TokenCredential, error) {
+	cred, err := c.newCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.AppConfigAudience == "" {
+		return cred, nil
+	}
+	return audienceCredential{
+		TokenCredential: cred,
+		scope:           strings.TrimSuffix(c.AppConfigAudience, "/") + "/.default",
+	}, nil
+}
+
+// audienceCredential requests every token for a fixed scope
+type audienceCredential struct {
+	azcore.TokenCredential
+	scope string
+}
+
+// GetToken implements azcore.TokenCredential
+func (a audienceCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	options.Scopes = []string{a.scope}
+	return a.TokenCredential.GetToken(ctx, options)
+}
@@ -0,0 +1,141 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// kvSetItem is one setting in a file written by az appconfig kv export --profile appconfig/kvset
+type kvSetItem struct {
+	Key         string            `json:"key"`
+	Value       *string           `json:"value"`
+	Label       *string           `json:"label"`
+	ContentType *string           `json:"content_type"`
+	Tags        map[string]string `json:"tags"`
+}
+
+// kvSetFile is the kvset profile: every setting with its label, content type and tags
+type kvSetFile struct {
+	Items []kvSetItem `json:"items"`
+}
+
+// IsKVSet reports whether decoded file content is an export in the kvset profile: an object
+// whose only member is an items list of settings with keys
+func IsKVSet(data interface{}) bool {
+	root, ok := data.(map[string]interface{})
+	if !ok || len(root) != 1 {
+		return false
+	}
+
+	items, ok := root["items"].([]interface{})
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		setting, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := setting["key"].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// KVSetItems converts decoded kvset content into configuration items in file order, keeping
+// labels, tags and content types. Values are normalized the way FetchAll normalizes them,
+// so Key Vault references and feature flags compare equal to the store.
+func KVSetItems(data interface{}, cloud Cloud) ([]ConfigItem, error) {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kvset export: %w", err)
+	}
+
+	var file kvSetFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("invalid kvset export: %w", err)
+	}
+
+	normalizer := &Client{cloud: cloud}
+	items := make([]ConfigItem, 0, len(file.Items))
+	seen := make(map[string]bool, len(file.Items))
+	for i, setting := range file.Items {
+		if setting.Key == "" {
+			return nil, fmt.Errorf("invalid kvset export: item %d has no key", i)
+		}
+
+		item := ConfigItem{Key: setting.Key, Tags: setting.Tags}
+		if setting.Value != nil {
+			item.Value = normalizer.normalizeRetrievedValue(*setting.Value)
+		}
+		if setting.Label != nil {
+			item.Label = *setting.Label
+		}
+		if setting.ContentType != nil {
+			item.ContentType = *setting.ContentType
+		}
+
+		id := item.Key + "\x00" + item.Label
+		if seen[id] {
+			return nil, fmt.Errorf("invalid kvset export: %s is listed twice for label %q", item.Key, item.Label)
+		}
+		seen[id] = true
+
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// FormatKVSet writes configuration items in the kvset profile, as az appconfig kv export
+// --profile appconfig/kvset does, sorted by key and label. Key Vault references are written
+// in their stored JSON form, and settings without a content type get the one they would be
+// stored with when their value needs it.
+func FormatKVSet(items []ConfigItem, cloud Cloud) ([]byte, error) {
+	sorted := make([]ConfigItem, len(items))
+	copy(sorted, items)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Key != sorted[j].Key {
+			return sorted[i].Key < sorted[j].Key
+		}
+		return sorted[i].Label < sorted[j].Label
+	})
+
+	client := &Client{cloud: cloud}
+	file := kvSetFile{Items: make([]kvSetItem, 0, len(sorted))}
+	for _, item := range sorted {
+		setting := kvSetItem{Key: item.Key, Tags: item.Tags}
+		if setting.Tags == nil {
+			setting.Tags = map[string]string{}
+		}
+		if item.Label != "" {
+			label := item.Label
+			setting.Label = &label
+		}
+
+		contentType := item.ContentType
+		if contentType == "" && client.isKeyVaultReference(item.Value) {
+			contentType = *client.detectContentType(item.Value)
+		}
+		if contentType != "" {
+			setting.ContentType = &contentType
+		}
+
+		value := client.formatValueForStorage(item.Value, &contentType)
+		setting.Value = &value
+
+		file.Items = append(file.Items, setting)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return nil, fmt.Errorf("failed to write kvset export: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package azure
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const kvSetExport = `{
+  "items": [
+    {"key": "app.name", "value": "shop", "label": null, "content_type": null, "tags": {}},
+    {"key": "app.name", "value": "shop-dev", "label": "dev", "content_type": "text/plain", "tags": {"team": "web"}},
+    {"key": "db.password", "value": "{\"uri\":\"https://myvault.vault.azure.net/secrets/db\"}", "label": null,
+     "content_type": "application/vnd.microsoft.appconfig.keyvaultref+json;charset=utf-8", "tags": {}}
+  ]
+}`
+
+func decodeExport(t *testing.T, content string) interface{} {
+	t.Helper()
+	var data interface{}
+	if err := json.Unmarshal([]byte(content), &data); err != nil {
+		t.Fatalf("invalid test export: %v", err)
+	}
+	return data
+}
+
+func TestIsKVSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected bool
+	}{
+		{"kvset export", kvSetExport, true},
+		{"empty export", `{"items": []}`, true},
+		{"configuration with items", `{"items": [{"name": "a"}]}`, false},
+		{"items next to other settings", `{"items": [], "app": {}}`, false},
+		{"items as an object", `{"items": {"key": "a"}}`, false},
+		{"top-level list", `[{"key": "a"}]`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := IsKVSet(decodeExport(t, tt.content)); result != tt.expected {
+				t.Errorf("IsKVSet() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKVSetItems(t *testing.T) {
+	items, err := KVSetItems(decodeExport(t, kvSetExport), PublicCloud)
+	if err != nil {
+		t.Fatalf("KVSetItems() error = %v", err)
+	}
+
+	expected := []ConfigItem{
+		{Key: "app.name", Value: "shop", Tags: map[string]string{}},
+		{Key: "app.name", Value: "shop-dev", Label: "dev", ContentType: "text/plain", Tags: map[string]string{"team": "web"}},
+		{Key: "db.password", Value: "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db)",
+			ContentType: KeyVaultReferenceContentType, Tags: map[string]string{}},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("KVSetItems() = %+v, expected %+v", items, expected)
+	}
+}
+
+func TestKVSetItems_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"duplicate key and label", `{"items": [{"key": "a", "label": "dev"}, {"key": "a", "label": "dev"}]}`, `a is listed twice for label "dev"`},
+		{"missing key", `{"items": [{"key": ""}]}`, "item 0 has no key"},
+		{"value of the wrong type", `{"items": [{"key": "a", "value": 5}]}`, "invalid kvset export"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := KVSetItems(decodeExport(t, tt.content), PublicCloud)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("KVSetItems() error = %v, expected it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatKVSet(t *testing.T) {
+	items := []ConfigItem{
+		{Key: "db.password", Value: "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db)"},
+		{Key: "app.name", Value: "shop-dev", Label: "dev", ContentType: "text/plain", Tags: map[string]string{"team": "web"}},
+		{Key: "app.name", Value: "shop <main>"},
+	}
+
+	content, err := FormatKVSet(items, PublicCloud)
+	if err != nil {
+		t.Fatalf("FormatKVSet() error = %v", err)
+	}
+
+	for _, want := range []string{
+		`"value": "shop <main>"`,
+		`"value": "{\"uri\":\"https://myvault.vault.azure.net/secrets/db\"}"`,
+		`"content_type": "` + KeyVaultReferenceContentType + `"`,
+		`"label": null`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("FormatKVSet() = %s, expected it to contain %s", content, want)
+		}
+	}
+
+	// Reading the export back gives the same settings, sorted by key and label
+	readBack, err := KVSetItems(decodeExport(t, string(content)), PublicCloud)
+	if err != nil {
+		t.Fatalf("KVSetItems() error = %v", err)
+	}
+	expected := []ConfigItem{
+		{Key: "app.name", Value: "shop <main>", Tags: map[string]string{}},
+		{Key: "app.name", Value: "shop-dev", Label: "dev", ContentType: "text/plain", Tags: map[string]string{"team": "web"}},
+		{Key: "db.password", Value: items[0].Value, ContentType: KeyVaultReferenceContentType, Tags: map[string]string{}},
+	}
+	if !reflect.DeepEqual(readBack, expected) {
+		t.Errorf("KVSetItems(FormatKVSet()) = %+v, expected %+v", readBack, expected)
+	}
+}
@@ -0,0 +1,333 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+// vaultClients creates and caches Key Vault secrets clients per vault
+type vaultClients struct {
+	credential azcore.TokenCredential
+	endpoint   string
+	clients    map[string]*azsecrets.Client
+}
+
+// SecretVerifier confirms that Key Vault references point at secrets that exist and are enabled
+type SecretVerifier struct {
+	*vaultClients
+	now func() time.Time
+}
+
+// SecretWriter stores secret values in Key Vault
+type SecretWriter struct {
+	*vaultClients
+}
+
+// SecretResolver reads the secret values that Key Vault references point at
+type SecretResolver struct {
+	*vaultClients
+}
+
+// NewSecretVerifier creates a verifier that authenticates with the same Azure Identity
+// credential chain as the App Configuration client, against the given cloud. When endpoint
+// is set, every vault is reached through it instead, so a local stub can stand in for
+// Key Vault; a plain http endpoint is called without credentials.
+func NewSecretVerifier(endpoint string, azureCloud Cloud) (*SecretVerifier, error) {
+	clients, err := newVaultClients(endpoint, azureCloud)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretVerifier{
+		vaultClients: clients,
+		now:          time.Now,
+	}, nil
+}
+
+// NewSecretWriter creates a writer that stores secrets with the Azure Identity credential
+// chain of the given cloud. The endpoint override works as for NewSecretVerifier.
+func NewSecretWriter(endpoint string, azureCloud Cloud) (*SecretWriter, error) {
+	clients, err := newVaultClients(endpoint, azureCloud)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretWriter{vaultClients: clients}, nil
+}
+
+// NewSecretResolver creates a resolver that reads secrets with the Azure Identity credential
+// chain of the given cloud. The endpoint override works as for NewSecretVerifier.
+func NewSecretResolver(endpoint string, azureCloud Cloud) (*SecretResolver, error) {
+	clients, err := newVaultClients(endpoint, azureCloud)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretResolver{vaultClients: clients}, nil
+}
+
+// newVaultClients validates the endpoint override and creates the credential
+func newVaultClients(endpoint string, azureCloud Cloud) (*vaultClients, error) {
+	clients := &vaultClients{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		clients:  make(map[string]*azsecrets.Client),
+	}
+
+	if endpoint != "" {
+		parsed, err := url.Parse(endpoint)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return nil, fmt.Errorf("invalid vault endpoint: %s", endpoint)
+		}
+		if parsed.Scheme == "http" {
+			return clients, nil
+		}
+	}
+
+	cred, err := azureCloud.newCredential()
+	if err != nil {
+		return nil, err
+	}
+	clients.credential = cred
+
+	return clients, nil
+}
+
+// SetSecret stores a secret value in a vault and returns the new version
+func (w *SecretWriter) SetSecret(ctx context.Context, vaultURL, name, value string) (string, error) {
+	client, err := w.client(vaultURL)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.SetSecret(ctx, name, azsecrets.SetSecretParameters{Value: &value}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to set secret %s in %s: %w", name, vaultURL, err)
+	}
+
+	if resp.ID == nil {
+		return "", nil
+	}
+	return resp.ID.Version(), nil
+}
+
+// SecretExists reports whether a vault has a secret with the given name. Only secret
+// properties are read, never secret values.
+func (w *SecretWriter) SecretExists(ctx context.Context, vaultURL, name string) (bool, error) {
+	client, err := w.client(vaultURL)
+	if err != nil {
+		return false, err
+	}
+
+	pager := client.NewListSecretPropertiesVersionsPager(name, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			var respErr *azcore.ResponseError
+			if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to read secret %s from %s: %w", name, vaultURL, err)
+		}
+		if len(page.Value) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// VerifyReference checks that the referenced secret exists and that the referenced version,
+// or the current version when none is pinned, is enabled and not expired. Only secret
+// properties are read, never secret values.
+func (v *SecretVerifier) VerifyReference(ctx context.Context, ref *validator.KeyVaultReference) error {
+	client, err := v.client(ref.VaultURL)
+	if err != nil {
+		return err
+	}
+
+	var versions []*azsecrets.SecretProperties
+	pager := client.NewListSecretPropertiesVersionsPager(ref.SecretName, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			var respErr *azcore.ResponseError
+			if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+				return fmt.Errorf("secret %s does not exist in %s", ref.SecretName, ref.VaultURL)
+			}
+			return fmt.Errorf("failed to read secret %s from %s: %w", ref.SecretName, ref.VaultURL, err)
+		}
+		versions = append(versions, page.Value...)
+	}
+
+	if len(versions) == 0 {
+		return fmt.Errorf("secret %s does not exist in %s", ref.SecretName, ref.VaultURL)
+	}
+
+	var target *azsecrets.SecretProperties
+	if ref.SecretVersion != "" {
+		for _, version := range versions {
+			if version.ID != nil && strings.EqualFold(version.ID.Version(), ref.SecretVersion) {
+				target = version
+				break
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("version %s of secret %s does not exist in %s", ref.SecretVersion, ref.SecretName, ref.VaultURL)
+		}
+	} else {
+		// The current version is the most recently created one
+		sort.SliceStable(versions, func(i, j int) bool {
+			return createdAt(versions[i]).After(createdAt(versions[j]))
+		})
+		target = versions[0]
+	}
+
+	description := fmt.Sprintf("secret %s", ref.SecretName)
+	if ref.SecretVersion != "" {
+		description = fmt.Sprintf("version %s of secret %s", ref.SecretVersion, ref.SecretName)
+	}
+
+	if attributes := target.Attributes; attributes != nil {
+		if attributes.Enabled != nil && !*attributes.Enabled {
+			return fmt.Errorf("%s is disabled", description)
+		}
+		if attributes.Expires != nil && !v.now().Before(*attributes.Expires) {
+			return fmt.Errorf("%s expired on %s", description, attributes.Expires.Format("2006-01-02"))
+		}
+		if attributes.NotBefore != nil && v.now().Before(*attributes.NotBefore) {
+			return fmt.Errorf("%s is not valid until %s", description, attributes.NotBefore.Format("2006-01-02"))
+		}
+	}
+
+	return nil
+}
+
+// VerifyConfiguration checks every Key Vault reference in a flat configuration and reports
+// the ones that cannot be resolved as validation errors
+func (v *SecretVerifier) VerifyConfiguration(ctx context.Context, config map[string]string, val *validator.Validator) []validator.ValidationError {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errors []validator.ValidationError
+	for _, key := range keys {
+		specialValue, err := val.ValidateAndParseValue(key, config[key])
+		if err != nil || specialValue.Type != validator.ValueTypeKeyVault {
+			continue
+		}
+
+		ref, ok := specialValue.ParsedValue.(*validator.KeyVaultReference)
+		if !ok {
+			continue
+		}
+
+		if err := v.VerifyReference(ctx, ref); err != nil {
+			errors = append(errors, validator.ValidationError{
+				Key:     key,
+				Value:   config[key],
+				Message: fmt.Sprintf("Key Vault verification error: %s", err.Error()),
+				Type:    "keyvault_missing",
+			})
+		}
+	}
+
+	return errors
+}
+
+// Resolve reads the value of the referenced secret version, or of the current version when
+// none is pinned
+func (r *SecretResolver) Resolve(ctx context.Context, ref *validator.KeyVaultReference) (string, error) {
+	client, err := r.client(ref.VaultURL)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.GetSecret(ctx, ref.SecretName, ref.SecretVersion, nil)
+	if err != nil {
+		var respErr *azcore.ResponseError
+		if errors.As(err, &respErr) && respErr.StatusCode == http.StatusNotFound {
+			return "", fmt.Errorf("secret %s does not exist in %s", ref.SecretName, ref.VaultURL)
+		}
+		return "", fmt.Errorf("failed to read secret %s from %s: %w", ref.SecretName, ref.VaultURL, err)
+	}
+
+	if resp.Value == nil {
+		return "", nil
+	}
+	return *resp.Value, nil
+}
+
+// ResolveConfiguration returns a copy of a flat configuration with every Key Vault reference
+// replaced by the secret value it points at. Each secret is read once, however often it is
+// referenced.
+func (r *SecretResolver) ResolveConfiguration(ctx context.Context, config map[string]string, val *validator.Validator) (map[string]string, error) {
+	resolved := make(map[string]string, len(config))
+	values := make(map[string]string)
+
+	for key, value := range config {
+		resolved[key] = value
+
+		specialValue, err := val.ValidateAndParseValue(key, value)
+		if err != nil || specialValue.Type != validator.ValueTypeKeyVault {
+			continue
+		}
+		ref, ok := specialValue.ParsedValue.(*validator.KeyVaultReference)
+		if !ok {
+			continue
+		}
+
+		uri := ref.SecretURI()
+		if _, ok := values[uri]; !ok {
+			secret, err := r.Resolve(ctx, ref)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve %s: %w", key, err)
+			}
+			values[uri] = secret
+		}
+		resolved[key] = values[uri]
+	}
+
+	return resolved, nil
+}
+
+// client returns a cached secrets client for a vault, honouring the endpoint override
+func (v *vaultClients) client(vaultURL string) (*azsecrets.Client, error) {
+	if v.endpoint != "" {
+		vaultURL = v.endpoint
+	}
+
+	if client, ok := v.clients[vaultURL]; ok {
+		return client, nil
+	}
+
+	options := &azsecrets.ClientOptions{
+		// A stub endpoint does not answer to the vault's own resource
+		DisableChallengeResourceVerification: v.endpoint != "",
+	}
+	client, err := azsecrets.NewClient(vaultURL, v.credential, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Key Vault client for %s: %w", vaultURL, err)
+	}
+
+	v.clients[vaultURL] = client
+	return client, nil
+}
+
+// createdAt returns when a secret version was created, or the zero time if unknown
+func createdAt(properties *azsecrets.SecretProperties) time.Time {
+	if properties.Attributes == nil || properties.Attributes.Created == nil {
+		return time.Time{}
+	}
+	return *properties.Attributes.Created
+}
@@ -0,0 +1,278 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+// stubVersion is a secret version served by the Key Vault stub
+type stubVersion struct {
+	version string
+	enabled bool
+	created time.Time
+	expires *time.Time
+}
+
+// newKeyVaultStub serves secret version listings for the given secrets
+func newKeyVaultStub(t *testing.T, secrets map[string][]stubVersion) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if r.Method != http.MethodGet || len(parts) != 3 || parts[0] != "secrets" || parts[2] != "versions" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		versions, ok := secrets[parts[1]]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"SecretNotFound","message":"not found"}}`)
+			return
+		}
+
+		var value []map[string]interface{}
+		for _, version := range versions {
+			attributes := map[string]interface{}{
+				"enabled": version.enabled,
+				"created": version.created.Unix(),
+			}
+			if version.expires != nil {
+				attributes["exp"] = version.expires.Unix()
+			}
+			value = append(value, map[string]interface{}{
+				"id":         fmt.Sprintf("https://stub.vault.azure.net/secrets/%s/%s", parts[1], version.version),
+				"attributes": attributes,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSecretVerifier_VerifyReference(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+
+	server := newKeyVaultStub(t, map[string][]stubVersion{
+		"db-password": {
+			{version: "v1", enabled: false, created: now.Add(-48 * time.Hour)},
+			{version: "v2", enabled: true, created: now.Add(-24 * time.Hour)},
+		},
+		"rotated-out": {
+			{version: "v1", enabled: true, created: now.Add(-48 * time.Hour)},
+			{version: "v2", enabled: false, created: now.Add(-24 * time.Hour)},
+		},
+		"api-key": {
+			{version: "v1", enabled: true, created: now.Add(-48 * time.Hour), expires: &expired},
+		},
+	})
+
+	verifier, err := NewSecretVerifier(server.URL, PublicCloud)
+	if err != nil {
+		t.Fatalf("NewSecretVerifier failed: %v", err)
+	}
+	verifier.now = func() time.Time { return now }
+
+	tests := []struct {
+		name     string
+		secret   string
+		version  string
+		errorMsg string
+	}{
+		{"current version enabled", "db-password", "", ""},
+		{"pinned version enabled", "db-password", "v2", ""},
+		{"pinned version disabled", "db-password", "v1", "version v1 of secret db-password is disabled"},
+		{"unknown version", "db-password", "v3", "version v3 of secret db-password does not exist"},
+		{"current version disabled", "rotated-out", "", "secret rotated-out is disabled"},
+		{"expired", "api-key", "", "secret api-key expired"},
+		{"missing secret", "missing", "", "secret missing does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := &validator.KeyVaultReference{
+				VaultURL:      "https://myvault.vault.azure.net",
+				SecretName:    tt.secret,
+				SecretVersion: tt.version,
+			}
+
+			err := verifier.VerifyReference(context.Background(), ref)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestSecretVerifier_VerifyConfiguration(t *testing.T) {
+	server := newKeyVaultStub(t, map[string][]stubVersion{
+		"present": {{version: "v1", enabled: true, created: time.Now().Add(-time.Hour)}},
+	})
+
+	verifier, err := NewSecretVerifier(server.URL, PublicCloud)
+	if err != nil {
+		t.Fatalf("NewSecretVerifier failed: %v", err)
+	}
+
+	config := map[string]string{
+		"App.Name":    "demo",
+		"Db.Password": "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/present)",
+		"Api.Key":     "https://myvault.vault.azure.net/secrets/absent/abc123",
+	}
+
+	errors := verifier.VerifyConfiguration(context.Background(), config, validator.NewValidator())
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+	}
+	if errors[0].Key != "Api.Key" || errors[0].Type != "keyvault_missing" {
+		t.Errorf("unexpected error: %+v", errors[0])
+	}
+}
+
+func TestNewSecretVerifier_InvalidEndpoint(t *testing.T) {
+	if _, err := NewSecretVerifier("not a url", PublicCloud); err == nil {
+		t.Error("expected error for invalid endpoint")
+	}
+}
+
+func TestSecretWriter_SetSecret(t *testing.T) {
+	stored := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/secrets/")
+		if r.Method != http.MethodPut || strings.Contains(name, "/") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var body struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		stored[name] = body.Value
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"https://stub.vault.azure.net/secrets/%s/abc123","attributes":{"enabled":true}}`, name)
+	}))
+	defer server.Close()
+
+	writer, err := NewSecretWriter(server.URL, PublicCloud)
+	if err != nil {
+		t.Fatalf("NewSecretWriter failed: %v", err)
+	}
+
+	version, err := writer.SetSecret(context.Background(), "https://myvault.vault.azure.net", "db-password", "hunter2")
+	if err != nil {
+		t.Fatalf("SetSecret failed: %v", err)
+	}
+	if version != "abc123" {
+		t.Errorf("expected version abc123, got %q", version)
+	}
+	if stored["db-password"] != "hunter2" {
+		t.Errorf("expected secret value to be stored, got %v", stored)
+	}
+}
+
+func TestSecretWriter_SecretExists(t *testing.T) {
+	server := newKeyVaultStub(t, map[string][]stubVersion{
+		"db-password": {{version: "v1", enabled: true, created: time.Now()}},
+	})
+
+	writer, err := NewSecretWriter(server.URL, PublicCloud)
+	if err != nil {
+		t.Fatalf("NewSecretWriter failed: %v", err)
+	}
+
+	for name, expected := range map[string]bool{"db-password": true, "api-key": false} {
+		exists, err := writer.SecretExists(context.Background(), "https://myvault.vault.azure.net", name)
+		if err != nil {
+			t.Fatalf("SecretExists(%s) error = %v", name, err)
+		}
+		if exists != expected {
+			t.Errorf("SecretExists(%s) = %v, expected %v", name, exists, expected)
+		}
+	}
+}
+
+func TestSecretResolver_ResolveConfiguration(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		values := map[string]string{
+			"secrets/db-password":     "hunter2",
+			"secrets/api-key/v1":      "old-key",
+			"secrets/tls-cert/abc123": "-----BEGIN CERTIFICATE-----",
+		}
+
+		value, ok := values[strings.Trim(r.URL.Path, "/")]
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"SecretNotFound","message":"not found"}}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": value, "id": "https://stub.vault.azure.net" + r.URL.Path})
+	}))
+	defer server.Close()
+
+	resolver, err := NewSecretResolver(server.URL, PublicCloud)
+	if err != nil {
+		t.Fatalf("NewSecretResolver failed: %v", err)
+	}
+
+	config := map[string]string{
+		"app.name":          "demo",
+		"database.password": "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-password)",
+		"replica.password":  "@Microsoft.KeyVault(VaultName=myvault;SecretName=db-password)",
+		"payments.apiKey":   "https://myvault.vault.azure.net/secrets/api-key/v1",
+		"tls.certificate":   "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/certificates/tls-cert/abc123)",
+	}
+
+	resolved, err := resolver.ResolveConfiguration(context.Background(), config, validator.NewValidator())
+	if err != nil {
+		t.Fatalf("ResolveConfiguration failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"app.name":          "demo",
+		"database.password": "hunter2",
+		"replica.password":  "hunter2",
+		"payments.apiKey":   "old-key",
+		"tls.certificate":   "-----BEGIN CERTIFICATE-----",
+	}
+	for key, value := range expected {
+		if resolved[key] != value {
+			t.Errorf("%s = %q, expected %q", key, resolved[key], value)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("expected each secret to be read once, got %d requests", requests)
+	}
+
+	config["missing"] = "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/missing)"
+	if _, err := resolver.ResolveConfiguration(context.Background(), config, validator.NewValidator()); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected missing secret error, got %v", err)
+	}
+}
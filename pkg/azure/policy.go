@@ -0,0 +1,64 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+)
+
+// settingTagsKey is the context key carrying the tags for a SetSetting call
+type settingTagsKey struct{}
+
+// settingTagsPolicy adds tags to the key-value PUT requests whose context carries them, which
+// only happens for changes asked to write their tags. The SDK does not expose tags on
+// SetSetting, but the REST API accepts them in the request body.
+// It runs per call, before the authentication policy signs the body.
+type settingTagsPolicy struct{}
+
+// clientOptions returns the App Configuration client options used by this package
+func clientOptions() *azappconfig.ClientOptions {
+	return &azappconfig.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			PerCallPolicies: []policy.Policy{settingTagsPolicy{}},
+		},
+	}
+}
+
+// Do implements policy.Policy
+func (settingTagsPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	tags, ok := raw.Context().Value(settingTagsKey{}).(map[string]string)
+	if !ok || raw.Method != http.MethodPut || !strings.HasPrefix(raw.URL.Path, "/kv/") || req.Body() == nil {
+		return req.Next()
+	}
+
+	body, err := io.ReadAll(req.Body())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read setting body: %w", err)
+	}
+
+	var kv map[string]interface{}
+	if err := json.Unmarshal(body, &kv); err != nil {
+		return nil, fmt.Errorf("failed to decode setting body: %w", err)
+	}
+	kv["tags"] = tags
+
+	body, err = json.Marshal(kv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode setting body: %w", err)
+	}
+
+	if err := req.SetBody(streaming.NopCloser(bytes.NewReader(body)), raw.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+
+	return req.Next()
+}
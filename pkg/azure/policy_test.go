@@ -0,0 +1,68 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+)
+
+func TestSettingTagsPolicy(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]map[string]interface{})
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		if err := json.Unmarshal(content, &body); err != nil {
+			t.Errorf("%s %s: invalid body %s", r.Method, r.URL.Path, content)
+		}
+
+		mu.Lock()
+		bodies[r.URL.Path] = body
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/vnd.microsoft.appconfig.kv+json")
+		w.Header().Set("Sync-Token", "token=1;sn=1")
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	options := clientOptions()
+	options.Transport = server.Client()
+	connStr := "Endpoint=" + server.URL + ";Id=test;Secret=" + base64.StdEncoding.EncodeToString([]byte("secret"))
+	sdkClient, err := azappconfig.NewClientFromConnectionString(connStr, options)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	client := &Client{client: sdkClient, cloud: PublicCloud}
+
+	err = client.ApplyChanges(context.Background(), []ChangeOperation{
+		{Operation: "update", Key: "tagged", Value: "1", Tags: map[string]string{"team": "web"}},
+		{Operation: "update", Key: "untagged", Value: "2"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+
+	tagged := bodies["/kv/tagged"]
+	if tagged == nil || tagged["value"] != "1" || !reflect.DeepEqual(tagged["tags"], map[string]interface{}{"team": "web"}) {
+		t.Errorf("tagged body = %v, expected value 1 with team=web", tagged)
+	}
+
+	// Settings written without tags are sent exactly as the SDK builds them
+	untagged := bodies["/kv/untagged"]
+	if untagged == nil || untagged["value"] != "2" {
+		t.Errorf("untagged body = %v, expected value 2", untagged)
+	}
+	if tags, _ := untagged["tags"].(map[string]interface{}); len(tags) > 0 {
+		t.Errorf("untagged body = %v, expected no tags", untagged)
+	}
+}
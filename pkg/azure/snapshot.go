@@ -0,0 +1,191 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azappconfig"
+)
+
+// Snapshot represents an immutable App Configuration snapshot
+type Snapshot struct {
+	Name            string
+	Status          string
+	Filters         []SnapshotFilter
+	ItemsCount      int64
+	RetentionPeriod time.Duration
+	Created         time.Time
+	Expires         *time.Time
+}
+
+// SnapshotFilter selects the key-values captured by a snapshot
+type SnapshotFilter struct {
+	Key   string
+	Label string
+}
+
+// CreateSnapshot creates a snapshot from the given filters and waits for it to be ready.
+// Without filters the snapshot captures every key.
+func (c *Client) CreateSnapshot(ctx context.Context, name string, filters []SnapshotFilter, retention time.Duration) (*Snapshot, error) {
+	settingFilters := snapshotSettingFilters(filters)
+
+	options := &azappconfig.BeginCreateSnapshotOptions{}
+	if retention > 0 {
+		seconds := int64(retention / time.Second)
+		options.RetentionPeriod = &seconds
+	}
+
+	poller, err := c.client.BeginCreateSnapshot(ctx, name, settingFilters, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot %s: %w", name, err)
+	}
+
+	resp, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot %s: %w", name, err)
+	}
+
+	return convertSnapshot(resp.Snapshot), nil
+}
+
+// snapshotSettingFilters converts snapshot filters for the SDK. The service rejects a
+// snapshot without filters, so no filters means all keys.
+func snapshotSettingFilters(filters []SnapshotFilter) []azappconfig.SettingFilter {
+	if len(filters) == 0 {
+		filters = []SnapshotFilter{{Key: "*"}}
+	}
+
+	settingFilters := make([]azappconfig.SettingFilter, len(filters))
+	for i := range filters {
+		filter := filters[i]
+		if filter.Key != "" {
+			settingFilters[i].KeyFilter = &filter.Key
+		}
+		if filter.Label != "" {
+			settingFilters[i].LabelFilter = &filter.Label
+		}
+	}
+	return settingFilters
+}
+
+// ListSnapshots retrieves all snapshots in the store
+func (c *Client) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	var snapshots []Snapshot
+
+	pager := c.client.NewListSnapshotsPager(nil)
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list snapshots: %w", err)
+		}
+
+		for _, snapshot := range page.Snapshots {
+			snapshots = append(snapshots, *convertSnapshot(snapshot))
+		}
+	}
+
+	return snapshots, nil
+}
+
+// ArchiveSnapshot moves a snapshot to the archived state
+func (c *Client) ArchiveSnapshot(ctx context.Context, name string) (*Snapshot, error) {
+	resp, err := c.client.ArchiveSnapshot(ctx, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive snapshot %s: %w", name, err)
+	}
+
+	return convertSnapshot(resp.Snapshot), nil
+}
+
+// RecoverSnapshot moves an archived snapshot back to the ready state
+func (c *Client) RecoverSnapshot(ctx context.Context, name string) (*Snapshot, error) {
+	resp, err := c.client.RecoverSnapshot(ctx, name, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover snapshot %s: %w", name, err)
+	}
+
+	return convertSnapshot(resp.Snapshot), nil
+}
+
+// FetchSnapshot retrieves all configuration items captured by a snapshot
+func (c *Client) FetchSnapshot(ctx context.Context, name string) ([]ConfigItem, error) {
+	var items []ConfigItem
+
+	pager := c.client.NewListSettingsForSnapshotPager(name, nil)
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list settings for snapshot %s: %w", name, err)
+		}
+
+		for _, setting := range page.Settings {
+			if setting.Key == nil || setting.Value == nil {
+				continue
+			}
+
+			item := ConfigItem{
+				Key:   *setting.Key,
+				Value: c.normalizeRetrievedValue(*setting.Value),
+			}
+
+			if setting.Label != nil {
+				item.Label = *setting.Label
+			}
+
+			if setting.Tags != nil {
+				item.Tags = setting.Tags
+			}
+
+			if setting.ContentType != nil {
+				item.ContentType = *setting.ContentType
+			}
+
+			if setting.LastModified != nil {
+				item.LastModified = *setting.LastModified
+			}
+
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// convertSnapshot converts an SDK snapshot into the package representation
+func convertSnapshot(snapshot azappconfig.Snapshot) *Snapshot {
+	result := &Snapshot{
+		Expires: snapshot.Expires,
+	}
+
+	if snapshot.Name != nil {
+		result.Name = *snapshot.Name
+	}
+	if snapshot.Status != nil {
+		result.Status = string(*snapshot.Status)
+	}
+	if snapshot.ItemsCount != nil {
+		result.ItemsCount = *snapshot.ItemsCount
+	}
+	if snapshot.RetentionPeriod != nil {
+		result.RetentionPeriod = time.Duration(*snapshot.RetentionPeriod) * time.Second
+	}
+	if snapshot.Created != nil {
+		result.Created = *snapshot.Created
+	}
+
+	for _, filter := range snapshot.Filters {
+		converted := SnapshotFilter{}
+		if filter.KeyFilter != nil {
+			converted.Key = *filter.KeyFilter
+		}
+		if filter.LabelFilter != nil {
+			converted.Label = *filter.LabelFilter
+		}
+		result.Filters = append(result.Filters, converted)
+	}
+
+	return result
+}
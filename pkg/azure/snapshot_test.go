@@ -0,0 +1,22 @@
+package azure
+
+import "testing"
+
+func TestSnapshotSettingFilters(t *testing.T) {
+	// No filters captures every key rather than sending an empty list the service rejects
+	filters := snapshotSettingFilters(nil)
+	if len(filters) != 1 || filters[0].KeyFilter == nil || *filters[0].KeyFilter != "*" || filters[0].LabelFilter != nil {
+		t.Errorf("snapshotSettingFilters(nil) = %+v, expected a single key=* filter", filters)
+	}
+
+	filters = snapshotSettingFilters([]SnapshotFilter{{Key: "app.*", Label: "production"}, {Key: "*"}})
+	if len(filters) != 2 {
+		t.Fatalf("snapshotSettingFilters() returned %d filters, expected 2", len(filters))
+	}
+	if *filters[0].KeyFilter != "app.*" || *filters[0].LabelFilter != "production" {
+		t.Errorf("first filter = key %s label %v, expected key app.* label production", *filters[0].KeyFilter, filters[0].LabelFilter)
+	}
+	if *filters[1].KeyFilter != "*" || filters[1].LabelFilter != nil {
+		t.Errorf("second filter = key %s label %v, expected key * without a label", *filters[1].KeyFilter, filters[1].LabelFilter)
+	}
+}
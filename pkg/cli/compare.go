@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/spf13/cobra"
+)
+
+var compareOutput string
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <source> <target>",
+	Short: "Compare two configuration sources without a local file",
+	Long: `Compare two configuration sources and show what would change in the target
+to make it match the source. Keys that only exist in the target are shown as deletions.
+
+Settings are compared by key and label, so a key stored under several labels is compared
+label by label. When each side holds a single label, such as label:staging and
+label:production, settings are compared by key regardless of the label names.
+
+Each source can be one of:
+  config.json, file:config.json             a local file or downloaded backup
+  file:export.json?label=staging            a label of an az appconfig kv export (kvset profile)
+  https://store.azconfig.io?label=staging   a label of a live store
+  https://store.azconfig.io?snapshot=rel-1  a snapshot of a store
+  label:staging                             a label of the store given by --endpoint
+  snapshot:rel-1                            a snapshot of the store given by --endpoint
+
+EXAMPLES:
+  # What is different between staging and production in the same store?
+  appconfigguard compare label:staging label:production --endpoint=https://mystorage.azconfig.io
+
+  # Compare two stores
+  appconfigguard compare "https://staging.azconfig.io?label=app" "https://prod.azconfig.io?label=app"
+
+  # Compare a backup file with a snapshot, as JSON
+  appconfigguard compare backup.json snapshot:release-42 --endpoint=https://mystorage.azconfig.io --output=json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCompare,
+}
+
+func init() {
+	compareCmd.Flags().StringVarP(&endpoint, "endpoint", "e", "", "Default Azure App Configuration endpoint for label: and snapshot: sources")
+	compareCmd.Flags().StringVarP(&compareOutput, "output", "o", "console", "Output format: console, json")
+	compareCmd.Flags().BoolVar(&ci, "ci", false, "Exit with code 1 when the sources differ")
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	source, err := parseSource(args[0], endpoint)
+	if err != nil {
+		return err
+	}
+
+	target, err := parseSource(args[1], endpoint)
+	if err != nil {
+		return err
+	}
+
+	sourceItems, err := source.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	targetItems, err := target.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	diffEngine := newDiffEngine()
+	changes, err := diffEngine.CompareItems(alignSourceLabel(sourceItems, targetItems, target.Label), targetItems, true)
+	if err != nil {
+		return fmt.Errorf("failed to generate diff: %w", err)
+	}
+
+	if compareOutput == "json" {
+		if err := outputJSON(changes, diffEngine); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Comparing %s → %s\n\n", source, target)
+		fmt.Println(diffEngine.FormatConsole(changes))
+	}
+
+	if ci && diffEngine.HasChanges(changes) {
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+// alignSourceLabel moves the settings of a source holding a single label onto the label of a
+// target holding a single label, so label:staging and label:production are compared setting by
+// setting. Sources or targets spanning several labels are compared by key and label as they are.
+func alignSourceLabel(sourceItems, targetItems []azure.ConfigItem, targetLabel string) []azure.ConfigItem {
+	sourceLabel, ok := sharedLabel(sourceItems, "")
+	if !ok {
+		return sourceItems
+	}
+	targetLabel, ok = sharedLabel(targetItems, targetLabel)
+	if !ok || sourceLabel == targetLabel {
+		return sourceItems
+	}
+
+	aligned := make([]azure.ConfigItem, len(sourceItems))
+	for i, item := range sourceItems {
+		item.Label = targetLabel
+		aligned[i] = item
+	}
+	return aligned
+}
+
+// sharedLabel returns the label all items have, or fallback when there are no items.
+// It reports false when the items span several labels.
+func sharedLabel(items []azure.ConfigItem, fallback string) (string, bool) {
+	if len(items) == 0 {
+		return fallback, true
+	}
+	for _, item := range items[1:] {
+		if item.Label != items[0].Label {
+			return "", false
+		}
+	}
+	return items[0].Label, true
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+)
+
+func TestCompare_KeyWithSeveralLabels(t *testing.T) {
+	source := []azure.ConfigItem{
+		{Key: "app.name", Value: "staging-app", Label: "staging"},
+		{Key: "app.name", Value: "prod-app", Label: "production"},
+	}
+	target := []azure.ConfigItem{
+		{Key: "app.name", Value: "prod-app", Label: "production"},
+		{Key: "app.name", Value: "old-staging-app", Label: "staging"},
+	}
+
+	changes, err := diff.NewEngine().CompareItems(alignSourceLabel(source, target, ""), target, true)
+	if err != nil {
+		t.Fatalf("CompareItems() error = %v", err)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("CompareItems() returned %d changes, expected 1: %+v", len(changes), changes)
+	}
+	if change := changes[0]; change.Type != diff.ChangeTypeUpdate || change.Label != "staging" ||
+		change.OldValue != "old-staging-app" || change.NewValue != "staging-app" {
+		t.Errorf("change = %+v, expected staging update from old-staging-app to staging-app", change)
+	}
+}
+
+func TestAlignSourceLabel(t *testing.T) {
+	staging := []azure.ConfigItem{{Key: "app.name", Value: "a", Label: "staging"}}
+	production := []azure.ConfigItem{{Key: "app.name", Value: "b", Label: "production"}}
+	mixed := []azure.ConfigItem{
+		{Key: "app.name", Value: "a", Label: "staging"},
+		{Key: "app.name", Value: "b", Label: "production"},
+	}
+
+	tests := []struct {
+		name        string
+		source      []azure.ConfigItem
+		target      []azure.ConfigItem
+		targetLabel string
+		expected    []azure.ConfigItem
+	}{
+		{"single labels", staging, production, "production", []azure.ConfigItem{{Key: "app.name", Value: "a", Label: "production"}}},
+		{"empty target label", staging, nil, "production", []azure.ConfigItem{{Key: "app.name", Value: "a", Label: "production"}}},
+		{"source spans labels", mixed, production, "production", mixed},
+		{"target spans labels", staging, mixed, "", staging},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if aligned := alignSourceLabel(tt.source, tt.target, tt.targetLabel); !reflect.DeepEqual(aligned, tt.expected) {
+				t.Errorf("alignSourceLabel() = %+v, expected %+v", aligned, tt.expected)
+			}
+		})
+	}
+
+	if staging[0].Label != "staging" {
+		t.Errorf("source items were modified: %+v", staging)
+	}
+}
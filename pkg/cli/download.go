@@ -2,12 +2,13 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/chan27-2/appconfigguard/pkg/azure"
-	jsonpkg "github.com/chan27-2/appconfigguard/pkg/json"
+	"github.com/chan27-2/appconfigguard/pkg/format"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
 	"github.com/spf13/cobra"
 )
 
@@ -15,18 +16,28 @@ var (
 	downloadOutputFile string
 	downloadLabel      string
 	downloadTags       string
+	downloadFormat     string
+	downloadProfile    string
 )
 
 // downloadCmd represents the download command
 var downloadCmd = &cobra.Command{
 	Use:   "download",
-	Short: "Download configuration from Azure App Configuration and save as JSON",
-	Long: `Download configuration from Azure App Configuration and convert it to a local JSON file
-in the same format that the tool expects for uploads.
+	Short: "Download configuration from Azure App Configuration to a local file",
+	Long: `Download configuration from Azure App Configuration and convert it to a local JSON,
+YAML, TOML, INI, .env or .properties file in the same format that the tool expects for
+uploads. The format follows the output file extension (.yaml or .yml for YAML, .toml, .ini or
+.cfg for INI, .env, .properties), unless --format is given. Feature flags are left out of INI,
+.env and .properties files, whose values are all strings.
+
+With --profile=kvset the file is written the way az appconfig kv export --profile
+appconfig/kvset writes it: a JSON list of every setting with its label, tags and content
+type. Such a file can be uploaded again as it is, or imported with az appconfig kv import.
 
 This command fetches all configuration settings from the specified Azure App Configuration
-store and converts them back into structured JSON format. The resulting file can then be
-used with the main sync command.
+store and converts them back into a structured file in the format chosen by --format or
+detected from the output file extension. The resulting file can then be used with the main
+sync command.
 
 EXAMPLES:
   # Download all configuration to config.json
@@ -35,6 +46,21 @@ EXAMPLES:
   # Download configuration with specific label
   appconfigguard download --endpoint=https://mystorage.azconfig.io --output=config.json --label=production
 
+  # Download configuration as YAML
+  appconfigguard download --endpoint=https://mystorage.azconfig.io --output=config.yaml
+
+  # Download configuration as TOML for a Go or Python service
+  appconfigguard download --endpoint=https://mystorage.azconfig.io --output=config.toml
+
+  # Download configuration as a .env file for a legacy service
+  appconfigguard download --endpoint=https://mystorage.azconfig.io --output=service.env --format=env
+
+  # Download configuration as Spring properties
+  appconfigguard download --endpoint=https://mystorage.azconfig.io --output=application.properties
+
+  # Download every label with its tags and content types, as az appconfig kv export does
+  appconfigguard download --endpoint=https://mystorage.azconfig.io --output=export.json --profile=kvset
+
   # Download configuration with specific tags
   appconfigguard download --endpoint=https://mystorage.azconfig.io --output=config.json --tags="env=prod,team=backend"`,
 	RunE: runDownload,
@@ -45,6 +71,9 @@ func init() {
 	downloadCmd.Flags().StringVarP(&downloadOutputFile, "output", "o", "", "Output file path for the downloaded configuration (required)")
 	downloadCmd.Flags().StringVarP(&downloadLabel, "label", "l", "", "App Configuration label filter (optional)")
 	downloadCmd.Flags().StringVar(&downloadTags, "tags", "", "App Configuration tags filter as key=value pairs (optional)")
+	downloadCmd.Flags().StringVar(&downloadFormat, "format", "", "Output format: json, yaml, toml, ini, env, properties (default: from the output file extension)")
+
+	downloadCmd.Flags().StringVar(&downloadProfile, "profile", "default", "File layout: default (structured configuration), kvset (settings with labels, tags and content types)")
 
 	downloadCmd.MarkFlagRequired("endpoint")
 	downloadCmd.MarkFlagRequired("output")
@@ -53,10 +82,28 @@ func init() {
 func runDownload(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	outputFormat := format.Detect(downloadOutputFile)
+	if downloadFormat != "" {
+		var err error
+		if outputFormat, err = format.Parse(downloadFormat); err != nil {
+			return err
+		}
+	}
+
+	switch downloadProfile {
+	case "default":
+	case "kvset":
+		if outputFormat != format.JSON {
+			return fmt.Errorf("the kvset profile is written as JSON, not %s", outputFormat)
+		}
+	default:
+		return fmt.Errorf("unknown profile %q: expected default or kvset", downloadProfile)
+	}
+
 	fmt.Println("📥 Downloading configuration from Azure App Configuration...")
 
 	// Create Azure client
-	azureClient, err := azure.NewClient(endpoint)
+	azureClient, err := newAzureClient(endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to create Azure client: %w", err)
 	}
@@ -79,6 +126,10 @@ func runDownload(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("✅ Found %d configuration items\n", len(configItems))
 
+	if downloadProfile == "kvset" {
+		return writeKVSet(configItems)
+	}
+
 	// Convert ConfigItems to flat map
 	flatConfig := make(map[string]string)
 	for _, item := range configItems {
@@ -86,7 +137,7 @@ func runDownload(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize JSON flattener
-	jsonFlattener := jsonpkg.NewFlattener()
+	jsonFlattener := newFlattener()
 
 	// Validate the configuration
 	validationErrors, err := jsonFlattener.ValidateConfiguration(flatConfig)
@@ -95,34 +146,45 @@ func runDownload(cmd *cobra.Command, args []string) error {
 	}
 
 	if len(validationErrors) > 0 {
-		fmt.Println("⚠️  Configuration validation warnings:")
-		for _, validationErr := range validationErrors {
-			fmt.Printf("   %s: %s\n", colorize(validationErr.Key, colorYellow), validationErr.Message)
-		}
-		fmt.Println()
+		printValidationWarnings(validationErrors)
 	}
 
-	// Unflatten the configuration back to structured JSON
-	fmt.Println("🔄 Converting to structured JSON...")
+	// Unflatten the configuration back to structured data
+	fmt.Printf("🔄 Converting to structured %s...\n", strings.ToUpper(string(outputFormat)))
 	structuredConfig, err := jsonFlattener.Unflatten(flatConfig)
 	if err != nil {
 		return fmt.Errorf("failed to unflatten configuration: %w", err)
 	}
 
+	if !outputFormat.HoldsFeatureFlags() {
+		if flags, ok := structuredConfig[validator.FeatureFlagsSection].(map[string]interface{}); ok {
+			fmt.Printf("⚠️  Leaving out %d feature flags: %s files cannot hold them\n", len(flags), outputFormat)
+			delete(structuredConfig, validator.FeatureFlagsSection)
+		}
+	}
+
 	// Write to file
 	fmt.Printf("💾 Saving to file: %s\n", downloadOutputFile)
-	file, err := os.Create(downloadOutputFile)
+	if err := format.WriteFile(downloadOutputFile, structuredConfig, outputFormat); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Successfully downloaded and saved configuration to %s\n", downloadOutputFile)
+	return nil
+}
+
+// writeKVSet saves every downloaded setting with its label, tags and content type
+func writeKVSet(configItems []azure.ConfigItem) error {
+	content, err := azure.FormatKVSet(configItems, toolCloud)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(structuredConfig); err != nil {
-		return fmt.Errorf("failed to write JSON to file: %w", err)
+	fmt.Printf("💾 Saving to file: %s\n", downloadOutputFile)
+	if err := os.WriteFile(downloadOutputFile, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", downloadOutputFile, err)
 	}
 
-	fmt.Printf("✅ Successfully downloaded and saved configuration to %s\n", downloadOutputFile)
+	fmt.Printf("✅ Successfully downloaded and saved %d settings to %s\n", len(configItems), downloadOutputFile)
 	return nil
 }
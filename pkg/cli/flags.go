@@ -0,0 +1,607 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+	"github.com/chan27-2/appconfigguard/pkg/evaluator"
+	"github.com/chan27-2/appconfigguard/pkg/format"
+	"github.com/chan27-2/appconfigguard/pkg/report"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagsUser       string
+	flagsGroups     []string
+	flagsAt         string
+	flagsIgnoreCase bool
+	flagsOutput     string
+	flagsKeepAlias  bool
+	flagsYes        bool
+	flagsStaleDays  int
+	flagsScanDir    string
+)
+
+// flagsCmd represents the flags command
+var flagsCmd = &cobra.Command{
+	Use:   "flags",
+	Short: "Work with Microsoft feature flags",
+	Long: `Work with the Microsoft feature flags stored under .appconfig.featureflag/ keys.
+
+Feature flags are read from the featureFlags section of a local file (and --flags-file),
+or from a live store when --endpoint is given.`,
+}
+
+var flagsEvaluateCmd = &cobra.Command{
+	Use:   "evaluate <flag>",
+	Short: "Evaluate a feature flag locally for a user and groups",
+	Long: `Evaluate a feature flag for a user and groups exactly as the Microsoft feature
+management libraries would, without deploying anything.
+
+Microsoft.Targeting and Microsoft.TimeWindow are evaluated deterministically. Microsoft.Percentage
+is random for every evaluation, so the chance of the flag being on is reported instead.
+
+EXAMPLES:
+  # Is the Beta flag on for alice in the beta group, according to the local file?
+  appconfigguard flags evaluate Beta --file=config.json --user=alice --groups=beta
+
+  # The same against the production label of the live store
+  appconfigguard flags evaluate Beta --endpoint=https://mystorage.azconfig.io --label=production --user=alice --groups=beta
+
+  # Check a time window at a given moment
+  appconfigguard flags evaluate Sale --file=config.json --at=2024-12-24T09:00:00Z`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFlagsEvaluate,
+}
+
+var flagsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Convert heuristic boolean flags into real feature flags",
+	Long: `Convert settings that are only recognised as flags by their key pattern, such as
+feature.Beta = true or checkout.enabled = false, into .appconfig.featureflag/ entries with the
+same effective state. The flag name is the part of the key matched by the * of its detection
+pattern, such as feature.* or *.enabled (see featureFlags.patterns in the settings file).
+Flags matched by a disable pattern, such as disable.* or *.disabled, are inverted.
+
+The old keys are removed unless --keep-alias is given, in which case they stay as
+compatibility aliases for code that has not moved to the feature manager yet.
+
+With --file the local file is edited in place, keeping its comments and layout: migrated
+flags move into its featureFlags section (or into --flags-file), which must be JSON, YAML or
+TOML. With --endpoint the store is changed directly. Either way the changes are previewed
+first and only written with --apply, after confirmation unless --yes or --ci is given.
+
+EXAMPLES:
+  # Preview migrating the flags in a local file
+  appconfigguard flags migrate --file=config.json
+
+  # Rewrite the file, keeping the old keys as aliases
+  appconfigguard flags migrate --file=config.json --keep-alias --apply
+
+  # Migrate the flags of the production label in the store
+  appconfigguard flags migrate --endpoint=https://mystorage.azconfig.io --label=production --apply`,
+	RunE: runFlagsMigrate,
+}
+
+var flagsReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "List feature flags that are ready to be cleaned up",
+	Long: `List feature flags that look ready to be cleaned up:
+
+  - flags that are always on or always off (disabled, no filters, a 100% or 0% rollout,
+    or a time window that has ended)
+  - flags unchanged for more than --stale-days, based on App Configuration last-modified
+    timestamps (only available with --endpoint)
+  - flags whose name does not appear in the source tree given by --scan
+
+EXAMPLES:
+  # Report stale flags in the production label
+  appconfigguard flags report --endpoint=https://mystorage.azconfig.io --label=production
+
+  # Also look for flags that are no longer referenced in code, as markdown for an issue
+  appconfigguard flags report --endpoint=https://mystorage.azconfig.io --scan=./src --output=markdown
+
+  # Fail a scheduled pipeline when flags have been unchanged for six months
+  appconfigguard flags report --endpoint=https://mystorage.azconfig.io --stale-days=180 --ci`,
+	RunE: runFlagsReport,
+}
+
+func init() {
+	flagsCmd.PersistentFlags().StringVarP(&filePath, "file", "f", "", "Path to local configuration file: JSON, YAML, TOML, INI, .env or .properties")
+	flagsCmd.PersistentFlags().StringVar(&flagsFile, "flags-file", "", "Path to a separate file with a featureFlags section (optional)")
+	flagsCmd.PersistentFlags().StringVarP(&endpoint, "endpoint", "e", "", "Read flags from this Azure App Configuration store instead of a file")
+	flagsCmd.PersistentFlags().StringVarP(&label, "label", "l", "", "App Configuration label to read flags from (with --endpoint)")
+	flagsCmd.PersistentFlags().StringVar(&snapshotTarget, "snapshot", "", "Read flags from this snapshot (with --endpoint)")
+
+	flagsEvaluateCmd.Flags().StringVar(&flagsUser, "user", "", "User id to evaluate the flag for")
+	flagsEvaluateCmd.Flags().StringSliceVar(&flagsGroups, "groups", nil, "Comma-separated groups the user belongs to")
+	flagsEvaluateCmd.Flags().StringVar(&flagsAt, "at", "", "Evaluate time windows at this RFC3339 time instead of now")
+	flagsEvaluateCmd.Flags().BoolVar(&flagsIgnoreCase, "ignore-case", false, "Compare user and group names case-insensitively")
+	flagsEvaluateCmd.Flags().StringVarP(&flagsOutput, "output", "o", "console", "Output format: console, json")
+
+	flagsMigrateCmd.Flags().BoolVar(&flagsKeepAlias, "keep-alias", false, "Keep the old keys as compatibility aliases")
+	flagsMigrateCmd.Flags().BoolVar(&apply, "apply", false, "Apply the migration after preview (default: dry-run only)")
+	flagsMigrateCmd.Flags().BoolVarP(&flagsYes, "yes", "y", false, "Rewrite local files without asking for confirmation")
+	flagsMigrateCmd.Flags().BoolVar(&ci, "ci", false, "Non-interactive CI/CD mode")
+	flagsMigrateCmd.Flags().StringVarP(&output, "output", "o", "console", "Output format: console, json")
+
+	flagsReportCmd.Flags().IntVar(&flagsStaleDays, "stale-days", 90, "Report flags unchanged for more than this many days (0 disables)")
+	flagsReportCmd.Flags().StringVar(&flagsScanDir, "scan", "", "Source tree to search for flag name references (optional)")
+	flagsReportCmd.Flags().StringVarP(&output, "output", "o", "console", "Output format: console, json, markdown")
+	flagsReportCmd.Flags().BoolVar(&ci, "ci", false, "Exit with code 1 when stale flags are found")
+
+	flagsCmd.AddCommand(flagsEvaluateCmd)
+	flagsCmd.AddCommand(flagsMigrateCmd)
+	flagsCmd.AddCommand(flagsReportCmd)
+}
+
+func runFlagsEvaluate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	name := args[0]
+
+	flags, source, err := loadFeatureFlags(ctx)
+	if err != nil {
+		return err
+	}
+
+	ff, ok := flags[name]
+	if !ok {
+		return fmt.Errorf("feature flag %s not found in %s", name, source)
+	}
+
+	flagEvaluator := evaluator.NewEvaluator()
+	flagEvaluator.IgnoreCase = flagsIgnoreCase
+	if flagsAt != "" {
+		at, err := time.Parse(time.RFC3339, flagsAt)
+		if err != nil {
+			return fmt.Errorf("invalid --at time %q: %w", flagsAt, err)
+		}
+		flagEvaluator.SetTime(at)
+	}
+
+	result := flagEvaluator.Evaluate(ff, evaluator.TargetingContext{
+		UserID: flagsUser,
+		Groups: flagsGroups,
+	})
+
+	if flagsOutput == "json" {
+		output := map[string]interface{}{
+			"flag":        name,
+			"user":        flagsUser,
+			"groups":      flagsGroups,
+			"enabled":     result.Enabled,
+			"probability": result.Probability,
+			"reasons":     result.Reasons,
+		}
+
+		jsonBytes, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+		return nil
+	}
+
+	subject := "everyone"
+	if flagsUser != "" {
+		subject = flagsUser
+	}
+	if len(flagsGroups) > 0 {
+		subject += fmt.Sprintf(" (groups: %s)", strings.Join(flagsGroups, ", "))
+	}
+
+	switch {
+	case result.Probability == 1:
+		fmt.Printf("✅ %s is ON for %s\n", name, subject)
+	case result.Probability == 0:
+		fmt.Printf("⛔ %s is OFF for %s\n", name, subject)
+	default:
+		fmt.Printf("🎲 %s is ON for %s in %.1f%% of evaluations\n", name, subject, result.Probability*100)
+	}
+
+	for _, reason := range result.Reasons {
+		fmt.Printf("   • %s\n", reason)
+	}
+
+	return nil
+}
+
+// loadFeatureFlags reads the feature flags from the local file or the store given by the
+// flags command, keyed by flag name, and returns a description of where they came from
+func loadFeatureFlags(ctx context.Context) (map[string]*validator.FeatureFlag, string, error) {
+	items, description, err := loadFeatureFlagItems(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	flags := make(map[string]*validator.FeatureFlag, len(items))
+	for _, item := range items {
+		ff, err := validator.ParseFeatureFlagValue(item.Value)
+		if err != nil {
+			return nil, "", fmt.Errorf("feature flag %s: %w", validator.FeatureFlagName(item.Key), err)
+		}
+		flags[validator.FeatureFlagName(item.Key)] = ff
+	}
+
+	return flags, description, nil
+}
+
+// loadFeatureFlagItems reads the feature flag settings from the local file or the store
+// given by the flags command, and returns a description of where they came from
+func loadFeatureFlagItems(ctx context.Context) ([]azure.ConfigItem, string, error) {
+	var source *configSource
+	switch {
+	case endpoint != "":
+		if label != "" && snapshotTarget != "" {
+			return nil, "", fmt.Errorf("--label and --snapshot cannot be combined")
+		}
+		source = &configSource{Endpoint: endpoint, Label: label, Snapshot: snapshotTarget}
+	case filePath != "":
+		source = &configSource{File: filePath}
+	case flagsFile != "":
+		// Only a flags file: load it on its own below
+	default:
+		return nil, "", fmt.Errorf("either --file, --flags-file or --endpoint is required")
+	}
+
+	var items []azure.ConfigItem
+	description := flagsFile
+
+	if source != nil {
+		var err error
+		if items, err = source.Load(ctx); err != nil {
+			return nil, "", err
+		}
+		description = source.String()
+	}
+
+	// A flags file complements a local file; a store already holds its flags
+	if flagsFile != "" && (source == nil || source.File != "") {
+		if _, err := os.Stat(flagsFile); os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("flags file does not exist: %s", flagsFile)
+		}
+
+		settings := configItemsToMap(items)
+		if err := mergeFlagsFile(settings, nil, flagsFile, newFlattener()); err != nil {
+			return nil, "", fmt.Errorf("failed to parse %s: %w", flagsFile, err)
+		}
+
+		items = items[:0]
+		for key, value := range settings {
+			items = append(items, azure.ConfigItem{Key: key, Value: value})
+		}
+	}
+
+	flagItems := make([]azure.ConfigItem, 0, len(items))
+	for _, item := range items {
+		if validator.IsFeatureFlagSetting(item.Key) {
+			flagItems = append(flagItems, item)
+		}
+	}
+
+	return flagItems, description, nil
+}
+
+func runFlagsReport(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	items, _, err := loadFeatureFlagItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	flags := make([]report.Flag, 0, len(items))
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		ff, err := validator.ParseFeatureFlagValue(item.Value)
+		if err != nil {
+			return fmt.Errorf("feature flag %s: %w", validator.FeatureFlagName(item.Key), err)
+		}
+		flags = append(flags, report.Flag{Flag: ff, Label: item.Label, LastModified: item.LastModified})
+		names = append(names, ff.ID)
+	}
+
+	options := report.Options{
+		StaleAfter: time.Duration(flagsStaleDays) * 24 * time.Hour,
+	}
+	if flagsScanDir != "" {
+		// The configuration files mention every flag, so they do not count as references
+		options.References, err = report.ScanReferences(flagsScanDir, names, []string{filePath, flagsFile})
+		if err != nil {
+			return err
+		}
+	}
+
+	reporter := report.NewReporter()
+	findings := reporter.Analyze(flags, options)
+
+	switch output {
+	case "json":
+		jsonBytes, err := reporter.FormatJSON(findings)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonBytes))
+	case "markdown":
+		fmt.Print(reporter.FormatMarkdown(findings))
+	default:
+		fmt.Println(reporter.FormatConsole(findings))
+	}
+
+	if ci && len(findings) > 0 {
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func runFlagsMigrate(cmd *cobra.Command, args []string) error {
+	if endpoint != "" {
+		return migrateStoreFlags(context.Background())
+	}
+	if filePath == "" {
+		return fmt.Errorf("either --file or --endpoint is required")
+	}
+	return migrateFileFlags()
+}
+
+// migrateStoreFlags migrates the heuristic flags of each label in the store
+func migrateStoreFlags(ctx context.Context) error {
+	azureClient, err := newAzureClient(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	items, err := azureClient.FetchAll(ctx, label)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote config: %w", err)
+	}
+
+	// Flag names only have to be unique within a label
+	byLabel := make(map[string]map[string]string)
+	for _, item := range items {
+		if byLabel[item.Label] == nil {
+			byLabel[item.Label] = make(map[string]string)
+		}
+		byLabel[item.Label][item.Key] = item.Value
+	}
+
+	flagValidator := newValidator()
+	var changes []diff.Change
+	for itemLabel, config := range byLabel {
+		migrations, skipped := flagValidator.PlanFeatureFlagMigration(config)
+		printMigrationSkips(skipped)
+
+		labelChanges, err := flagMigrationChanges(migrations, itemLabel)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, labelChanges...)
+	}
+	sortChanges(changes)
+
+	diffEngine := newDiffEngine()
+	if output == "json" {
+		return outputJSON(changes, diffEngine)
+	}
+	fmt.Println(diffEngine.FormatConsole(changes))
+
+	// Exit codes for CI mode; with --apply the flags are migrated without confirmation
+	if ci && !apply {
+		if diffEngine.HasChanges(changes) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if !apply {
+		if diffEngine.HasChanges(changes) {
+			fmt.Println("\nUse --apply to migrate these flags.")
+		}
+		return nil
+	}
+
+	return confirmAndApply(ctx, azureClient, diffEngine, changes, true, label)
+}
+
+// migrateFileFlags migrates the heuristic flags of the local file and rewrites it
+func migrateFileFlags() error {
+	jsonFlattener := newFlattener()
+
+	// Flags kept in a separate file receive the migrated flags
+	flagsTarget := filePath
+	if flagsFile != "" {
+		flagsTarget = flagsFile
+	}
+	if targetFormat := format.Detect(flagsTarget); !targetFormat.HoldsFeatureFlags() {
+		return fmt.Errorf("%s files cannot hold feature flags; use --flags-file to migrate them to a JSON, YAML or TOML file", targetFormat)
+	}
+
+	data, err := readConfigObject(filePath)
+	if err != nil {
+		return err
+	}
+
+	config, err := jsonFlattener.Flatten(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse local config: %w", err)
+	}
+
+	if flagsFile != "" {
+		if _, statErr := os.Stat(flagsFile); statErr == nil {
+			if err := mergeFlagsFile(config, nil, flagsFile, jsonFlattener); err != nil {
+				return fmt.Errorf("failed to parse flags file: %w", err)
+			}
+		}
+	}
+
+	migrations, skipped := newValidator().PlanFeatureFlagMigration(config)
+	printMigrationSkips(skipped)
+
+	changes, err := flagMigrationChanges(migrations, "")
+	if err != nil {
+		return err
+	}
+	sortChanges(changes)
+
+	diffEngine := newDiffEngine()
+	if output == "json" {
+		if err := outputJSON(changes, diffEngine); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println(diffEngine.FormatConsole(changes))
+	}
+
+	// Exit codes for CI mode; with --apply the files are rewritten without confirmation
+	if ci && !apply {
+		if diffEngine.HasChanges(changes) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if !diffEngine.HasChanges(changes) {
+		return nil
+	}
+	if !apply {
+		fmt.Printf("\nUse --apply to rewrite %s.\n", filePath)
+		return nil
+	}
+
+	if !ci && !flagsYes {
+		rewritten := filePath
+		if flagsFile != "" {
+			rewritten += " and " + flagsFile
+		}
+		fmt.Printf("\nMigrate %d flags and rewrite %s? (y/N): ", len(migrations), rewritten)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	// The files are edited in place, so their comments and layout are kept
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	flagsContent := content
+	if flagsFile != "" {
+		if flagsContent, err = os.ReadFile(flagsFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	for _, migration := range migrations {
+		path := []string{validator.FeatureFlagsSection, migration.Flag.ID}
+		edited, err := format.AddValue(flagsContent, format.Detect(flagsTarget), path, validator.FeatureFlagToLocal(migration.Flag))
+		if err != nil {
+			return fmt.Errorf("failed to add %s to %s: %w", migration.Flag.ID, flagsTarget, err)
+		}
+		flagsContent = edited
+		if flagsFile == "" {
+			content = flagsContent
+		}
+
+		if flagsKeepAlias {
+			continue
+		}
+		edited, err = format.RemoveValue(content, format.Detect(filePath), strings.Split(migration.Key, "."))
+		if err != nil {
+			fmt.Printf("⚠️  Could not remove %s from %s; remove it by hand\n", migration.Key, filePath)
+			continue
+		}
+		content = edited
+		if flagsFile == "" {
+			flagsContent = content
+		}
+	}
+
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	if flagsFile != "" {
+		if err := os.WriteFile(flagsFile, flagsContent, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", flagsFile, err)
+		}
+	}
+
+	fmt.Printf("✅ Migrated %d flags\n", len(migrations))
+	return nil
+}
+
+// flagMigrationChanges turns planned migrations into changes under the given label
+func flagMigrationChanges(migrations []validator.FeatureFlagMigration, changeLabel string) ([]diff.Change, error) {
+	var changes []diff.Change
+	for _, migration := range migrations {
+		value, err := validator.FormatFeatureFlagValue(migration.Flag)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, diff.Change{
+			Type:     diff.ChangeTypeAdd,
+			Key:      validator.FeatureFlagKey(migration.Flag.ID),
+			NewValue: value,
+			Label:    changeLabel,
+		})
+
+		if !flagsKeepAlias {
+			changes = append(changes, diff.Change{
+				Type:     diff.ChangeTypeDelete,
+				Key:      migration.Key,
+				OldValue: migration.Value,
+				Label:    changeLabel,
+			})
+		}
+	}
+	return changes, nil
+}
+
+// printMigrationSkips reports heuristic flags that cannot be migrated
+func printMigrationSkips(skipped []validator.FeatureFlagMigrationSkip) {
+	for _, skip := range skipped {
+		fmt.Printf("⚠️  Skipping %s: %s\n", colorize(skip.Key, colorYellow), skip.Reason)
+	}
+}
+
+// sortChanges orders changes by key, then label, like the diff engine does
+func sortChanges(changes []diff.Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Key != changes[j].Key {
+			return changes[i].Key < changes[j].Key
+		}
+		return changes[i].Label < changes[j].Label
+	})
+}
+
+// readConfigObject reads a configuration file, in the format given by its extension,
+// whose top level is an object
+func readConfigObject(path string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := format.Decode(content, format.Detect(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to parse %s: the top level must be an object", path)
+	}
+	return object, nil
+}
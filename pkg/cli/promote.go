@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+	"github.com/chan27-2/appconfigguard/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+// Tags written on promoted settings when --tag-promotion is set
+const (
+	promotedFromTag = "promoted-from"
+	promotedAtTag   = "promoted-at"
+)
+
+var (
+	promoteFromEndpoint string
+	promoteToEndpoint   string
+	promoteFromLabel    string
+	promoteToLabel      string
+	promoteKeys         string
+	promoteTag          bool
+)
+
+// promoteCmd represents the promote command
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Copy settings from one label or store to another",
+	Long: `Copy settings from a source label (and optionally store) to a target label or store.
+
+The standard diff is shown first, and changes are applied through the same sync engine
+and confirmation as the main command. Only keys matching --keys are promoted; with --strict,
+keys matching --keys that are missing from the source are removed from the target.
+
+--from-label and --to-label are given together to promote one label into another. Without
+them, every label of the source store is promoted into the same label of the target store.
+
+EXAMPLES:
+  # Preview promoting API settings from staging to production
+  appconfigguard promote --endpoint=https://mystorage.azconfig.io --from-label=staging --to-label=production --keys='api.*'
+
+  # Promote between two stores and record where the values came from
+  appconfigguard promote --from-endpoint=https://staging.azconfig.io --to-endpoint=https://prod.azconfig.io --tag-promotion --apply`,
+	RunE: runPromote,
+}
+
+func init() {
+	promoteCmd.Flags().StringVarP(&endpoint, "endpoint", "e", "", "Azure App Configuration endpoint used for both source and target")
+	promoteCmd.Flags().StringVar(&promoteFromEndpoint, "from-endpoint", "", "Source endpoint (default: --endpoint)")
+	promoteCmd.Flags().StringVar(&promoteToEndpoint, "to-endpoint", "", "Target endpoint (default: --endpoint)")
+	promoteCmd.Flags().StringVar(&promoteFromLabel, "from-label", "", "Source label")
+	promoteCmd.Flags().StringVar(&promoteToLabel, "to-label", "", "Target label")
+	promoteCmd.Flags().StringVar(&promoteKeys, "keys", "", "Comma-separated key patterns to promote, * matches any characters (default: all keys)")
+	promoteCmd.Flags().BoolVar(&promoteTag, "tag-promotion", false, "Tag promoted settings with their source and promotion time")
+	promoteCmd.Flags().BoolVar(&apply, "apply", false, "Apply the changes after preview (default: dry-run only)")
+	promoteCmd.Flags().BoolVar(&strict, "strict", false, "Remove matching keys from the target that are not in the source")
+	promoteCmd.Flags().BoolVar(&allowPlaintextSecrets, "allow-plaintext-secrets", false, "Promote even when values look like plaintext secrets")
+	promoteCmd.Flags().BoolVar(&ci, "ci", false, "Non-interactive CI/CD mode with machine-readable output")
+	promoteCmd.Flags().StringVarP(&output, "output", "o", "console", "Output format: console, json")
+	promoteCmd.Flags().StringVar(&sentinelKey, "sentinel-key", "", "Key to bump in the target after changes are applied so App Configuration providers refresh")
+	promoteCmd.Flags().StringVar(&sentinelValue, "sentinel-value", "timestamp", "Value written to the sentinel key: timestamp, plan-hash, git-sha")
+}
+
+func runPromote(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	source := &configSource{Endpoint: promoteFromEndpoint, Label: promoteFromLabel}
+	target := &configSource{Endpoint: promoteToEndpoint, Label: promoteToLabel}
+	if source.Endpoint == "" {
+		source.Endpoint = endpoint
+	}
+	if target.Endpoint == "" {
+		target.Endpoint = endpoint
+	}
+
+	if source.Endpoint == "" || target.Endpoint == "" {
+		return fmt.Errorf("source and target endpoints are required (use --endpoint, --from-endpoint or --to-endpoint)")
+	}
+	if (source.Label == "") != (target.Label == "") {
+		return fmt.Errorf("--from-label and --to-label must be given together")
+	}
+	if strings.EqualFold(source.Endpoint, target.Endpoint) && source.Label == target.Label {
+		return fmt.Errorf("source and target are the same: %s", source)
+	}
+
+	patterns, err := compileKeyPatterns(promoteKeys)
+	if err != nil {
+		return err
+	}
+
+	sourceItems, err := source.Load(ctx)
+	if err != nil {
+		return err
+	}
+	sourceItems = promotedItems(filterItemsByKey(sourceItems, patterns), target.Label)
+
+	if len(sourceItems) == 0 {
+		return fmt.Errorf("no settings in %s match the requested keys", source)
+	}
+
+	// Promoted values go through the same validation as local files
+	validationErrors, err := newFlattener().ValidateConfiguration(configItemsToMap(sourceItems))
+	if err != nil {
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+	if len(validationErrors) > 0 {
+		printValidationWarnings(validationErrors)
+	}
+
+	targetClient, err := newAzureClient(target.Endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	targetItems, err := targetClient.FetchAll(ctx, target.Label)
+	if err != nil {
+		return fmt.Errorf("failed to fetch remote config: %w", err)
+	}
+
+	diffEngine := newDiffEngine()
+	changes, err := diffEngine.CompareItems(sourceItems, filterItemsByKey(targetItems, patterns), strict)
+	if err != nil {
+		return fmt.Errorf("failed to generate diff: %w", err)
+	}
+	changes = sync.ProtectSentinel(changes, sentinelKey)
+
+	if promoteTag {
+		tagPromotedChanges(changes, source.String(), time.Now().UTC())
+	}
+
+	if output == "json" {
+		return outputJSON(changes, diffEngine)
+	}
+
+	fmt.Printf("Promoting %s → %s\n\n", source, target)
+	fmt.Println(diffEngine.FormatConsole(changes))
+
+	if ci && !apply {
+		if diffEngine.HasChanges(changes) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if !apply {
+		if diffEngine.HasChanges(changes) {
+			fmt.Println("\nUse --apply to promote these changes.")
+		}
+		return nil
+	}
+
+	return confirmAndApply(ctx, targetClient, diffEngine, changes, strict, target.Label)
+}
+
+// promotedItems moves the source settings onto the target label. Without a target label the
+// settings keep their own labels, so each label is promoted into the same label of the target.
+func promotedItems(items []azure.ConfigItem, targetLabel string) []azure.ConfigItem {
+	if targetLabel == "" {
+		return items
+	}
+
+	promoted := make([]azure.ConfigItem, len(items))
+	for i, item := range items {
+		item.Label = targetLabel
+		promoted[i] = item
+	}
+	return promoted
+}
+
+// tagPromotedChanges records the promotion source and time on added and updated settings,
+// keeping any tags the promoted setting already has
+func tagPromotedChanges(changes []diff.Change, source string, promotedAt time.Time) {
+	for i := range changes {
+		if changes[i].Type == diff.ChangeTypeDelete {
+			continue
+		}
+
+		tags := make(map[string]string, len(changes[i].Tags)+2)
+		for key, value := range changes[i].Tags {
+			tags[key] = value
+		}
+		tags[promotedFromTag] = source
+		tags[promotedAtTag] = promotedAt.Format(time.RFC3339)
+		changes[i].Tags = tags
+		changes[i].WriteTags = true
+	}
+}
+
+// compileKeyPatterns compiles a comma-separated list of key patterns where * matches any characters
+func compileKeyPatterns(raw string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+
+	for _, pattern := range strings.Split(raw, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		compiled, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, compiled)
+	}
+
+	return patterns, nil
+}
+
+// filterItemsByKey keeps the items whose key matches any of the patterns (all items when there are none)
+func filterItemsByKey(items []azure.ConfigItem, patterns []*regexp.Regexp) []azure.ConfigItem {
+	if len(patterns) == 0 {
+		return items
+	}
+
+	filtered := make([]azure.ConfigItem, 0, len(items))
+	for _, item := range items {
+		for _, pattern := range patterns {
+			if pattern.MatchString(item.Key) {
+				filtered = append(filtered, item)
+				break
+			}
+		}
+	}
+	return filtered
+}
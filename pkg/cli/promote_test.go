@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+)
+
+func TestCompileKeyPatterns(t *testing.T) {
+	patterns, err := compileKeyPatterns("app.*, db.host ,,feature.*.enabled")
+	if err != nil {
+		t.Fatalf("compileKeyPatterns() error = %v", err)
+	}
+	if len(patterns) != 3 {
+		t.Fatalf("compileKeyPatterns() returned %d patterns, expected 3", len(patterns))
+	}
+
+	tests := []struct {
+		key      string
+		expected bool
+	}{
+		{"app.name", true},
+		{"app.", true},
+		{"myapp.name", false},
+		{"db.host", true},
+		{"db.hostname", false},
+		{"dbxhost", false},
+		{"feature.beta.enabled", true},
+		{"feature.beta.disabled", false},
+	}
+	for _, tt := range tests {
+		matched := false
+		for _, pattern := range patterns {
+			matched = matched || pattern.MatchString(tt.key)
+		}
+		if matched != tt.expected {
+			t.Errorf("patterns match %s = %v, expected %v", tt.key, matched, tt.expected)
+		}
+	}
+
+	if patterns, err := compileKeyPatterns(""); err != nil || len(patterns) != 0 {
+		t.Errorf("compileKeyPatterns(\"\") = %v, %v, expected no patterns", patterns, err)
+	}
+}
+
+func TestFilterItemsByKey(t *testing.T) {
+	items := []azure.ConfigItem{{Key: "app.name"}, {Key: "app.timeout"}, {Key: "db.host"}}
+
+	if filtered := filterItemsByKey(items, nil); !reflect.DeepEqual(filtered, items) {
+		t.Errorf("filterItemsByKey() without patterns = %v, expected all items", filtered)
+	}
+
+	patterns, err := compileKeyPatterns("app.*")
+	if err != nil {
+		t.Fatalf("compileKeyPatterns() error = %v", err)
+	}
+	expected := []azure.ConfigItem{{Key: "app.name"}, {Key: "app.timeout"}}
+	if filtered := filterItemsByKey(items, patterns); !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("filterItemsByKey() = %v, expected %v", filtered, expected)
+	}
+}
+
+func TestTagPromotedChanges(t *testing.T) {
+	existing := map[string]string{"team": "web"}
+	changes := []diff.Change{
+		{Type: diff.ChangeTypeAdd, Key: "app.color"},
+		{Type: diff.ChangeTypeUpdate, Key: "app.name", Tags: existing},
+		{Type: diff.ChangeTypeDelete, Key: "app.retired", Tags: map[string]string{"team": "ops"}},
+	}
+	promotedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tagPromotedChanges(changes, "https://dev.azconfig.io (label dev)", promotedAt)
+
+	if expected := map[string]string{
+		promotedFromTag: "https://dev.azconfig.io (label dev)",
+		promotedAtTag:   "2024-05-01T12:00:00Z",
+	}; !reflect.DeepEqual(changes[0].Tags, expected) || !changes[0].WriteTags {
+		t.Errorf("added change tags = %v (write %v), expected %v", changes[0].Tags, changes[0].WriteTags, expected)
+	}
+
+	if changes[1].Tags["team"] != "web" || changes[1].Tags[promotedFromTag] == "" || !changes[1].WriteTags {
+		t.Errorf("updated change tags = %v (write %v), expected existing tags plus promotion tags", changes[1].Tags, changes[1].WriteTags)
+	}
+	if len(existing) != 1 {
+		t.Errorf("existing tags were modified: %v", existing)
+	}
+
+	if changes[2].WriteTags || len(changes[2].Tags) != 1 {
+		t.Errorf("deleted change = %+v, expected it to be left alone", changes[2])
+	}
+}
+
+func TestPromotedItems(t *testing.T) {
+	source := []azure.ConfigItem{
+		{Key: "app.name", Value: "staging-app", Label: "staging", ContentType: "text/plain"},
+	}
+	target := []azure.ConfigItem{
+		{Key: "app.name", Value: "prod-app", Label: "production"},
+		{Key: "app.name", Value: "other-app", Label: "other"},
+	}
+
+	changes, err := diff.NewEngine().CompareItems(promotedItems(source, "production"), target, true)
+	if err != nil {
+		t.Fatalf("CompareItems() error = %v", err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("CompareItems() returned %d changes, expected 1: %+v", len(changes), changes)
+	}
+	if change := changes[0]; change.Type != diff.ChangeTypeUpdate || change.Label != "production" ||
+		change.OldValue != "prod-app" || change.NewValue != "staging-app" || change.ContentType != "text/plain" {
+		t.Errorf("change = %+v, expected production update from prod-app to staging-app", change)
+	}
+	if source[0].Label != "staging" {
+		t.Errorf("source items were modified: %+v", source)
+	}
+
+	// Without a target label every setting is promoted into its own label
+	allLabels := []azure.ConfigItem{
+		{Key: "app.name", Value: "a", Label: "staging"},
+		{Key: "app.name", Value: "b", Label: "production"},
+	}
+	if promoted := promotedItems(allLabels, ""); !reflect.DeepEqual(promoted, allLabels) {
+		t.Errorf("promotedItems() without target label = %+v, expected %+v", promoted, allLabels)
+	}
+}
@@ -2,20 +2,27 @@ package cli
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/config"
 	"github.com/chan27-2/appconfigguard/pkg/diff"
+	"github.com/chan27-2/appconfigguard/pkg/format"
 	jsonpkg "github.com/chan27-2/appconfigguard/pkg/json"
+	"github.com/chan27-2/appconfigguard/pkg/redact"
 	"github.com/chan27-2/appconfigguard/pkg/sync"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
 	"github.com/spf13/cobra"
 )
 
 // ANSI color codes for terminal output
 const (
-	colorReset = "\033[0m"
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
 	colorYellow = "\033[33m"
 )
 
@@ -26,22 +33,63 @@ func colorize(text, color string) string {
 
 var (
 	// Global flags
-	filePath    string
-	endpoint    string
-	apply       bool
-	strict      bool
-	ci          bool
-	output      string
-	label       string
-	tags        string
+	filePath       string
+	endpoint       string
+	apply          bool
+	strict         bool
+	ci             bool
+	output         string
+	label          string
+	tags           string
+	snapshotTarget string
+	flagsFile      string
+	configPath     string
+
+	// toolValidator validates configuration values with the rules from the settings file
+	toolValidator *validator.Validator
+
+	// Azure cloud selection
+	cloudName string
+	toolCloud = azure.PublicCloud
+
+	// featureManagement reads feature management sections of local files as feature flags
+	featureManagement bool
+
+	// versionPinning overrides the Key Vault version pinning policy of the settings file
+	versionPinning string
+
+	// showSecrets turns off masking of sensitive values in output
+	showSecrets bool
+
+	// toolRedactor hides sensitive values in output; nil when --show-secrets is given
+	toolRedactor *redact.Redactor
+
+	// Canary flags
+	canary        bool
+	canaryLabel   string
+	canarySettle  time.Duration
+	healthURL     string
+	healthyFor    time.Duration
+	healthTimeout time.Duration
+
+	// Sentinel flags
+	sentinelKey   string
+	sentinelValue string
+
+	// Key Vault verification flags
+	verifySecrets bool
+	vaultEndpoint string
+
+	// allowPlaintextSecrets lets apply push values that look like secrets
+	allowPlaintextSecrets bool
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "appconfigguard",
-	Short: "Safely manage Azure App Configuration with local JSON files",
+	Short: "Safely manage Azure App Configuration with local JSON, YAML, TOML, INI, .env or .properties files",
 	Long: `AppConfigGuard is a CLI tool that helps developers and DevOps teams safely preview,
-verify, and synchronize cloud configuration with local JSON files—reducing risk and making
+verify, and synchronize cloud configuration with local JSON, YAML, TOML, INI, .env or .properties files—reducing risk and making
 config updates predictable and automation-friendly.
 
 AUTHENTICATION:
@@ -78,9 +126,40 @@ EXAMPLES:
   # Use specific label
   appconfigguard --file=config.json --endpoint=https://mystorage.azconfig.io --label=production
 
+  # Compare against a snapshot instead of the live store
+  appconfigguard --file=config.json --endpoint=https://mystorage.azconfig.io --snapshot=release-42
+
+  # Apply to production-canary first, promote once the app stays healthy for a minute
+  appconfigguard --file=config.json --endpoint=https://mystorage.azconfig.io --label=production --apply --canary --health-url=http://localhost:8080/healthz --healthy-for=1m
+
+  # Bump the Sentinel key after applying so apps refresh their configuration
+  appconfigguard --file=config.json --endpoint=https://mystorage.azconfig.io --label=production --strict --apply --sentinel-key=Sentinel
+
+  # Sync a store in Azure China
+  appconfigguard --file=config.json --endpoint=https://mystorage.azconfig.azure.cn --cloud=china
+
+  # Confirm every Key Vault reference points at an existing, enabled secret
+  appconfigguard --file=config.json --endpoint=https://mystorage.azconfig.io --verify-secrets
+
+  # Require every Key Vault reference to be pinned to a secret version
+  appconfigguard --file=config.json --endpoint=https://mystorage.azconfig.io --version-pinning=require-pinned
+
+  # Move plaintext secrets into Key Vault before syncing
+  appconfigguard secrets externalize --file=config.json --vault=myvault --apply
+
+  # Keep feature flags in a separate file
+  appconfigguard --file=config.json --flags-file=flags.json --endpoint=https://mystorage.azconfig.io
+
+  # Check whether a feature flag is on for a user before applying a targeting change
+  appconfigguard flags evaluate Beta --file=config.json --user=alice --groups=beta
+
+  # Run a service locally with the dev label and its Key Vault secrets resolved
+  appconfigguard run --endpoint=https://mystorage.azconfig.io --label=dev -- dotnet run
+
   # Download configuration from Azure
   appconfigguard download --endpoint=https://mystorage.azconfig.io --output=config.json`,
-	RunE: runRoot,
+	PersistentPreRunE: loadSettings,
+	RunE:              runRoot,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -90,7 +169,13 @@ func Execute() error {
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&filePath, "file", "f", "", "Path to local JSON configuration file (required)")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to the AppConfigGuard settings file (default: "+config.DefaultFileName+" if present)")
+	rootCmd.PersistentFlags().StringVar(&versionPinning, "version-pinning", "", "Key Vault reference version policy: allow, require-pinned, forbid-pinned (default: allow, or the settings file)")
+	rootCmd.PersistentFlags().BoolVar(&featureManagement, "feature-management", false, "Read FeatureManagement and feature_management sections of local files as feature flags (default: off, or the settings file)")
+	rootCmd.PersistentFlags().BoolVar(&showSecrets, "show-secrets", false, "Show sensitive values in output instead of masking them (local use only)")
+	rootCmd.PersistentFlags().StringVar(&cloudName, "cloud", "", "Azure cloud: public, china, usgov, custom (default: public, or the settings file)")
+
+	rootCmd.Flags().StringVarP(&filePath, "file", "f", "", "Path to local configuration file: JSON, YAML, TOML, INI, .env or .properties (required)")
 	rootCmd.Flags().StringVarP(&endpoint, "endpoint", "e", "", "Azure App Configuration endpoint URL (required)")
 	rootCmd.Flags().BoolVar(&apply, "apply", false, "Apply the changes after preview (default: dry-run only)")
 	rootCmd.Flags().BoolVar(&strict, "strict", false, "Remove keys from Azure App Config that are not in the local file")
@@ -98,62 +183,250 @@ func init() {
 	rootCmd.Flags().StringVarP(&output, "output", "o", "console", "Output format: console, json")
 	rootCmd.Flags().StringVarP(&label, "label", "l", "", "App Configuration label filter (optional)")
 	rootCmd.Flags().StringVar(&tags, "tags", "", "App Configuration tags filter as key=value pairs (optional)")
+	rootCmd.Flags().StringVar(&flagsFile, "flags-file", "", "Path to a separate file with a featureFlags section (optional)")
+	rootCmd.Flags().StringVar(&snapshotTarget, "snapshot", "", "Compare against this snapshot instead of the live store (read-only)")
+
+	rootCmd.Flags().BoolVar(&canary, "canary", false, "Write changes to a canary label first and promote them once confirmed or healthy")
+	rootCmd.Flags().StringVar(&canaryLabel, "canary-label", "", "Label used for the canary (default: <label>-canary)")
+	rootCmd.Flags().StringVar(&healthURL, "health-url", "", "HTTP endpoint that must stay healthy before the canary is promoted")
+	rootCmd.Flags().DurationVar(&canarySettle, "canary-settle", 30*time.Second, "Time the app gets to load the canary values before --health-url counts; match the provider refresh interval")
+	rootCmd.Flags().DurationVar(&healthyFor, "healthy-for", 30*time.Second, "How long --health-url must stay healthy before promoting")
+	rootCmd.Flags().DurationVar(&healthTimeout, "health-timeout", 5*time.Minute, "Maximum time to wait for the canary to become healthy")
+
+	rootCmd.Flags().StringVar(&sentinelKey, "sentinel-key", "", "Key to bump after changes are applied so App Configuration providers refresh; with --canary it is bumped on the canary label too")
+	rootCmd.Flags().StringVar(&sentinelValue, "sentinel-value", "timestamp", "Value written to the sentinel key: timestamp, plan-hash, git-sha")
+
+	rootCmd.Flags().BoolVar(&verifySecrets, "verify-secrets", false, "Check that referenced Key Vault secrets exist and are enabled")
+	rootCmd.Flags().StringVar(&vaultEndpoint, "vault-endpoint", "", "Send Key Vault verification requests to this endpoint instead of each vault (e.g. a local stub)")
+
+	rootCmd.Flags().BoolVar(&allowPlaintextSecrets, "allow-plaintext-secrets", false, "Apply even when values look like plaintext secrets")
 
 	rootCmd.MarkFlagRequired("file")
 	rootCmd.MarkFlagRequired("endpoint")
 
 	// Add subcommands
 	rootCmd.AddCommand(downloadCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(promoteCmd)
+	rootCmd.AddCommand(flagsCmd)
+	rootCmd.AddCommand(secretsCmd)
+	rootCmd.AddCommand(runCmd)
+}
+
+// loadSettings reads the settings file before any command runs
+func loadSettings(cmd *cobra.Command, args []string) error {
+	settings, err := config.Load(configPath)
+	if err != nil {
+		return err
+	}
+
+	toolValidator, err = validator.NewValidatorWithRules(settings.FlagDetectionRules())
+	if err != nil {
+		return fmt.Errorf("invalid settings file: %w", err)
+	}
+
+	toolCloud, err = settings.AzureCloud(cloudName)
+	if err != nil {
+		return err
+	}
+	toolValidator.SetVaultSuffixes(toolCloud.VaultSuffixes)
+
+	policy := settings.KeyVault.VersionPinning
+	if versionPinning != "" {
+		policy = versionPinning
+	}
+	pinning, err := validator.ParseVersionPinning(policy)
+	if err != nil {
+		return err
+	}
+	toolValidator.SetVersionPinning(pinning)
+
+	if settings.FeatureFlags.FeatureManagement {
+		featureManagement = true
+	}
+
+	toolRedactor = nil
+	if !showSecrets {
+		toolRedactor, err = redact.NewRedactor(toolValidator, settings.RedactionOptions())
+		if err != nil {
+			return fmt.Errorf("invalid settings file: %w", err)
+		}
+	}
+	return nil
+}
+
+// newDiffEngine returns a diff engine that hides sensitive values unless --show-secrets is given
+func newDiffEngine() *diff.Engine {
+	diffEngine := diff.NewEngine()
+	if showSecrets {
+		diffEngine.SetRedactor(nil)
+	} else if toolRedactor != nil {
+		diffEngine.SetRedactor(toolRedactor)
+	}
+	return diffEngine
+}
+
+// newSyncEngine returns a sync engine that hides sensitive values unless --show-secrets is given
+func newSyncEngine(azureClient *azure.Client) *sync.Engine {
+	syncEngine := sync.NewEngine(azureClient)
+	if showSecrets {
+		syncEngine.SetRedactor(nil)
+	} else if toolRedactor != nil {
+		syncEngine.SetRedactor(toolRedactor)
+	}
+	return syncEngine
+}
+
+// pinningViolations returns the validation errors raised by the Key Vault version pinning policy
+func pinningViolations(validationErrors []validator.ValidationError) []validator.ValidationError {
+	var violations []validator.ValidationError
+	for _, validationErr := range validationErrors {
+		if validationErr.Type == validator.ValidationTypeVersionPinning {
+			violations = append(violations, validationErr)
+		}
+	}
+	return violations
+}
+
+// printValidationWarnings prints validation warnings, hiding sensitive values quoted in them
+func printValidationWarnings(validationErrors []validator.ValidationError) {
+	fmt.Println("⚠️  Configuration validation warnings:")
+	for _, validationErr := range validationErrors {
+		message := validationErr.Message
+		if toolRedactor != nil {
+			message = toolRedactor.Text(validationErr.Key, validationErr.Value, message)
+		}
+		fmt.Printf("   %s: %s\n", keyWithLocation(validationErr.Key, validationErr.Location, colorYellow), message)
+	}
+	fmt.Println()
+}
+
+// newAzureClient creates an App Configuration client for a store in the selected cloud
+func newAzureClient(endpoint string) (*azure.Client, error) {
+	if connEndpoint, ignored := azure.IgnoredConnectionString(endpoint); ignored {
+		if connEndpoint == "" {
+			connEndpoint = "no endpoint"
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Ignoring APP_CONFIG_CONNECTION_STRING for %s (it is for %s); using Azure Identity instead\n", endpoint, connEndpoint)
+	}
+	return azure.NewClientForCloud(endpoint, toolCloud)
+}
+
+// newValidator returns a validator using the rules from the settings file
+func newValidator() *validator.Validator {
+	if toolValidator == nil {
+		return validator.NewValidator()
+	}
+	return toolValidator
+}
+
+// newFlattener returns a JSON flattener that validates with the rules from the settings file
+// and reads feature management sections when asked to
+func newFlattener() *jsonpkg.Flattener {
+	flattener := jsonpkg.NewFlattenerWithValidator(newValidator())
+	flattener.SetFeatureManagement(featureManagement)
+	return flattener
 }
 
 func runRoot(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	if snapshotTarget != "" && apply {
+		return fmt.Errorf("snapshots are read-only; use 'appconfigguard snapshot restore' to apply a snapshot")
+	}
+
+	if err := validateCanaryFlags(); err != nil {
+		return err
+	}
+
 	// Validate file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return fmt.Errorf("configuration file does not exist: %s", filePath)
 	}
 
 	// Initialize components
-	jsonFlattener := jsonpkg.NewFlattener()
-	diffEngine := diff.NewEngine()
+	jsonFlattener := newFlattener()
+	diffEngine := newDiffEngine()
 
-	// Parse local JSON file
-	localConfig, err := parseLocalConfig(filePath, jsonFlattener)
+	// Parse local configuration file
+	localConfig, locations, exportItems, err := readLocalConfig(filePath, label, jsonFlattener)
 	if err != nil {
 		return fmt.Errorf("failed to parse local config: %w", err)
 	}
 
+	// Merge feature flags kept in a separate file
+	if flagsFile != "" {
+		if err := mergeFlagsFile(localConfig, locations, flagsFile, jsonFlattener); err != nil {
+			return fmt.Errorf("failed to parse flags file: %w", err)
+		}
+	}
+
+	// Changes and warnings point at the line that defines each key
+	diffEngine.SetLocations(locations)
+
 	// Validate configuration
 	validationErrors, err := jsonFlattener.ValidateConfiguration(localConfig)
 	if err != nil {
 		return fmt.Errorf("failed to validate config: %w", err)
 	}
+	validationErrors = locateErrors(validationErrors, locations)
 
 	// Display validation errors if any
 	if len(validationErrors) > 0 {
-		fmt.Println("⚠️  Configuration validation warnings:")
-		for _, validationErr := range validationErrors {
-			fmt.Printf("   %s: %s\n", colorize(validationErr.Key, colorYellow), validationErr.Message)
+		printValidationWarnings(validationErrors)
+	}
+
+	// Plaintext secrets belong in Key Vault, so they block apply unless explicitly allowed
+	secretFindings := newValidator().ScanSecrets(localConfig)
+	if len(secretFindings) > 0 {
+		fmt.Println("🔑 Possible plaintext secrets:")
+		for _, finding := range secretFindings {
+			fmt.Printf("   %s: %s\n", keyWithLocation(finding.Key, locations[finding.Key], colorYellow), finding.Reason)
 		}
+		fmt.Println("   Move them to Key Vault with 'appconfigguard secrets externalize'.")
 		fmt.Println()
 	}
 
+	// Confirm referenced secrets exist before anything points at them
+	var secretErrors []validator.ValidationError
+	if verifySecrets {
+		secretErrors, err = verifySecretReferences(ctx, localConfig)
+		if err != nil {
+			return err
+		}
+		secretErrors = locateErrors(secretErrors, locations)
+		if len(secretErrors) > 0 {
+			fmt.Fprintln(os.Stderr, "❌ Key Vault reference errors:")
+			for _, validationErr := range secretErrors {
+				fmt.Fprintf(os.Stderr, "   %s: %s\n", keyWithLocation(validationErr.Key, validationErr.Location, colorRed), validationErr.Message)
+			}
+			fmt.Fprintln(os.Stderr)
+		} else if output != "json" {
+			fmt.Println("🔐 All Key Vault references resolved.")
+			fmt.Println()
+		}
+	}
+
 	// Create Azure client and fetch remote config
-	azureClient, err := azure.NewClient(endpoint)
+	azureClient, err := newAzureClient(endpoint)
 	if err != nil {
 		return fmt.Errorf("failed to create Azure client: %w", err)
 	}
 
-	remoteConfig, err := azureClient.FetchAll(ctx, label)
+	var remoteConfig []azure.ConfigItem
+	if snapshotTarget != "" {
+		remoteConfig, err = fetchSnapshotItems(ctx, azureClient, snapshotTarget, label)
+	} else {
+		remoteConfig, err = azureClient.FetchAll(ctx, label)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to fetch remote config: %w", err)
 	}
-
-	// Convert remote config to map for comparison
-	remoteMap := make(map[string]string)
-	for _, item := range remoteConfig {
-		remoteMap[item.Key] = item.Value
+	if snapshotTarget != "" {
+		if err := requireSingleLabel(remoteConfig, snapshotTarget); err != nil {
+			return err
+		}
 	}
 
 	// Generate diff
@@ -162,16 +435,33 @@ func runRoot(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to generate diff: %w", err)
 	}
 
+	// Settings from a kvset export keep their own labels, tags and content types
+	changes = withItemMetadata(changes, exportItems)
+
+	// New keys belong to the label being synchronized
+	if label != "" {
+		changes = withLabel(changes, label)
+	}
+	changes = sync.ProtectSentinel(changes, sentinelKey)
+
 	// Handle output based on mode
 	if output == "json" {
-		return outputJSON(changes, diffEngine)
+		if err := outputJSON(changes, diffEngine); err != nil {
+			return err
+		}
+		return secretReferenceError(secretErrors)
 	}
 
 	// Display changes
 	fmt.Println(diffEngine.FormatConsole(changes))
 
-	// Exit codes for CI mode
-	if ci {
+	// Unresolvable references fail the run, so they are never applied
+	if err := secretReferenceError(secretErrors); err != nil {
+		return err
+	}
+
+	// Exit codes for CI mode; a canary rollout with --apply goes ahead without confirmation
+	if ci && !(apply && canary) {
 		if diffEngine.HasChanges(changes) {
 			os.Exit(1) // Changes detected
 		}
@@ -179,62 +469,151 @@ func runRoot(cmd *cobra.Command, args []string) error {
 	}
 
 	// Apply changes if requested
-	if apply {
-		if !diffEngine.HasChanges(changes) {
-			fmt.Println("No changes to apply.")
-			return nil
+	if apply && canary {
+		return confirmAndApplyCanary(ctx, azureClient, diffEngine, changes, strict)
+	} else if apply {
+		return confirmAndApply(ctx, azureClient, diffEngine, changes, strict, label)
+	} else {
+		if diffEngine.HasChanges(changes) {
+			fmt.Println("\nUse --apply to apply these changes.")
 		}
+	}
 
-		// Confirm with user (unless in CI mode)
-		if !ci {
-			fmt.Print("\nDo you want to apply these changes? (y/N): ")
-			var response string
-			fmt.Scanln(&response)
-			if response != "y" && response != "Y" {
-				fmt.Println("Operation cancelled.")
-				return nil
-			}
+	return nil
+}
+
+// parseLocalConfig reads and flattens the local configuration file, in the format given by its
+// extension. Settings of an az appconfig kv export in the kvset profile are limited to labelFilter.
+func parseLocalConfig(filePath, labelFilter string, flattener *jsonpkg.Flattener) (map[string]string, error) {
+	localConfig, _, _, err := readLocalConfig(filePath, labelFilter, flattener)
+	return localConfig, err
+}
+
+// readLocalConfig reads and flattens the local configuration file like parseLocalConfig, and
+// also returns where each key is defined, as file:line:column. For an az appconfig kv export
+// in the kvset profile it also returns the settings themselves, whose labels, tags and content
+// types a flat configuration cannot hold.
+func readLocalConfig(filePath, labelFilter string, flattener *jsonpkg.Flattener) (map[string]string, map[string]string, []azure.ConfigItem, error) {
+	data, positions, err := format.ReadFileWithPositions(filePath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if azure.IsKVSet(data) {
+		return readKVSet(filePath, labelFilter, data, positions)
+	}
+
+	// Flatten the nested structure
+	localConfig, err := flattener.Flatten(data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	locations := make(map[string]string, len(localConfig))
+	for key := range localConfig {
+		if location := positions.Location(filePath, key); location != "" {
+			locations[key] = location
 		}
+	}
+
+	return localConfig, locations, nil, nil
+}
 
-		syncEngine := sync.NewEngine(azureClient)
+// readKVSet reads the settings of a kvset export with the given label, or with any label when
+// labelFilter is empty, as long as no key is then listed for several labels
+func readKVSet(filePath, labelFilter string, data interface{}, positions format.Positions) (map[string]string, map[string]string, []azure.ConfigItem, error) {
+	items, err := azure.KVSetItems(data, toolCloud)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-		// Validate changes
-		if err := syncEngine.ValidateChanges(changes); err != nil {
-			return fmt.Errorf("validation failed: %w", err)
+	localConfig := make(map[string]string, len(items))
+	locations := make(map[string]string, len(items))
+	labels := make(map[string]string, len(items))
+	var selected []azure.ConfigItem
+	for i, item := range items {
+		if labelFilter != "" && item.Label != labelFilter {
+			continue
 		}
+		if other, exists := labels[item.Key]; exists {
+			return nil, nil, nil, fmt.Errorf("%s is listed for labels %q and %q; choose one with --label, or file:<path>?label= in compare", item.Key, other, item.Label)
+		}
+		labels[item.Key] = item.Label
 
-		// Apply changes
-		fmt.Println("Applying changes...")
-		if err := syncEngine.ApplyChanges(ctx, changes, strict); err != nil {
-			return fmt.Errorf("failed to apply changes: %w", err)
+		localConfig[item.Key] = item.Value
+		if location := positions.Location(filePath, fmt.Sprintf("items.%d.value", i)); location != "" {
+			locations[item.Key] = location
 		}
+		selected = append(selected, item)
+	}
 
-		fmt.Println("Changes applied successfully!")
-	} else {
-		if diffEngine.HasChanges(changes) {
-			fmt.Println("\nUse --apply to apply these changes.")
+	if len(selected) == 0 && len(items) > 0 {
+		return nil, nil, nil, fmt.Errorf("%s has no settings with label %q", filePath, labelFilter)
+	}
+	return localConfig, locations, selected, nil
+}
+
+// mergeFlagsFile reads a feature flags file and adds its flags to the local configuration.
+// When locations is not nil, it receives where each flag is defined.
+func mergeFlagsFile(localConfig map[string]string, locations map[string]string, path string, flattener *jsonpkg.Flattener) error {
+	data, positions, err := format.ReadFileWithPositions(path)
+	if err != nil {
+		return err
+	}
+
+	flags, err := flattener.FlattenFeatureFlags(data)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range flags {
+		if _, exists := localConfig[key]; exists {
+			return fmt.Errorf("feature flag %s is defined in both %s and %s", validator.FeatureFlagName(key), filePath, path)
+		}
+		localConfig[key] = value
+
+		if location := positions.Location(path, key); locations != nil && location != "" {
+			locations[key] = location
 		}
 	}
 
 	return nil
 }
 
-// parseLocalConfig reads and flattens the local JSON configuration file
-func parseLocalConfig(filePath string, flattener *jsonpkg.Flattener) (map[string]string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
+// locateErrors fills in where the value of each validation error is defined
+func locateErrors(validationErrors []validator.ValidationError, locations map[string]string) []validator.ValidationError {
+	for i := range validationErrors {
+		if validationErrors[i].Location == "" {
+			validationErrors[i].Location = locations[validationErrors[i].Key]
+		}
+	}
+	return validationErrors
+}
+
+// keyWithLocation formats a key for console output, followed by where it is defined when known
+func keyWithLocation(key, location, color string) string {
+	if location == "" {
+		return colorize(key, color)
 	}
-	defer file.Close()
+	return fmt.Sprintf("%s (%s)", colorize(key, color), location)
+}
 
-	var data interface{}
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&data); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+// verifySecretReferences checks the Key Vault references in the local configuration against their vaults
+func verifySecretReferences(ctx context.Context, localConfig map[string]string) ([]validator.ValidationError, error) {
+	verifier, err := azure.NewSecretVerifier(vaultEndpoint, toolCloud)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Key Vault verifier: %w", err)
 	}
 
-	// Flatten the JSON structure
-	return flattener.Flatten(data)
+	return verifier.VerifyConfiguration(ctx, localConfig, newValidator()), nil
+}
+
+// secretReferenceError summarizes Key Vault references that could not be verified
+func secretReferenceError(secretErrors []validator.ValidationError) error {
+	if len(secretErrors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d Key Vault references could not be verified", len(secretErrors))
 }
 
 // outputJSON outputs changes in JSON format
@@ -247,3 +626,224 @@ func outputJSON(changes []diff.Change, diffEngine *diff.Engine) error {
 	fmt.Println(string(jsonData))
 	return nil
 }
+
+// checkAppliedValues refuses changes that write Key Vault references breaking the version
+// pinning policy, or values looking like plaintext secrets unless --allow-plaintext-secrets is
+// given. Every apply path runs it before confirming.
+func checkAppliedValues(changes []diff.Change) error {
+	written := make(map[string]string)
+	for _, change := range changes {
+		switch change.Type {
+		case diff.ChangeTypeAdd, diff.ChangeTypeUpdate, diff.ChangeTypeRotate:
+			written[change.Key] = change.NewValue
+		}
+	}
+
+	validationErrors, err := newValidator().ValidateConfiguration(written)
+	if err != nil {
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+	if violations := pinningViolations(validationErrors); len(violations) > 0 {
+		return fmt.Errorf("refusing to apply %d Key Vault references that violate the version pinning policy", len(violations))
+	}
+
+	if findings := newValidator().ScanSecrets(written); len(findings) > 0 && !allowPlaintextSecrets {
+		return fmt.Errorf("refusing to apply %d possible plaintext secrets; move them to Key Vault or use --allow-plaintext-secrets", len(findings))
+	}
+	return nil
+}
+
+// confirmAndApply asks for confirmation and applies the changes through the sync engine,
+// then bumps the sentinel key under targetLabel if one is configured
+func confirmAndApply(ctx context.Context, azureClient *azure.Client, diffEngine *diff.Engine, changes []diff.Change, strict bool, targetLabel string) error {
+	if !diffEngine.HasChanges(changes) {
+		fmt.Println("No changes to apply.")
+		return nil
+	}
+	if err := checkAppliedValues(changes); err != nil {
+		return err
+	}
+
+	// Confirm with user (unless in CI mode)
+	if !ci {
+		fmt.Print("\nDo you want to apply these changes? (y/N): ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	syncEngine := newSyncEngine(azureClient)
+
+	// Validate changes
+	if err := syncEngine.ValidateChanges(changes); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	// Apply changes
+	fmt.Println("Applying changes...")
+	if err := syncEngine.ApplyChanges(ctx, changes, strict); err != nil {
+		return fmt.Errorf("failed to apply changes: %w", err)
+	}
+
+	fmt.Println("Changes applied successfully!")
+	return bumpSentinel(ctx, syncEngine, changes, targetLabel)
+}
+
+// validateCanaryFlags checks the canary flags before anything is applied
+func validateCanaryFlags() error {
+	if !canary {
+		return nil
+	}
+	if ci && apply && healthURL == "" {
+		return fmt.Errorf("--canary in CI mode requires --health-url")
+	}
+	if canaryLabel != "" && canaryLabel == label {
+		return fmt.Errorf("--canary-label must differ from --label %q", label)
+	}
+	if healthURL != "" && healthTimeout > 0 && healthTimeout < healthyFor {
+		return fmt.Errorf("--health-timeout (%v) must be at least --healthy-for (%v)", healthTimeout, healthyFor)
+	}
+	return nil
+}
+
+// confirmAndApplyCanary asks for confirmation, applies the changes to the canary label and
+// promotes them once the canary is confirmed or healthy
+func confirmAndApplyCanary(ctx context.Context, azureClient *azure.Client, diffEngine *diff.Engine, changes []diff.Change, strict bool) error {
+	if !diffEngine.HasChanges(changes) {
+		fmt.Println("No changes to apply.")
+		return nil
+	}
+	if err := checkAppliedValues(changes); err != nil {
+		return err
+	}
+
+	options := sync.CanaryOptions{
+		CanaryLabel:  canaryLabel,
+		HealthURL:    healthURL,
+		SettleTime:   canarySettle,
+		HealthyFor:   healthyFor,
+		Timeout:      healthTimeout,
+		PollInterval: time.Second,
+		Confirm: func() bool {
+			fmt.Print("\nCanary is live. Promote the changes? (y/N): ")
+			var response string
+			fmt.Scanln(&response)
+			return response == "y" || response == "Y"
+		},
+	}
+	if options.CanaryLabel == "" {
+		options.CanaryLabel = sync.CanaryLabel(label)
+	}
+	if sentinelKey != "" {
+		value, err := newSentinelValue(changes)
+		if err != nil {
+			return err
+		}
+		options.SentinelKey, options.SentinelValue = sentinelKey, value
+	}
+
+	if !ci {
+		fmt.Printf("\nDo you want to apply these changes via canary label %s? (y/N): ", options.CanaryLabel)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	syncEngine := newSyncEngine(azureClient)
+
+	// Validate changes
+	if err := syncEngine.ValidateChanges(changes); err != nil {
+		return fmt.Errorf("validation failed: %w", err)
+	}
+
+	if err := syncEngine.ApplyCanary(ctx, changes, strict, options); err != nil {
+		return fmt.Errorf("canary apply failed: %w", err)
+	}
+
+	fmt.Println("Changes applied successfully!")
+	return bumpSentinel(ctx, syncEngine, changes, label)
+}
+
+// bumpSentinel writes a new sentinel value after a non-empty change set has been applied
+func bumpSentinel(ctx context.Context, syncEngine *sync.Engine, changes []diff.Change, targetLabel string) error {
+	if sentinelKey == "" || len(changes) == 0 {
+		return nil
+	}
+
+	value, err := newSentinelValue(changes)
+	if err != nil {
+		return err
+	}
+
+	if err := syncEngine.BumpSentinel(ctx, sentinelKey, targetLabel, value); err != nil {
+		return fmt.Errorf("changes applied, but failed to bump sentinel key %s: %w", sentinelKey, err)
+	}
+
+	fmt.Printf("Sentinel %s set to %s\n", sentinelKey, value)
+	return nil
+}
+
+// newSentinelValue returns the sentinel value for changes, as chosen by --sentinel-value
+func newSentinelValue(changes []diff.Change) (string, error) {
+	switch sentinelValue {
+	case "timestamp":
+		return time.Now().UTC().Format(time.RFC3339), nil
+	case "plan-hash":
+		return sync.PlanHash(changes), nil
+	case "git-sha":
+		out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine git SHA for sentinel: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	default:
+		return "", fmt.Errorf("invalid sentinel value %q: expected timestamp, plan-hash or git-sha", sentinelValue)
+	}
+}
+
+// configItemsToMap converts configuration items into a flat key/value map
+func configItemsToMap(items []azure.ConfigItem) map[string]string {
+	result := make(map[string]string, len(items))
+	for _, item := range items {
+		result[item.Key] = item.Value
+	}
+	return result
+}
+
+// withItemMetadata gives added and updated settings the label, tags and content type of the
+// local item they come from
+func withItemMetadata(changes []diff.Change, items []azure.ConfigItem) []diff.Change {
+	byKey := make(map[string]azure.ConfigItem, len(items))
+	for _, item := range items {
+		byKey[item.Key] = item
+	}
+
+	for i := range changes {
+		item, ok := byKey[changes[i].Key]
+		if !ok || changes[i].Type == diff.ChangeTypeDelete {
+			continue
+		}
+		changes[i].Label = item.Label
+		changes[i].Tags = item.Tags
+		changes[i].WriteTags = true
+		changes[i].ContentType = item.ContentType
+	}
+	return changes
+}
+
+// withLabel puts added keys that have no label of their own under the given label; updates
+// and deletions already carry the label of the setting they change
+func withLabel(changes []diff.Change, targetLabel string) []diff.Change {
+	for i := range changes {
+		if changes[i].Type == diff.ChangeTypeAdd && changes[i].Label == "" {
+			changes[i].Label = targetLabel
+		}
+	}
+	return changes
+}
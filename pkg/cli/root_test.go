@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+func TestWithLabel(t *testing.T) {
+	changes := []diff.Change{
+		{Type: diff.ChangeTypeAdd, Key: "app.new"},
+		{Type: diff.ChangeTypeAdd, Key: "app.imported", Label: "dev"},
+		{Type: diff.ChangeTypeUpdate, Key: "app.name", Label: "staging"},
+		{Type: diff.ChangeTypeDelete, Key: "app.old"},
+	}
+
+	// Only new keys move to the target label; other changes keep the setting's own label
+	expected := []string{"production", "dev", "staging", ""}
+	var labels []string
+	for _, change := range withLabel(changes, "production") {
+		labels = append(labels, change.Label)
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("withLabel() labels = %v, expected %v", labels, expected)
+	}
+}
+
+func TestCheckAppliedValues(t *testing.T) {
+	defer func() { allowPlaintextSecrets = false }()
+
+	secret := []diff.Change{{Type: diff.ChangeTypeUpdate, Key: "database.password", OldValue: "old", NewValue: "hunter2-prod"}}
+	if err := checkAppliedValues(secret); err == nil {
+		t.Errorf("checkAppliedValues() should refuse a plaintext secret")
+	}
+
+	// Deleting a secret or writing a reference is fine
+	safe := []diff.Change{
+		{Type: diff.ChangeTypeDelete, Key: "database.password", OldValue: "hunter2-prod"},
+		{Type: diff.ChangeTypeAdd, Key: "api.key", NewValue: "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/api-key)"},
+	}
+	if err := checkAppliedValues(safe); err != nil {
+		t.Errorf("checkAppliedValues() error = %v", err)
+	}
+
+	allowPlaintextSecrets = true
+	if err := checkAppliedValues(secret); err != nil {
+		t.Errorf("checkAppliedValues() with --allow-plaintext-secrets error = %v", err)
+	}
+}
+
+func TestCheckAppliedValues_VersionPinning(t *testing.T) {
+	toolValidator = validator.NewValidator()
+	toolValidator.SetVersionPinning(validator.PinningRequired)
+	defer func() { toolValidator = nil }()
+
+	unpinned := []diff.Change{{Type: diff.ChangeTypeAdd, Key: "database.password",
+		NewValue: "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-password)"}}
+	if err := checkAppliedValues(unpinned); err == nil {
+		t.Errorf("checkAppliedValues() should refuse an unpinned reference")
+	}
+
+	pinned := []diff.Change{{Type: diff.ChangeTypeAdd, Key: "database.password",
+		NewValue: "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-password/1f0c2a)"}}
+	if err := checkAppliedValues(pinned); err != nil {
+		t.Errorf("checkAppliedValues() error = %v", err)
+	}
+}
+
+func TestCheckAppliedValues_PinnedRotation(t *testing.T) {
+	toolValidator = validator.NewValidator()
+	toolValidator.SetVersionPinning(validator.PinningForbidden)
+	defer func() { toolValidator = nil }()
+
+	rotation := []diff.Change{{Type: diff.ChangeTypeRotate, Key: "database.password",
+		OldValue: "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-password/1f0c2a)",
+		NewValue: "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-password/7b9e4d)"}}
+	if err := checkAppliedValues(rotation); err == nil {
+		t.Errorf("checkAppliedValues() should refuse rotating to a pinned reference under forbid-pinned")
+	}
+}
+
+func TestValidateCanaryFlags(t *testing.T) {
+	defer func() {
+		canary, ci, apply, healthURL, canaryLabel, label = false, false, false, "", "", ""
+		healthyFor, healthTimeout = 0, 0
+	}()
+
+	tests := []struct {
+		name          string
+		ci            bool
+		healthURL     string
+		canaryLabel   string
+		healthyFor    time.Duration
+		healthTimeout time.Duration
+		hasError      bool
+	}{
+		{"health url", false, "http://localhost/health", "", 30 * time.Second, 5 * time.Minute, false},
+		{"ci without health url", true, "", "", 30 * time.Second, 5 * time.Minute, true},
+		{"canary label equals label", false, "", "production", 30 * time.Second, 5 * time.Minute, true},
+		{"timeout shorter than healthy-for", false, "http://localhost/health", "", 5 * time.Minute, time.Minute, true},
+		{"timeout without health url", false, "", "", 5 * time.Minute, time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canary, apply, label = true, true, "production"
+			ci, healthURL, canaryLabel = tt.ci, tt.healthURL, tt.canaryLabel
+			healthyFor, healthTimeout = tt.healthyFor, tt.healthTimeout
+
+			err := validateCanaryFlags()
+			if tt.hasError && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
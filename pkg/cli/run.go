@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/format"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runInject  string
+	runFileEnv string
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run [flags] -- <command> [args...]",
+	Short: "Run a command with configuration from the store and Key Vault secrets resolved",
+	Long: `Run a command locally against the configuration of a store, with every Key Vault
+reference resolved to its secret value using your own Azure credential.
+
+With --inject=env (the default) each setting becomes an environment variable of the command,
+with dots and colons in the key replaced by a double underscore (database.host becomes
+database__host), which .NET and other configuration providers read as nested sections.
+Feature flags without filters become FeatureManagement__<name>=true|false.
+
+With --inject=file the configuration is written as nested JSON to a temporary file and its
+path is passed in the environment variable named by --file-env. The file is only readable by
+you, lives in a memory-backed directory (XDG_RUNTIME_DIR or /dev/shm) so resolved secrets are
+never written to disk, and is removed when the command exits.
+
+EXAMPLES:
+  # Run a .NET service against the dev label
+  appconfigguard run --endpoint=https://mystorage.azconfig.io --label=dev -- dotnet run
+
+  # Pass the configuration as a JSON file instead
+  appconfigguard run --endpoint=https://mystorage.azconfig.io --label=dev --inject=file -- node server.js`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runRun,
+}
+
+func init() {
+	runCmd.Flags().StringVarP(&endpoint, "endpoint", "e", "", "Azure App Configuration endpoint URL (required)")
+	runCmd.Flags().StringVarP(&label, "label", "l", "", "App Configuration label to read (optional)")
+	runCmd.Flags().StringVar(&runInject, "inject", "env", "How to pass the configuration to the command: env, file")
+	runCmd.Flags().StringVar(&runFileEnv, "file-env", "APPCONFIGGUARD_CONFIG_FILE", "Environment variable holding the configuration file path (with --inject=file)")
+	runCmd.Flags().StringVar(&vaultEndpoint, "vault-endpoint", "", "Send Key Vault requests to this endpoint instead of each vault (e.g. a local stub)")
+
+	// Flags after the command belong to the command
+	runCmd.Flags().SetInterspersed(false)
+
+	runCmd.MarkFlagRequired("endpoint")
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if runInject != "env" && runInject != "file" {
+		return fmt.Errorf("invalid --inject %q: expected env or file", runInject)
+	}
+
+	// Progress goes to stderr, so the command's own output stays untouched
+	fmt.Fprintf(os.Stderr, "📥 Fetching configuration from %s\n", (&configSource{Endpoint: endpoint, Label: label}).String())
+
+	azureClient, err := newAzureClient(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	items, err := azureClient.FetchAll(ctx, label)
+	if err != nil {
+		return fmt.Errorf("failed to fetch configuration: %w", err)
+	}
+
+	config := make(map[string]string, len(items))
+	for _, item := range items {
+		if _, exists := config[item.Key]; exists {
+			return fmt.Errorf("key %s has several labels; choose one with --label", item.Key)
+		}
+		config[item.Key] = item.Value
+	}
+
+	resolver, err := azure.NewSecretResolver(vaultEndpoint, toolCloud)
+	if err != nil {
+		return fmt.Errorf("failed to create Key Vault client: %w", err)
+	}
+
+	resolved, err := resolver.ResolveConfiguration(ctx, config, newValidator())
+	if err != nil {
+		return err
+	}
+
+	env := os.Environ()
+	var configFile string
+	if runInject == "file" {
+		if configFile, err = writeRunConfigFile(resolved); err != nil {
+			return err
+		}
+		env = append(env, fmt.Sprintf("%s=%s", runFileEnv, configFile))
+	} else {
+		variables, skipped := configEnvironment(resolved)
+		for _, key := range skipped {
+			fmt.Fprintf(os.Stderr, "⚠️  Skipping feature flag %s: its filters cannot be expressed as an environment variable\n", validator.FeatureFlagName(key))
+		}
+		env = append(env, variables...)
+	}
+
+	fmt.Fprintf(os.Stderr, "🚀 Running %s with %d settings\n", args[0], len(resolved))
+
+	exitCode, err := runChild(args, env)
+	if configFile != "" {
+		os.Remove(configFile)
+	}
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+
+	return nil
+}
+
+// configEnvironment turns a flat configuration into NAME=value environment variables, and
+// returns the feature flag keys that cannot be expressed as one
+func configEnvironment(config map[string]string) ([]string, []string) {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var variables, skipped []string
+	for _, key := range keys {
+		if validator.IsFeatureFlagSetting(key) {
+			ff, err := validator.ParseFeatureFlagValue(config[key])
+			if err != nil || len(ff.Conditions.ClientFilters) > 0 {
+				skipped = append(skipped, key)
+				continue
+			}
+			variables = append(variables, fmt.Sprintf("FeatureManagement__%s=%t", ff.ID, ff.Enabled))
+			continue
+		}
+
+		variables = append(variables, fmt.Sprintf("%s=%s", format.EnvName(key), config[key]))
+	}
+
+	return variables, skipped
+}
+
+// writeRunConfigFile writes a flat configuration as nested JSON to a new file that only the
+// current user can read, in a memory-backed directory, and returns its path
+func writeRunConfigFile(config map[string]string) (string, error) {
+	dir, err := memoryBackedDir()
+	if err != nil {
+		return "", err
+	}
+
+	structured, err := newFlattener().Unflatten(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to unflatten configuration: %w", err)
+	}
+
+	// CreateTemp creates the file with mode 0600
+	file, err := os.CreateTemp(dir, "appconfigguard-*.json")
+	if err != nil {
+		return "", fmt.Errorf("failed to create configuration file: %w", err)
+	}
+	file.Close()
+
+	if err := format.WriteFile(file.Name(), structured, format.JSON); err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+	return file.Name(), nil
+}
+
+// memoryBackedDir returns a directory whose files are kept in memory rather than on disk
+func memoryBackedDir() (string, error) {
+	for _, dir := range []string{os.Getenv("XDG_RUNTIME_DIR"), "/dev/shm"} {
+		if dir == "" {
+			continue
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+
+	return "", fmt.Errorf("no memory-backed directory (XDG_RUNTIME_DIR or /dev/shm) to hold resolved secrets; use --inject=env")
+}
+
+// runChild runs a command with the given environment and standard streams, forwarding
+// interrupts to it, and returns its exit code
+func runChild(args []string, env []string) (int, error) {
+	child := exec.Command(args[0], args[1:]...)
+	child.Env = env
+	child.Stdin = os.Stdin
+	child.Stdout = os.Stdout
+	child.Stderr = os.Stderr
+
+	if err := child.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", args[0], err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			child.Process.Signal(sig)
+		}
+	}()
+
+	err := child.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// A child killed by a signal exits with 128+signal, as shells report it
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to run %s: %w", args[0], err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestConfigEnvironment(t *testing.T) {
+	config := map[string]string{
+		"Database.Host":                    "db.internal",
+		"Logging:LogLevel":                 "Debug",
+		".appconfig.featureflag/Beta":      `{"id":"Beta","enabled":true,"conditions":{"client_filters":[]}}`,
+		".appconfig.featureflag/Rollout":   `{"id":"Rollout","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.Percentage","parameters":{"Value":50}}]}}`,
+		".appconfig.featureflag/Malformed": `{"id":`,
+	}
+
+	variables, skipped := configEnvironment(config)
+
+	expectedVariables := []string{
+		"FeatureManagement__Beta=true",
+		"Database__Host=db.internal",
+		"Logging__LogLevel=Debug",
+	}
+	if !reflect.DeepEqual(variables, expectedVariables) {
+		t.Errorf("configEnvironment() variables = %v, expected %v", variables, expectedVariables)
+	}
+
+	// Flags with filters are only decided at runtime, so they have no single value
+	expectedSkipped := []string{".appconfig.featureflag/Malformed", ".appconfig.featureflag/Rollout"}
+	if !reflect.DeepEqual(skipped, expectedSkipped) {
+		t.Errorf("configEnvironment() skipped = %v, expected %v", skipped, expectedSkipped)
+	}
+}
+
+func TestWriteRunConfigFile(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	path, err := writeRunConfigFile(map[string]string{"Database.Host": "db.internal", "Database.Port": "5432"})
+	if err != nil {
+		t.Fatalf("writeRunConfigFile() error = %v", err)
+	}
+	defer os.Remove(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("os.Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("config file mode = %v, expected 0600", info.Mode().Perm())
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(content, &data); err != nil {
+		t.Fatalf("config file is not JSON: %v", err)
+	}
+	expected := map[string]interface{}{
+		"Database": map[string]interface{}{"Host": "db.internal", "Port": "5432"},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("config file = %v, expected %v", data, expected)
+	}
+}
+
+func TestRunChild_ExitCode(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	tests := []struct {
+		script   string
+		expected int
+	}{
+		{"exit 0", 0},
+		{"exit 3", 3},
+		{"kill -TERM $$", 143},
+	}
+	for _, tt := range tests {
+		code, err := runChild([]string{"sh", "-c", tt.script}, os.Environ())
+		if err != nil {
+			t.Fatalf("runChild(%q) error = %v", tt.script, err)
+		}
+		if code != tt.expected {
+			t.Errorf("runChild(%q) = %d, expected %d", tt.script, code, tt.expected)
+		}
+	}
+}
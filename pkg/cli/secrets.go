@@ -0,0 +1,216 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/format"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	secretsVault     string
+	secretsOverwrite bool
+)
+
+// secretsCmd represents the secrets command
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Find and move plaintext secrets out of configuration files",
+	Long: `Find values that look like plaintext secrets in a local configuration file and move
+them into Key Vault.
+
+Secrets are recognised by key names (password, secret, token, apikey, connectionstring, ...),
+known formats (connection strings with keys or passwords, SAS tokens, JSON Web Tokens, PEM
+private keys, URLs with embedded passwords) and high-entropy values. Key Vault references
+are never reported.`,
+}
+
+var secretsExternalizeCmd = &cobra.Command{
+	Use:   "externalize",
+	Short: "Store plaintext secrets in Key Vault and replace them with references",
+	Long: `Store every plaintext secret found in the local file as a Key Vault secret and rewrite
+the file so the setting holds a Key Vault reference instead. The secret name is the key with
+every character Key Vault does not accept replaced by a dash, e.g. database.credentials.password
+becomes database-credentials-password.
+
+The plan is previewed first; secrets are only written to the vault, and the file rewritten,
+with --apply. Only the rewritten values change, so comments and layout are kept.
+
+Secrets that already exist in the vault are not overwritten unless --overwrite is given.
+When the version pinning policy is require-pinned, the references name the version that
+was stored.
+
+EXAMPLES:
+  # Preview which settings would move to Key Vault
+  appconfigguard secrets externalize --file=config.json --vault=myvault
+
+  # Store them in the vault and rewrite config.json
+  appconfigguard secrets externalize --file=config.json --vault=https://myvault.vault.azure.net --apply`,
+	RunE: runSecretsExternalize,
+}
+
+func init() {
+	secretsExternalizeCmd.Flags().StringVarP(&filePath, "file", "f", "", "Path to local configuration file: JSON, YAML, TOML, INI, .env or .properties (required)")
+	secretsExternalizeCmd.Flags().StringVar(&secretsVault, "vault", "", "Target Key Vault name or URL (required)")
+	secretsExternalizeCmd.Flags().StringVar(&vaultEndpoint, "vault-endpoint", "", "Send Key Vault requests to this endpoint instead of the vault (e.g. a local stub)")
+	secretsExternalizeCmd.Flags().BoolVar(&secretsOverwrite, "overwrite", false, "Store a new version of secrets that already exist in the vault")
+	secretsExternalizeCmd.Flags().BoolVar(&apply, "apply", false, "Store the secrets and rewrite the file after preview (default: dry-run only)")
+	secretsExternalizeCmd.Flags().BoolVar(&ci, "ci", false, "Non-interactive CI/CD mode")
+
+	secretsExternalizeCmd.MarkFlagRequired("file")
+	secretsExternalizeCmd.MarkFlagRequired("vault")
+
+	secretsCmd.AddCommand(secretsExternalizeCmd)
+}
+
+// externalizedSecret is a plaintext setting planned to move into Key Vault
+type externalizedSecret struct {
+	finding    validator.SecretFinding
+	value      string
+	secretName string
+	reference  string
+}
+
+func runSecretsExternalize(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	vaultURL, err := resolveVaultURL(secretsVault)
+	if err != nil {
+		return err
+	}
+
+	jsonFlattener := newFlattener()
+	data, err := readConfigObject(filePath)
+	if err != nil {
+		return err
+	}
+
+	config, err := jsonFlattener.Flatten(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse local config: %w", err)
+	}
+
+	findings := newValidator().ScanSecrets(config)
+	if len(findings) == 0 {
+		fmt.Printf("✨ No plaintext secrets found in %s.\n", filePath)
+		return nil
+	}
+
+	// Distinct keys can map to the same secret name, such as a.b and a_b
+	planned := make([]externalizedSecret, 0, len(findings))
+	used := make(map[string]bool)
+	for _, finding := range findings {
+		name := validator.SecretNameForKey(finding.Key)
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s-%d", validator.SecretNameForKey(finding.Key), i)
+		}
+		used[strings.ToLower(name)] = true
+
+		planned = append(planned, externalizedSecret{
+			finding:    finding,
+			value:      config[finding.Key],
+			secretName: name,
+			reference:  secretReference(vaultURL, name, ""),
+		})
+	}
+
+	fmt.Printf("🔐 Plaintext secrets to move to %s:\n", vaultURL)
+	for _, secret := range planned {
+		fmt.Printf("   %s → %s (%s)\n", colorize(secret.finding.Key, colorYellow), secret.secretName, secret.finding.Reason)
+	}
+
+	if !apply {
+		fmt.Printf("\nUse --apply to store these secrets and rewrite %s.\n", filePath)
+		return nil
+	}
+
+	writer, err := azure.NewSecretWriter(vaultEndpoint, toolCloud)
+	if err != nil {
+		return fmt.Errorf("failed to create Key Vault client: %w", err)
+	}
+
+	// Secrets already in the vault would silently get a new version
+	if !secretsOverwrite {
+		for _, secret := range planned {
+			exists, err := writer.SecretExists(ctx, vaultURL, secret.secretName)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return fmt.Errorf("secret %s already exists in %s; use --overwrite to store a new version", secret.secretName, vaultURL)
+			}
+		}
+	}
+
+	if !ci {
+		fmt.Printf("\nStore %d secrets in %s and rewrite %s? (y/N): ", len(planned), vaultURL, filePath)
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Operation cancelled.")
+			return nil
+		}
+	}
+
+	// Write every secret before touching the file, so a failure leaves the file as it was
+	pinned := newValidator().VersionPinning() == validator.PinningRequired
+	for i, secret := range planned {
+		version, err := writer.SetSecret(ctx, vaultURL, secret.secretName, secret.value)
+		if err != nil {
+			return err
+		}
+		if pinned {
+			if version == "" {
+				return fmt.Errorf("%s returned no version for secret %s, which the version pinning policy requires", vaultURL, secret.secretName)
+			}
+			planned[i].reference = secretReference(vaultURL, secret.secretName, version)
+		}
+	}
+
+	// The file is edited in place, so its comments and layout are kept
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	for _, secret := range planned {
+		edited, err := format.SetValue(content, format.Detect(filePath), strings.Split(secret.finding.Key, "."), secret.reference)
+		if err != nil {
+			fmt.Printf("⚠️  Could not rewrite %s in %s; set it to %s by hand\n", secret.finding.Key, filePath, secret.reference)
+			continue
+		}
+		content = edited
+	}
+
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+
+	fmt.Printf("✅ Moved %d secrets to %s\n", len(planned), vaultURL)
+	return nil
+}
+
+// secretReference returns the Key Vault reference to a secret, pinned to version when one is given
+func secretReference(vaultURL, name, version string) string {
+	if version == "" {
+		return fmt.Sprintf("@Microsoft.KeyVault(SecretUri=%s/secrets/%s)", vaultURL, name)
+	}
+	return fmt.Sprintf("@Microsoft.KeyVault(SecretUri=%s/secrets/%s/%s)", vaultURL, name, version)
+}
+
+// resolveVaultURL turns a vault name or URL into the vault URL in the selected cloud
+func resolveVaultURL(vault string) (string, error) {
+	vaultURL := strings.TrimSuffix(vault, "/")
+	if !strings.Contains(vaultURL, "://") {
+		vaultURL = fmt.Sprintf("https://%s.%s", vaultURL, toolCloud.VaultSuffixes[0])
+	}
+
+	if !toolCloud.IsVaultURI(vaultURL) {
+		return "", fmt.Errorf("%s is not a Key Vault in the %s cloud", vault, toolCloud.Name)
+	}
+	return vaultURL, nil
+}
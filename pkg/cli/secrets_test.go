@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+func TestSecretReference(t *testing.T) {
+	v := validator.NewValidator()
+	v.SetVersionPinning(validator.PinningRequired)
+
+	tests := []struct {
+		version  string
+		expected string
+		pinned   bool
+	}{
+		{"", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-password)", false},
+		{"1f0c2a", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-password/1f0c2a)", true},
+	}
+
+	for _, tt := range tests {
+		reference := secretReference("https://myvault.vault.azure.net", "db-password", tt.version)
+		if reference != tt.expected {
+			t.Errorf("secretReference(%q) = %s, expected %s", tt.version, reference, tt.expected)
+		}
+
+		validationErrors, err := v.ValidateConfiguration(map[string]string{"database.password": reference})
+		if err != nil {
+			t.Fatalf("ValidateConfiguration() error = %v", err)
+		}
+		if passes := len(pinningViolations(validationErrors)) == 0; passes != tt.pinned {
+			t.Errorf("reference %s passes require-pinned = %v, expected %v", reference, passes, tt.pinned)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+	"github.com/chan27-2/appconfigguard/pkg/sync"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotName      string
+	snapshotFilters   []string
+	snapshotRetention time.Duration
+	snapshotLabel     string
+	snapshotFile      string
+	snapshotStrict    bool
+	snapshotOutput    string
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Create, list, diff and restore App Configuration snapshots",
+	Long: `Manage immutable App Configuration snapshots.
+
+Snapshots capture the key-values matching a set of filters at a point in time, which makes
+them a good record of the configuration shipped with each deployment. A snapshot can be
+diffed against a local file or the live store, and restored back into the live store.
+
+EXAMPLES:
+  # Snapshot all production settings
+  appconfigguard snapshot create --endpoint=https://mystorage.azconfig.io --name=release-42 --filter="key=*,label=production"
+
+  # List snapshots
+  appconfigguard snapshot list --endpoint=https://mystorage.azconfig.io
+
+  # Compare a local file with a snapshot
+  appconfigguard snapshot diff release-42 --endpoint=https://mystorage.azconfig.io --file=config.json
+
+  # Preview and apply restoring a snapshot into the production label
+  appconfigguard snapshot restore release-42 --endpoint=https://mystorage.azconfig.io --label=production --apply`,
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a snapshot from key and label filters",
+	RunE:  runSnapshotCreate,
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots in the store",
+	RunE:  runSnapshotList,
+}
+
+var snapshotArchiveCmd = &cobra.Command{
+	Use:   "archive <name>",
+	Short: "Archive a snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotArchive,
+}
+
+var snapshotRecoverCmd = &cobra.Command{
+	Use:   "recover <name>",
+	Short: "Recover an archived snapshot",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotRecover,
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff <name>",
+	Short: "Compare a snapshot with a local file or the live store",
+	Long: `Compare a snapshot with a local file or the live store.
+
+With --file, the snapshot is the target and the output shows how the local file differs from it.
+The file can be JSON, YAML, TOML, INI, .env or .properties. A snapshot holding several labels
+must be narrowed to one with --label.
+Without --file, the snapshot is the source and the output shows what restoring it would change
+in the live store.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSnapshotDiff,
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore <name>",
+	Short: "Restore a snapshot into the live store",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSnapshotRestore,
+}
+
+func init() {
+	snapshotCmd.PersistentFlags().StringVarP(&endpoint, "endpoint", "e", "", "Azure App Configuration endpoint URL (required)")
+	snapshotCmd.MarkPersistentFlagRequired("endpoint")
+
+	snapshotCreateCmd.Flags().StringVar(&snapshotName, "name", "", "Snapshot name (required)")
+	snapshotCreateCmd.Flags().StringArrayVar(&snapshotFilters, "filter", nil, "Key/label filter as key=<pattern>,label=<pattern> (repeatable, default: all settings)")
+	snapshotCreateCmd.Flags().DurationVar(&snapshotRetention, "retention", 0, "How long the snapshot is kept after archiving (default: service default)")
+	snapshotCreateCmd.MarkFlagRequired("name")
+
+	for _, cmd := range []*cobra.Command{snapshotDiffCmd, snapshotRestoreCmd} {
+		cmd.Flags().StringVarP(&snapshotLabel, "label", "l", "", "Only compare settings with this label (optional)")
+		cmd.Flags().BoolVar(&snapshotStrict, "strict", false, "Include keys that are missing from the source as deletions")
+		cmd.Flags().StringVarP(&snapshotOutput, "output", "o", "console", "Output format: console, json")
+	}
+	snapshotDiffCmd.Flags().StringVarP(&snapshotFile, "file", "f", "", "Compare the snapshot with a local configuration file (JSON, YAML, TOML, INI, .env or .properties) instead of the live store")
+	snapshotRestoreCmd.Flags().BoolVar(&apply, "apply", false, "Apply the changes after preview (default: dry-run only)")
+	snapshotRestoreCmd.Flags().BoolVar(&allowPlaintextSecrets, "allow-plaintext-secrets", false, "Restore even when values look like plaintext secrets")
+	snapshotRestoreCmd.Flags().StringVar(&sentinelKey, "sentinel-key", "", "Key to bump after changes are applied so App Configuration providers refresh")
+	snapshotRestoreCmd.Flags().StringVar(&sentinelValue, "sentinel-value", "timestamp", "Value written to the sentinel key: timestamp, plan-hash, git-sha")
+
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotArchiveCmd)
+	snapshotCmd.AddCommand(snapshotRecoverCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+}
+
+func runSnapshotCreate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	filters := make([]azure.SnapshotFilter, 0, len(snapshotFilters))
+	for _, raw := range snapshotFilters {
+		filter, err := parseSnapshotFilter(raw)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, filter)
+	}
+
+	azureClient, err := newAzureClient(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	fmt.Printf("📸 Creating snapshot %s...\n", snapshotName)
+	snapshot, err := azureClient.CreateSnapshot(ctx, snapshotName, filters, snapshotRetention)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Snapshot %s is %s with %d items\n", snapshot.Name, snapshot.Status, snapshot.ItemsCount)
+	return nil
+}
+
+func runSnapshotList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	azureClient, err := newAzureClient(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	snapshots, err := azureClient.ListSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots found.")
+		return nil
+	}
+
+	fmt.Printf("%-32s %-12s %8s  %-20s %s\n", "NAME", "STATUS", "ITEMS", "CREATED", "FILTERS")
+	for _, snapshot := range snapshots {
+		fmt.Printf("%-32s %-12s %8d  %-20s %s\n",
+			snapshot.Name,
+			snapshot.Status,
+			snapshot.ItemsCount,
+			snapshot.Created.Format(time.DateTime),
+			formatSnapshotFilters(snapshot.Filters))
+	}
+
+	return nil
+}
+
+func runSnapshotArchive(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	azureClient, err := newAzureClient(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	snapshot, err := azureClient.ArchiveSnapshot(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Snapshot %s is now %s\n", snapshot.Name, snapshot.Status)
+	if snapshot.Expires != nil {
+		fmt.Printf("   Expires: %s\n", snapshot.Expires.Format(time.DateTime))
+	}
+	return nil
+}
+
+func runSnapshotRecover(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	azureClient, err := newAzureClient(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	snapshot, err := azureClient.RecoverSnapshot(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Snapshot %s is now %s\n", snapshot.Name, snapshot.Status)
+	return nil
+}
+
+func runSnapshotDiff(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	azureClient, err := newAzureClient(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	diffEngine := newDiffEngine()
+
+	var changes []diff.Change
+	if snapshotFile != "" {
+		// Snapshot as the target: how does the local file differ from it?
+		if _, err := os.Stat(snapshotFile); os.IsNotExist(err) {
+			return fmt.Errorf("configuration file does not exist: %s", snapshotFile)
+		}
+
+		localConfig, err := parseLocalConfig(snapshotFile, snapshotLabel, newFlattener())
+		if err != nil {
+			return fmt.Errorf("failed to parse local config: %w", err)
+		}
+
+		snapshotItems, err := fetchSnapshotItems(ctx, azureClient, args[0], snapshotLabel)
+		if err != nil {
+			return err
+		}
+		if err := requireSingleLabel(snapshotItems, args[0]); err != nil {
+			return err
+		}
+
+		changes, err = diffEngine.Compare(localConfig, snapshotItems, snapshotStrict)
+		if err != nil {
+			return fmt.Errorf("failed to generate diff: %w", err)
+		}
+	} else {
+		changes, err = compareSnapshotWithStore(ctx, azureClient, diffEngine, args[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	if snapshotOutput == "json" {
+		return outputJSON(changes, diffEngine)
+	}
+
+	fmt.Println(diffEngine.FormatConsole(changes))
+	return nil
+}
+
+func runSnapshotRestore(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	azureClient, err := newAzureClient(endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	diffEngine := newDiffEngine()
+
+	changes, err := compareSnapshotWithStore(ctx, azureClient, diffEngine, args[0])
+	if err != nil {
+		return err
+	}
+
+	if snapshotOutput == "json" && !apply {
+		return outputJSON(changes, diffEngine)
+	}
+
+	fmt.Println(diffEngine.FormatConsole(changes))
+
+	if !apply {
+		if diffEngine.HasChanges(changes) {
+			fmt.Println("\nUse --apply to restore this snapshot.")
+		}
+		return nil
+	}
+
+	return confirmAndApply(ctx, azureClient, diffEngine, changes, snapshotStrict, snapshotLabel)
+}
+
+// compareSnapshotWithStore diffs a snapshot (as the source) against the live store (as the target)
+func compareSnapshotWithStore(ctx context.Context, azureClient *azure.Client, diffEngine *diff.Engine, name string) ([]diff.Change, error) {
+	snapshotItems, err := fetchSnapshotItems(ctx, azureClient, name, snapshotLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteConfig, err := azureClient.FetchAll(ctx, snapshotLabel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch remote config: %w", err)
+	}
+
+	return snapshotRestoreChanges(diffEngine, snapshotItems, remoteConfig, snapshotStrict, sentinelKey)
+}
+
+// snapshotRestoreChanges returns the changes that restore snapshot items into the store. Each
+// item is compared with the setting of the same key and label, and is restored into its own
+// label with its tags and content type.
+func snapshotRestoreChanges(diffEngine *diff.Engine, snapshotItems, remoteConfig []azure.ConfigItem, strict bool, sentinel string) ([]diff.Change, error) {
+	changes, err := diffEngine.CompareItems(snapshotItems, remoteConfig, strict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate diff: %w", err)
+	}
+
+	return sync.ProtectSentinel(changes, sentinel), nil
+}
+
+// fetchSnapshotItems retrieves the items of a snapshot, keeping only those with the given label
+func fetchSnapshotItems(ctx context.Context, azureClient *azure.Client, name, labelFilter string) ([]azure.ConfigItem, error) {
+	items, err := azureClient.FetchSnapshot(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if labelFilter == "" {
+		return items, nil
+	}
+
+	filtered := make([]azure.ConfigItem, 0, len(items))
+	for _, item := range items {
+		if item.Label == labelFilter {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered, nil
+}
+
+// requireSingleLabel rejects snapshot items spanning several labels, which cannot be compared
+// with a local file keyed by key only
+func requireSingleLabel(items []azure.ConfigItem, name string) error {
+	if _, ok := sharedLabel(items, ""); ok {
+		return nil
+	}
+
+	labels := make(map[string]bool)
+	for _, item := range items {
+		labels[fmt.Sprintf("%q", item.Label)] = true
+	}
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("snapshot %s holds settings under several labels (%s); choose one with --label", name, strings.Join(names, ", "))
+}
+
+// parseSnapshotFilter parses a filter in the form key=<pattern>,label=<pattern>
+func parseSnapshotFilter(raw string) (azure.SnapshotFilter, error) {
+	filter := azure.SnapshotFilter{Key: "*"}
+
+	for _, part := range strings.Split(raw, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return filter, fmt.Errorf("invalid snapshot filter %q: expected key=<pattern>,label=<pattern>", raw)
+		}
+
+		switch strings.TrimSpace(kv[0]) {
+		case "key":
+			filter.Key = strings.TrimSpace(kv[1])
+		case "label":
+			filter.Label = strings.TrimSpace(kv[1])
+		default:
+			return filter, fmt.Errorf("invalid snapshot filter %q: unknown field %s", raw, kv[0])
+		}
+	}
+
+	return filter, nil
+}
+
+// formatSnapshotFilters renders snapshot filters for display
+func formatSnapshotFilters(filters []azure.SnapshotFilter) string {
+	parts := make([]string, 0, len(filters))
+	for _, filter := range filters {
+		part := "key=" + filter.Key
+		if filter.Label != "" {
+			part += ",label=" + filter.Label
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
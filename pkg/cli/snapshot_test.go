@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+)
+
+func TestParseSnapshotFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected azure.SnapshotFilter
+		hasError bool
+	}{
+		{"key and label", "key=app.*,label=production", azure.SnapshotFilter{Key: "app.*", Label: "production"}, false},
+		{"label only", "label=production", azure.SnapshotFilter{Key: "*", Label: "production"}, false},
+		{"spaces", " key = db.* , label = dev ", azure.SnapshotFilter{Key: "db.*", Label: "dev"}, false},
+		{"missing value", "key", azure.SnapshotFilter{}, true},
+		{"unknown field", "tag=team", azure.SnapshotFilter{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseSnapshotFilter(tt.raw)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSnapshotFilter() error = %v", err)
+			}
+			if filter != tt.expected {
+				t.Errorf("parseSnapshotFilter() = %+v, expected %+v", filter, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSnapshotRestoreChanges(t *testing.T) {
+	snapshotItems := []azure.ConfigItem{
+		{Key: "app.name", Value: "shop", Label: "production", Tags: map[string]string{"team": "web"}},
+		{Key: "app.name", Value: "shop-dev", Label: "dev"},
+		{Key: "app.timeout", Value: "30", Label: "production", ContentType: "text/plain"},
+		{Key: "app.color", Value: "blue"},
+	}
+	remoteItems := []azure.ConfigItem{
+		{Key: "app.name", Value: "shop", Label: "production"},
+		{Key: "app.name", Value: "old-dev", Label: "dev"},
+		{Key: "app.retired", Value: "x", Label: "production"},
+		{Key: "app.other", Value: "y", Label: "staging"},
+		{Key: "refresh", Value: "1", Label: "production"},
+	}
+
+	t.Run("each item keeps its own label", func(t *testing.T) {
+		changes, err := snapshotRestoreChanges(diff.NewEngine(), snapshotItems, remoteItems, false, "")
+		if err != nil {
+			t.Fatalf("snapshotRestoreChanges() error = %v", err)
+		}
+
+		expected := []diff.Change{
+			{Type: diff.ChangeTypeAdd, Key: "app.color", NewValue: "blue", WriteTags: true},
+			{Type: diff.ChangeTypeUpdate, Key: "app.name", OldValue: "old-dev", NewValue: "shop-dev", Label: "dev", WriteTags: true},
+			{Type: diff.ChangeTypeAdd, Key: "app.timeout", NewValue: "30", Label: "production", WriteTags: true, ContentType: "text/plain"},
+		}
+		if !reflect.DeepEqual(changes, expected) {
+			t.Errorf("snapshotRestoreChanges() = %+v, expected %+v", changes, expected)
+		}
+	})
+
+	t.Run("strict deletes only under the snapshot's labels", func(t *testing.T) {
+		changes, err := snapshotRestoreChanges(diff.NewEngine(), snapshotItems, remoteItems, true, "refresh")
+		if err != nil {
+			t.Fatalf("snapshotRestoreChanges() error = %v", err)
+		}
+
+		var deleted []string
+		for _, change := range changes {
+			if change.Type == diff.ChangeTypeDelete {
+				deleted = append(deleted, change.Key+"@"+change.Label)
+			}
+		}
+		if !reflect.DeepEqual(deleted, []string{"app.retired@production"}) {
+			t.Errorf("deleted = %v, expected only app.retired@production", deleted)
+		}
+	})
+}
+
+func TestRequireSingleLabel(t *testing.T) {
+	single := []azure.ConfigItem{{Key: "app.name", Label: "prod"}, {Key: "app.color", Label: "prod"}}
+	if err := requireSingleLabel(single, "rel-1"); err != nil {
+		t.Errorf("requireSingleLabel() error = %v, expected none for a single label", err)
+	}
+	if err := requireSingleLabel(nil, "rel-1"); err != nil {
+		t.Errorf("requireSingleLabel() error = %v, expected none for an empty snapshot", err)
+	}
+
+	mixed := []azure.ConfigItem{{Key: "app.name", Label: "prod"}, {Key: "app.name"}}
+	err := requireSingleLabel(mixed, "rel-1")
+	if err == nil {
+		t.Fatal("requireSingleLabel() expected an error for several labels")
+	}
+	if expected := `snapshot rel-1 holds settings under several labels ("", "prod"); choose one with --label`; err.Error() != expected {
+		t.Errorf("requireSingleLabel() error = %q, expected %q", err, expected)
+	}
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+)
+
+// configSource describes where a set of configuration values is loaded from:
+// a local file, a label of a live store, or a snapshot
+type configSource struct {
+	File     string
+	Endpoint string
+	Label    string
+	Snapshot string
+}
+
+// parseSource parses a source specification. Supported forms:
+//   - config.json or file:config.json           local file
+//   - file:export.json?label=staging            label of a kvset export
+//   - https://store.azconfig.io?label=staging   live store, optionally filtered by label
+//   - https://store.azconfig.io?snapshot=rel-1  snapshot in a store
+//   - label:staging                             label of the store given by --endpoint
+//   - snapshot:rel-1                            snapshot of the store given by --endpoint
+func parseSource(spec, defaultEndpoint string) (*configSource, error) {
+	switch {
+	case strings.HasPrefix(spec, "file:"):
+		path, rawQuery, _ := strings.Cut(strings.TrimPrefix(spec, "file:"), "?")
+		query, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source %q: %w", spec, err)
+		}
+		return &configSource{File: path, Label: query.Get("label")}, nil
+
+	case strings.HasPrefix(spec, "label:"):
+		if defaultEndpoint == "" {
+			return nil, fmt.Errorf("source %q requires --endpoint", spec)
+		}
+		if spec == "label:" {
+			return nil, fmt.Errorf("invalid source %q: missing label name", spec)
+		}
+		return &configSource{Endpoint: defaultEndpoint, Label: strings.TrimPrefix(spec, "label:")}, nil
+
+	case strings.HasPrefix(spec, "snapshot:"):
+		if defaultEndpoint == "" {
+			return nil, fmt.Errorf("source %q requires --endpoint", spec)
+		}
+		if spec == "snapshot:" {
+			return nil, fmt.Errorf("invalid source %q: missing snapshot name", spec)
+		}
+		return &configSource{Endpoint: defaultEndpoint, Snapshot: strings.TrimPrefix(spec, "snapshot:")}, nil
+
+	case strings.HasPrefix(spec, "https://"):
+		parsedURL, err := url.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source %q: %w", spec, err)
+		}
+		if parsedURL.Host == "" {
+			return nil, fmt.Errorf("invalid source %q: missing store host", spec)
+		}
+
+		query := parsedURL.Query()
+		source := &configSource{
+			Endpoint: fmt.Sprintf("https://%s", parsedURL.Host),
+			Label:    query.Get("label"),
+			Snapshot: query.Get("snapshot"),
+		}
+		if source.Label != "" && source.Snapshot != "" {
+			return nil, fmt.Errorf("invalid source %q: label and snapshot cannot be combined", spec)
+		}
+		return source, nil
+	}
+
+	return &configSource{File: spec}, nil
+}
+
+// Load retrieves the configuration items of the source
+func (s *configSource) Load(ctx context.Context) ([]azure.ConfigItem, error) {
+	if s.File != "" {
+		if _, err := os.Stat(s.File); os.IsNotExist(err) {
+			return nil, fmt.Errorf("configuration file does not exist: %s", s.File)
+		}
+
+		localConfig, _, exportItems, err := readLocalConfig(s.File, s.Label, newFlattener())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", s.File, err)
+		}
+
+		// A kvset export keeps the labels, tags and content types of its settings
+		if exportItems != nil {
+			return exportItems, nil
+		}
+
+		items := make([]azure.ConfigItem, 0, len(localConfig))
+		for key, value := range localConfig {
+			items = append(items, azure.ConfigItem{Key: key, Value: value})
+		}
+		return items, nil
+	}
+
+	azureClient, err := newAzureClient(s.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client for %s: %w", s.Endpoint, err)
+	}
+
+	if s.Snapshot != "" {
+		return azureClient.FetchSnapshot(ctx, s.Snapshot)
+	}
+
+	items, err := azureClient.FetchAll(ctx, s.Label)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", s, err)
+	}
+	return items, nil
+}
+
+// String returns a human-readable description of the source
+func (s *configSource) String() string {
+	switch {
+	case s.File != "" && s.Label != "":
+		return fmt.Sprintf("%s (label %s)", s.File, s.Label)
+	case s.File != "":
+		return s.File
+	case s.Snapshot != "":
+		return fmt.Sprintf("%s (snapshot %s)", s.Endpoint, s.Snapshot)
+	case s.Label != "":
+		return fmt.Sprintf("%s (label %s)", s.Endpoint, s.Label)
+	default:
+		return s.Endpoint
+	}
+}
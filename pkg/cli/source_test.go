@@ -0,0 +1,49 @@
+package cli
+
+import "testing"
+
+func TestParseSource(t *testing.T) {
+	const defaultEndpoint = "https://default.azconfig.io"
+
+	tests := []struct {
+		name            string
+		spec            string
+		defaultEndpoint string
+		expected        configSource
+		hasError        bool
+	}{
+		{"bare file", "config.json", "", configSource{File: "config.json"}, false},
+		{"file prefix", "file:config.yaml", "", configSource{File: "config.yaml"}, false},
+		{"kvset export label", "file:export.json?label=staging", "", configSource{File: "export.json", Label: "staging"}, false},
+		{"store", "https://mystore.azconfig.io", "", configSource{Endpoint: "https://mystore.azconfig.io"}, false},
+		{"store label", "https://mystore.azconfig.io?label=staging", "", configSource{Endpoint: "https://mystore.azconfig.io", Label: "staging"}, false},
+		{"store snapshot", "https://mystore.azconfig.io/?snapshot=rel-1", "", configSource{Endpoint: "https://mystore.azconfig.io", Snapshot: "rel-1"}, false},
+		{"label of default store", "label:staging", defaultEndpoint, configSource{Endpoint: defaultEndpoint, Label: "staging"}, false},
+		{"snapshot of default store", "snapshot:rel-1", defaultEndpoint, configSource{Endpoint: defaultEndpoint, Snapshot: "rel-1"}, false},
+		{"label without endpoint", "label:staging", "", configSource{}, true},
+		{"snapshot without endpoint", "snapshot:rel-1", "", configSource{}, true},
+		{"empty label", "label:", defaultEndpoint, configSource{}, true},
+		{"empty snapshot", "snapshot:", defaultEndpoint, configSource{}, true},
+		{"label and snapshot", "https://mystore.azconfig.io?label=a&snapshot=b", "", configSource{}, true},
+		{"store without host", "https:///path", "", configSource{}, true},
+		{"malformed query", "file:export.json?label=%zz", "", configSource{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := parseSource(tt.spec, tt.defaultEndpoint)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("expected error but got none, source = %+v", source)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSource() error = %v", err)
+			}
+			if *source != tt.expected {
+				t.Errorf("parseSource() = %+v, expected %+v", *source, tt.expected)
+			}
+		})
+	}
+}
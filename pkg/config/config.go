@@ -0,0 +1,128 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/redact"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+// DefaultFileName is the settings file read from the working directory when no path is given
+const DefaultFileName = ".appconfigguard.json"
+
+// Config holds the tool's own settings, as opposed to the configuration it synchronizes
+type Config struct {
+	FeatureFlags FeatureFlagRules `json:"featureFlags"`
+	Cloud        CloudSettings    `json:"cloud"`
+	Redaction    RedactionRules   `json:"redaction"`
+	KeyVault     KeyVaultRules    `json:"keyVault"`
+}
+
+// KeyVaultRules holds policies for Key Vault references
+type KeyVaultRules struct {
+	// VersionPinning is allow, require-pinned or forbid-pinned
+	VersionPinning string `json:"versionPinning,omitempty"`
+}
+
+// RedactionRules controls how sensitive values are hidden in output. Keys matching Sensitive
+// are always hidden, in addition to values that look like secrets.
+type RedactionRules struct {
+	// Mode is mask or hash. Hashes are keyed per run, so they only show which values are
+	// equal within one run and cannot be compared across runs.
+	Mode      string   `json:"mode,omitempty"`
+	Sensitive []string `json:"sensitive,omitempty"`
+}
+
+// CloudSettings selects the Azure cloud. A custom cloud is described by its authority host,
+// App Configuration audience and Key Vault host suffixes.
+type CloudSettings struct {
+	Name              string   `json:"name,omitempty"`
+	AuthorityHost     string   `json:"authorityHost,omitempty"`
+	AppConfigAudience string   `json:"appConfigAudience,omitempty"`
+	VaultSuffixes     []string `json:"vaultSuffixes,omitempty"`
+}
+
+// FeatureFlagRules overrides how boolean settings are recognised as feature flags.
+// Fields that are not set keep their built-in defaults.
+type FeatureFlagRules struct {
+	Heuristics         *bool    `json:"heuristics,omitempty"`
+	Patterns           []string `json:"patterns,omitempty"`
+	Truthy             []string `json:"truthy,omitempty"`
+	Falsy              []string `json:"falsy,omitempty"`
+	RequireDescription *bool    `json:"requireDescription,omitempty"`
+	// FeatureManagement reads FeatureManagement and feature_management sections of local
+	// files as feature flags instead of plain settings
+	FeatureManagement bool `json:"featureManagement,omitempty"`
+}
+
+// Load reads the settings file at path. With an empty path the default file is read if it
+// exists; otherwise the built-in defaults are used.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		if _, err := os.Stat(DefaultFileName); err != nil {
+			return &Config{}, nil
+		}
+		path = DefaultFileName
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse settings file %s: %w", path, err)
+	}
+
+	return &cfg, nil
+}
+
+// FlagDetectionRules merges the configured feature flag rules with the built-in defaults
+func (c *Config) FlagDetectionRules() validator.FlagDetectionRules {
+	rules := validator.DefaultFlagDetectionRules()
+	configured := c.FeatureFlags
+
+	if configured.Heuristics != nil {
+		rules.Heuristics = *configured.Heuristics
+	}
+	if configured.Patterns != nil {
+		rules.Patterns = configured.Patterns
+	}
+	if configured.Truthy != nil {
+		rules.Truthy = configured.Truthy
+	}
+	if configured.Falsy != nil {
+		rules.Falsy = configured.Falsy
+	}
+	if configured.RequireDescription != nil {
+		rules.RequireDescription = *configured.RequireDescription
+	}
+
+	return rules
+}
+
+// AzureCloud resolves the configured Azure cloud. A non-empty name, such as from --cloud,
+// takes precedence over the name in the settings file.
+func (c *Config) AzureCloud(name string) (azure.Cloud, error) {
+	if name == "" {
+		name = c.Cloud.Name
+	}
+
+	if strings.EqualFold(name, "custom") {
+		return azure.NewCustomCloud(c.Cloud.AuthorityHost, c.Cloud.AppConfigAudience, c.Cloud.VaultSuffixes)
+	}
+	return azure.LookupCloud(name)
+}
+
+// RedactionOptions returns the configured output redaction
+func (c *Config) RedactionOptions() redact.Options {
+	return redact.Options{
+		Mode:     redact.Mode(c.Redaction.Mode),
+		Patterns: c.Redaction.Sensitive,
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	content := `{"featureFlags":{"heuristics":true,"patterns":["ff_*"],"requireDescription":false}}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	rules := cfg.FlagDetectionRules()
+	defaults := validator.DefaultFlagDetectionRules()
+
+	if !reflect.DeepEqual(rules.Patterns, []string{"ff_*"}) {
+		t.Errorf("expected configured patterns, got %v", rules.Patterns)
+	}
+	if rules.RequireDescription {
+		t.Errorf("expected requireDescription to be disabled")
+	}
+	if !reflect.DeepEqual(rules.Truthy, defaults.Truthy) || !reflect.DeepEqual(rules.Falsy, defaults.Falsy) {
+		t.Errorf("expected unset vocabularies to keep their defaults, got %v / %v", rules.Truthy, rules.Falsy)
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(cfg.FlagDetectionRules(), validator.DefaultFlagDetectionRules()) {
+		t.Errorf("expected default rules without a settings file")
+	}
+
+	if _, err := Load("missing.json"); err == nil {
+		t.Errorf("expected an error for an explicit missing settings file")
+	}
+}
+
+func TestAzureCloud(t *testing.T) {
+	custom := &Config{Cloud: CloudSettings{
+		Name:              "custom",
+		AuthorityHost:     "https://login.example.com",
+		AppConfigAudience: "https://appconfig.example.com",
+		VaultSuffixes:     []string{"vault.example.com"},
+	}}
+
+	tests := []struct {
+		name          string
+		cfg           *Config
+		flag          string
+		expectedName  string
+		expectedVault string
+		hasError      bool
+	}{
+		{"default is public", &Config{}, "", "public", "vault.azure.net", false},
+		{"settings file", &Config{Cloud: CloudSettings{Name: "china"}}, "", "china", "vault.azure.cn", false},
+		{"flag overrides settings", &Config{Cloud: CloudSettings{Name: "china"}}, "usgov", "usgov", "vault.usgovcloudapi.net", false},
+		{"custom", custom, "", "custom", "vault.example.com", false},
+		{"custom without settings", &Config{}, "custom", "", "", true},
+		{"unknown cloud", &Config{}, "mars", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloud, err := tt.cfg.AzureCloud(tt.flag)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("expected error, got cloud %+v", cloud)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cloud.Name != tt.expectedName {
+				t.Errorf("expected cloud %s, got %s", tt.expectedName, cloud.Name)
+			}
+			if !cloud.IsVaultURI("https://myvault." + tt.expectedVault + "/secrets/db") {
+				t.Errorf("expected %s to be a vault host in %s", tt.expectedVault, cloud.Name)
+			}
+		})
+	}
+}
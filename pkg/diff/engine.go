@@ -7,6 +7,8 @@ import (
 	"strings"
 
 	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/redact"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
 )
 
 // ChangeType represents the type of change
@@ -16,6 +18,8 @@ const (
 	ChangeTypeAdd    ChangeType = "add"
 	ChangeTypeUpdate ChangeType = "update"
 	ChangeTypeDelete ChangeType = "delete"
+	// ChangeTypeRotate is an update that only moves a Key Vault reference to another secret version
+	ChangeTypeRotate ChangeType = "rotate"
 )
 
 // ANSI color codes for terminal output
@@ -53,6 +57,14 @@ type Change struct {
 	NewValue string
 	Label    string
 	Tags     map[string]string
+	// WriteTags stores Tags with the new value. Otherwise Tags only describes the existing
+	// setting, and the value is written without tags.
+	WriteTags bool
+	// ContentType is the content type to store the new value with, when the local source
+	// carries one; otherwise it is detected from the value
+	ContentType string
+	// Location is where the new value is defined in the local file, as file:line:column
+	Location string
 }
 
 // Summary provides a summary of changes
@@ -60,6 +72,7 @@ type Summary struct {
 	Added   int
 	Updated int
 	Deleted int
+	Rotated int
 	Total   int
 }
 
@@ -80,6 +93,8 @@ func formatChangeSymbol(changeType ChangeType) string {
 		return colorize("🔄", colorBoldYellow)
 	case ChangeTypeDelete:
 		return colorize("❌", colorBoldRed)
+	case ChangeTypeRotate:
+		return colorize("🔁", colorBoldPurple)
 	default:
 		return colorize("❓", colorGray)
 	}
@@ -93,17 +108,48 @@ func formatChangeType(changeType ChangeType) string {
 		return colorize("UPDATE", colorYellow)
 	case ChangeTypeDelete:
 		return colorize("DELETE", colorRed)
+	case ChangeTypeRotate:
+		return colorize("ROTATE", colorPurple)
 	default:
 		return colorize("UNKNOWN", colorGray)
 	}
 }
 
 // Engine handles diff operations between local and remote configurations
-type Engine struct{}
+type Engine struct {
+	redactor  *redact.Redactor
+	locations map[string]string
+}
 
-// NewEngine creates a new diff engine
+// NewEngine creates a new diff engine that masks values that look like secrets
 func NewEngine() *Engine {
-	return &Engine{}
+	redactor, _ := redact.NewRedactor(validator.NewValidator(), redact.Options{})
+	return &Engine{redactor: redactor}
+}
+
+// SetRedactor changes how sensitive values are hidden in output; nil shows every value
+func (e *Engine) SetRedactor(redactor *redact.Redactor) {
+	e.redactor = redactor
+}
+
+// SetLocations sets where each local key is defined, as file:line:column, so changes can
+// point at the line to edit
+func (e *Engine) SetLocations(locations map[string]string) {
+	e.locations = locations
+}
+
+// displayValues returns the old and new values of a change as they may be shown, and
+// whether they were hidden. Both values are hidden when either is sensitive.
+func (e *Engine) displayValues(change Change) (oldValue, newValue string, redacted bool) {
+	if !e.isSensitive(change) {
+		return change.OldValue, change.NewValue, false
+	}
+	return e.redactor.Value(change.OldValue), e.redactor.Value(change.NewValue), true
+}
+
+// isSensitive reports whether the values of a change must be hidden
+func (e *Engine) isSensitive(change Change) bool {
+	return e.redactor != nil && e.redactor.IsSensitive(change.Key, change.Tags, change.OldValue, change.NewValue)
 }
 
 // Compare compares local configuration with remote configuration
@@ -119,15 +165,21 @@ func (e *Engine) Compare(local map[string]string, remote []azure.ConfigItem, str
 	// Check for additions and updates
 	for key, localValue := range local {
 		if remoteItem, exists := remoteMap[key]; exists {
-			// Key exists, check if value changed
-			if remoteItem.Value != localValue {
+			// Key exists, check if value changed; a Key Vault reference or feature flag
+			// written in another form is the same value
+			if !sameValue(key, remoteItem.Value, localValue) {
+				changeType := ChangeTypeUpdate
+				if _, _, _, rotated := validator.SecretRotation(remoteItem.Value, localValue); rotated {
+					changeType = ChangeTypeRotate
+				}
 				changes = append(changes, Change{
-					Type:     ChangeTypeUpdate,
+					Type:     changeType,
 					Key:      key,
 					OldValue: remoteItem.Value,
 					NewValue: localValue,
 					Label:    remoteItem.Label,
 					Tags:     remoteItem.Tags,
+					Location: e.locations[key],
 				})
 			}
 			// Remove from remoteMap to track what's left
@@ -138,6 +190,7 @@ func (e *Engine) Compare(local map[string]string, remote []azure.ConfigItem, str
 				Type:     ChangeTypeAdd,
 				Key:      key,
 				NewValue: localValue,
+				Location: e.locations[key],
 			})
 		}
 	}
@@ -163,6 +216,88 @@ func (e *Engine) Compare(local map[string]string, remote []azure.ConfigItem, str
 	return changes, nil
 }
 
+// CompareItems compares two sets of settings by key and label, so the same key under several
+// labels is several settings. Added and updated settings keep the label, tags and content type
+// of the source. In strict mode, target settings missing from the source are deleted, but only
+// under labels the source has settings for.
+func (e *Engine) CompareItems(source, target []azure.ConfigItem, strict bool) ([]Change, error) {
+	changes := []Change{}
+
+	targetMap := make(map[settingID]azure.ConfigItem, len(target))
+	for _, item := range target {
+		targetMap[settingID{item.Key, item.Label}] = item
+	}
+
+	sourceLabels := make(map[string]bool)
+	for _, item := range source {
+		sourceLabels[item.Label] = true
+		id := settingID{item.Key, item.Label}
+
+		change := Change{
+			Key:         item.Key,
+			NewValue:    item.Value,
+			Label:       item.Label,
+			Tags:        item.Tags,
+			WriteTags:   true,
+			ContentType: item.ContentType,
+		}
+		if targetItem, exists := targetMap[id]; exists {
+			delete(targetMap, id)
+			if sameValue(item.Key, targetItem.Value, item.Value) {
+				continue
+			}
+			change.Type = ChangeTypeUpdate
+			change.OldValue = targetItem.Value
+			if _, _, _, rotated := validator.SecretRotation(targetItem.Value, item.Value); rotated {
+				change.Type = ChangeTypeRotate
+			}
+		} else {
+			change.Type = ChangeTypeAdd
+		}
+		changes = append(changes, change)
+	}
+
+	if strict {
+		for _, targetItem := range targetMap {
+			if !sourceLabels[targetItem.Label] {
+				continue
+			}
+			changes = append(changes, Change{
+				Type:     ChangeTypeDelete,
+				Key:      targetItem.Key,
+				OldValue: targetItem.Value,
+				Label:    targetItem.Label,
+				Tags:     targetItem.Tags,
+			})
+		}
+	}
+
+	// Sort changes for consistent output
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Key != changes[j].Key {
+			return changes[i].Key < changes[j].Key
+		}
+		return changes[i].Label < changes[j].Label
+	})
+
+	return changes, nil
+}
+
+// sameValue reports whether two values of a setting are equal, treating a Key Vault reference
+// or feature flag written in another form as the same value
+func sameValue(key, a, b string) bool {
+	if a == b || validator.SameSecretReference(a, b) {
+		return true
+	}
+	return validator.IsFeatureFlagSetting(key) && validator.SameFeatureFlag(a, b)
+}
+
+// settingID identifies a setting by its key and label
+type settingID struct {
+	key   string
+	label string
+}
+
 // GetSummary returns a summary of changes
 func (e *Engine) GetSummary(changes []Change) Summary {
 	summary := Summary{}
@@ -175,6 +310,8 @@ func (e *Engine) GetSummary(changes []Change) Summary {
 			summary.Updated++
 		case ChangeTypeDelete:
 			summary.Deleted++
+		case ChangeTypeRotate:
+			summary.Rotated++
 		}
 		summary.Total++
 	}
@@ -200,20 +337,56 @@ func (e *Engine) FormatConsole(changes []Change) string {
 		symbol := formatChangeSymbol(change.Type)
 		changeType := formatChangeType(change.Type)
 		key := colorize(bold(change.Key), colorBoldBlue)
+		if validator.IsFeatureFlagSetting(change.Key) {
+			key = "🚩 " + colorize(bold(validator.FeatureFlagName(change.Key)), colorBoldBlue)
+		}
+		if change.Location != "" {
+			key += " " + colorize(change.Location, colorGray)
+		}
+
+		// Feature flag updates are shown field by field instead of as raw JSON, unless the
+		// flag is sensitive and its values are masked like any other setting
+		if validator.IsFeatureFlagSetting(change.Key) && change.Type == ChangeTypeUpdate && !e.isSensitive(change) {
+			if flagChanges := validator.FeatureFlagChanges(change.OldValue, change.NewValue); len(flagChanges) > 0 {
+				output += fmt.Sprintf("%s %s %s\n", symbol, changeType, key)
+				for _, flagChange := range flagChanges {
+					output += fmt.Sprintf("   %s\n", flagChange)
+				}
+				continue
+			}
+		}
+
+		oldValue, newValue, redacted := e.displayValues(change)
+		formatValue := e.truncateValue
+		if redacted {
+			formatValue = formatRedacted
+		}
 
 		switch change.Type {
 		case ChangeTypeAdd:
 			output += fmt.Sprintf("%s %s %s\n", symbol, changeType, key)
-			output += fmt.Sprintf("   %s %s\n", colorize("New value:", colorCyan), e.truncateValue(change.NewValue))
+			output += fmt.Sprintf("   %s %s\n", colorize("New value:", colorCyan), formatValue(newValue))
 
 		case ChangeTypeUpdate:
 			output += fmt.Sprintf("%s %s %s\n", symbol, changeType, key)
-			output += fmt.Sprintf("   %s %s\n", colorize("New value:", colorCyan), e.truncateValue(change.NewValue))
-			output += fmt.Sprintf("   %s %s\n", colorize("Old value:", colorGray), e.truncateValue(change.OldValue))
+			if redacted {
+				// Masked values look alike, so say that the value changed
+				output += fmt.Sprintf("   %s %s %s\n", colorize("New value:", colorCyan), formatValue(newValue), colorize("(changed)", colorYellow))
+			} else {
+				output += fmt.Sprintf("   %s %s\n", colorize("New value:", colorCyan), formatValue(newValue))
+			}
+			output += fmt.Sprintf("   %s %s\n", colorize("Old value:", colorGray), formatValue(oldValue))
 
 		case ChangeTypeDelete:
 			output += fmt.Sprintf("%s %s %s\n", symbol, changeType, key)
-			output += fmt.Sprintf("   %s %s\n", colorize("Old value:", colorGray), e.truncateValue(change.OldValue))
+			output += fmt.Sprintf("   %s %s\n", colorize("Old value:", colorGray), formatValue(oldValue))
+
+		case ChangeTypeRotate:
+			output += fmt.Sprintf("%s %s %s\n", symbol, changeType, key)
+			if secret, fromVersion, toVersion, ok := validator.SecretRotation(change.OldValue, change.NewValue); ok {
+				output += fmt.Sprintf("   %s %s\n", colorize("Secret:", colorCyan), secret)
+				output += fmt.Sprintf("   %s %s → %s\n", colorize("Version:", colorCyan), fromVersion, toVersion)
+			}
 		}
 	}
 
@@ -231,6 +404,9 @@ func (e *Engine) FormatConsole(changes []Change) string {
 	if summary.Deleted > 0 {
 		output += fmt.Sprintf("   %s %d %s\n", colorize("❌", colorRed), summary.Deleted, colorize("deleted", colorRed))
 	}
+	if summary.Rotated > 0 {
+		output += fmt.Sprintf("   %s %d %s\n", colorize("🔁", colorPurple), summary.Rotated, colorize("secrets rotated", colorPurple))
+	}
 
 	output += fmt.Sprintf("\n   %s %d %s\n",
 		colorize("📈", colorBoldPurple),
@@ -249,6 +425,8 @@ func (e *Engine) FormatJSON(changes []Change) ([]byte, error) {
 		NewValue string            `json:"new_value,omitempty"`
 		Label    string            `json:"label,omitempty"`
 		Tags     map[string]string `json:"tags,omitempty"`
+		Location string            `json:"location,omitempty"`
+		Redacted bool              `json:"redacted,omitempty"`
 	}
 
 	type jsonOutput struct {
@@ -258,13 +436,16 @@ func (e *Engine) FormatJSON(changes []Change) ([]byte, error) {
 
 	jsonChanges := make([]jsonChange, len(changes))
 	for i, change := range changes {
+		oldValue, newValue, redacted := e.displayValues(change)
 		jsonChanges[i] = jsonChange{
 			Type:     string(change.Type),
 			Key:      change.Key,
-			OldValue: change.OldValue,
-			NewValue: change.NewValue,
+			OldValue: oldValue,
+			NewValue: newValue,
 			Label:    change.Label,
 			Tags:     change.Tags,
+			Location: change.Location,
+			Redacted: redacted,
 		}
 	}
 
@@ -289,6 +470,11 @@ func (e *Engine) truncateValue(value string) string {
 		   colorize(fmt.Sprintf(" (%d chars total)", len(value)), colorGray)
 }
 
+// formatRedacted formats a hidden value for display
+func formatRedacted(value string) string {
+	return "🔒 " + colorize(value, colorGray)
+}
+
 // HasChanges returns true if there are any changes
 func (e *Engine) HasChanges(changes []Change) bool {
 	return len(changes) > 0
@@ -0,0 +1,42 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chan27-2/appconfigguard/pkg/redact"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+func TestFormatConsole_FeatureFlagRedaction(t *testing.T) {
+	redactor, err := redact.NewRedactor(validator.NewValidator(), redact.Options{})
+	if err != nil {
+		t.Fatalf("NewRedactor failed: %v", err)
+	}
+
+	change := Change{
+		Type:     ChangeTypeUpdate,
+		Key:      ".appconfig.featureflag/Beta",
+		OldValue: `{"id":"Beta","description":"internal rollout","enabled":false}`,
+		NewValue: `{"id":"Beta","description":"internal rollout","enabled":true}`,
+	}
+
+	engine := NewEngine()
+	engine.SetRedactor(redactor)
+
+	// A flag that is not sensitive is shown field by field
+	output := engine.FormatConsole([]Change{change})
+	if !strings.Contains(output, "enabled: false → true") || strings.Contains(output, redact.Mask) {
+		t.Errorf("FormatConsole() = %q, expected the flag fields unmasked", output)
+	}
+
+	// A flag tagged sensitive is masked like any other setting
+	change.Tags = map[string]string{redact.SensitiveTag: "true"}
+	output = engine.FormatConsole([]Change{change})
+	if strings.Contains(output, "internal rollout") || strings.Contains(output, "enabled") {
+		t.Errorf("FormatConsole() = %q, expected the sensitive flag to be masked", output)
+	}
+	if !strings.Contains(output, redact.Mask) {
+		t.Errorf("FormatConsole() = %q, expected the masked value", output)
+	}
+}
@@ -0,0 +1,217 @@
+package evaluator
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+// TargetingContext identifies who a feature flag is evaluated for
+type TargetingContext struct {
+	UserID string
+	Groups []string
+}
+
+// Result is the outcome of evaluating a feature flag
+type Result struct {
+	// Enabled is true when the flag is on for the context. For flags that depend on
+	// Microsoft.Percentage it is true when the flag is on more often than not.
+	Enabled bool
+	// Probability is the chance that the flag is on for a single evaluation (0 to 1).
+	// It is only fractional when Microsoft.Percentage, which is random, is involved.
+	Probability float64
+	// Reasons explains how each part of the flag contributed to the result
+	Reasons []string
+}
+
+// Evaluator evaluates feature flags the way the Microsoft feature management libraries do
+type Evaluator struct {
+	now func() time.Time
+	// IgnoreCase compares user and group names case-insensitively in targeting
+	IgnoreCase bool
+}
+
+// NewEvaluator creates a new evaluator that evaluates time windows against the current time
+func NewEvaluator() *Evaluator {
+	return &Evaluator{
+		now: time.Now,
+	}
+}
+
+// SetTime evaluates time windows at a fixed time instead of the current time
+func (e *Evaluator) SetTime(at time.Time) {
+	e.now = func() time.Time { return at }
+}
+
+// Evaluate evaluates a feature flag for a targeting context
+func (e *Evaluator) Evaluate(ff *validator.FeatureFlag, context TargetingContext) Result {
+	if !ff.Enabled {
+		return Result{Reasons: []string{"flag is disabled"}}
+	}
+
+	filters := ff.Conditions.ClientFilters
+	if len(filters) == 0 {
+		return Result{Enabled: true, Probability: 1, Reasons: []string{"flag is enabled with no filters"}}
+	}
+
+	requireAll := strings.EqualFold(ff.Conditions.RequirementType, "All")
+
+	var reasons []string
+	// Filters are independent, so combine the chance that each one passes
+	passAll, missAll := 1.0, 1.0
+	for _, filter := range filters {
+		p, reason := e.evaluateFilter(ff.ID, filter, context)
+		reasons = append(reasons, reason)
+		passAll *= p
+		missAll *= 1 - p
+	}
+
+	probability := 1 - missAll
+	if requireAll {
+		probability = passAll
+		reasons = append(reasons, "all filters must pass (requirement_type All)")
+	} else {
+		reasons = append(reasons, "any filter may pass (requirement_type Any)")
+	}
+
+	return Result{
+		Enabled:     probability > 0.5,
+		Probability: probability,
+		Reasons:     reasons,
+	}
+}
+
+// evaluateFilter returns the probability that a filter passes and why
+func (e *Evaluator) evaluateFilter(featureName string, filter validator.ClientFilter, context TargetingContext) (float64, string) {
+	switch {
+	case filter.Percentage != nil:
+		p := math.Max(0, math.Min(100, float64(filter.Percentage.Value))) / 100
+		return p, fmt.Sprintf("%s: on for %s of evaluations at random", filter.Name, filter.Percentage.Value)
+
+	case filter.TimeWindow != nil:
+		return e.evaluateTimeWindow(filter)
+
+	case filter.Targeting != nil:
+		return e.evaluateTargeting(featureName, filter, context)
+
+	default:
+		return 0, fmt.Sprintf("%s: custom filter cannot be evaluated locally, treated as not passing", filter.Name)
+	}
+}
+
+// evaluateTimeWindow checks whether the current time is inside the window
+func (e *Evaluator) evaluateTimeWindow(filter validator.ClientFilter) (float64, string) {
+	now := e.now()
+	window := filter.TimeWindow
+
+	if window.Recurrence != nil {
+		active, err := window.ActiveAt(now)
+		if err != nil {
+			return 0, fmt.Sprintf("%s: invalid recurring time window: %v", filter.Name, err)
+		}
+		if !active {
+			return 0, fmt.Sprintf("%s: outside the recurring time window", filter.Name)
+		}
+		return 1, fmt.Sprintf("%s: inside an occurrence of the recurring time window", filter.Name)
+	}
+
+	if window.Start != "" {
+		start, err := validator.ParseFilterTime(window.Start)
+		if err != nil {
+			return 0, fmt.Sprintf("%s: invalid Start %q", filter.Name, window.Start)
+		}
+		if now.Before(start) {
+			return 0, fmt.Sprintf("%s: window has not started (starts %s)", filter.Name, window.Start)
+		}
+	}
+
+	if window.End != "" {
+		end, err := validator.ParseFilterTime(window.End)
+		if err != nil {
+			return 0, fmt.Sprintf("%s: invalid End %q", filter.Name, window.End)
+		}
+		if !now.Before(end) {
+			return 0, fmt.Sprintf("%s: window has ended (ended %s)", filter.Name, window.End)
+		}
+	}
+
+	return 1, fmt.Sprintf("%s: inside the time window", filter.Name)
+}
+
+// evaluateTargeting applies the targeting filter rules: exclusions first, then listed
+// users, then group rollouts, then the default rollout percentage
+func (e *Evaluator) evaluateTargeting(featureName string, filter validator.ClientFilter, context TargetingContext) (float64, string) {
+	audience := filter.Targeting.Audience
+
+	if audience.Exclusion != nil {
+		if context.UserID != "" && e.contains(audience.Exclusion.Users, context.UserID) {
+			return 0, fmt.Sprintf("%s: %s is excluded", filter.Name, context.UserID)
+		}
+		for _, group := range context.Groups {
+			if e.contains(audience.Exclusion.Groups, group) {
+				return 0, fmt.Sprintf("%s: group %s is excluded", filter.Name, group)
+			}
+		}
+	}
+
+	if context.UserID != "" && e.contains(audience.Users, context.UserID) {
+		return 1, fmt.Sprintf("%s: %s is listed in Users", filter.Name, context.UserID)
+	}
+
+	for _, group := range context.Groups {
+		for _, rollout := range audience.Groups {
+			if !e.equal(rollout.Name, group) {
+				continue
+			}
+
+			contextID := fmt.Sprintf("%s\n%s\n%s", context.UserID, featureName, group)
+			if IsTargeted(contextID, float64(rollout.RolloutPercentage)) {
+				return 1, fmt.Sprintf("%s: %s falls within the %s rollout of group %s", filter.Name, context.UserID, rollout.RolloutPercentage, group)
+			}
+		}
+	}
+
+	contextID := fmt.Sprintf("%s\n%s", context.UserID, featureName)
+	if IsTargeted(contextID, float64(audience.DefaultRolloutPercentage)) {
+		return 1, fmt.Sprintf("%s: %s falls within the default rollout of %s", filter.Name, context.UserID, audience.DefaultRolloutPercentage)
+	}
+
+	return 0, fmt.Sprintf("%s: %s is not targeted (default rollout %s)", filter.Name, context.UserID, audience.DefaultRolloutPercentage)
+}
+
+// IsTargeted reports whether a context falls within a rollout percentage. It matches the
+// feature management libraries: the first four bytes of the SHA-256 of the context id,
+// read as a little-endian uint32, are scaled to a percentage.
+func IsTargeted(contextID string, percentage float64) bool {
+	hash := sha256.Sum256([]byte(contextID))
+	marker := binary.LittleEndian.Uint32(hash[:4])
+	contextPercentage := float64(marker) / float64(math.MaxUint32) * 100
+
+	if percentage >= 100 {
+		return true
+	}
+	return contextPercentage < percentage
+}
+
+// contains checks whether a list holds a name, honouring IgnoreCase
+func (e *Evaluator) contains(list []string, name string) bool {
+	for _, item := range list {
+		if e.equal(item, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares two names, honouring IgnoreCase
+func (e *Evaluator) equal(a, b string) bool {
+	if e.IgnoreCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
@@ -0,0 +1,142 @@
+package evaluator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+func parseFlag(t *testing.T, value string) *validator.FeatureFlag {
+	t.Helper()
+	ff, err := validator.ParseFeatureFlagValue(value)
+	if err != nil {
+		t.Fatalf("failed to parse flag: %v", err)
+	}
+	return ff
+}
+
+func TestIsTargeted(t *testing.T) {
+	// Context percentages computed with the feature management hashing algorithm
+	tests := []struct {
+		contextID  string
+		percentage float64
+		expected   bool
+	}{
+		{"alice\nBeta", 88, false},
+		{"alice\nBeta", 89, true},
+		{"bob\nBeta", 1.4, false},
+		{"bob\nBeta", 1.5, true},
+		{"alice\nBeta\nbeta", 78, false},
+		{"alice\nBeta\nbeta", 79, true},
+		{"anyone", 0, false},
+		{"anyone", 100, true},
+	}
+
+	for _, tt := range tests {
+		if got := IsTargeted(tt.contextID, tt.percentage); got != tt.expected {
+			t.Errorf("IsTargeted(%q, %v) = %v, expected %v", tt.contextID, tt.percentage, got, tt.expected)
+		}
+	}
+}
+
+func TestEvaluateTargeting(t *testing.T) {
+	ff := parseFlag(t, `{"id":"Beta","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.Targeting","parameters":{"Audience":{
+		"Users":["carol"],
+		"Groups":[{"Name":"beta","RolloutPercentage":79}],
+		"DefaultRolloutPercentage":10,
+		"Exclusion":{"Users":["mallory"],"Groups":["blocked"]}}}}]}}`)
+
+	tests := []struct {
+		name     string
+		context  TargetingContext
+		expected bool
+	}{
+		{"listed user", TargetingContext{UserID: "carol"}, true},
+		{"group rollout", TargetingContext{UserID: "alice", Groups: []string{"beta"}}, true},
+		{"outside default rollout", TargetingContext{UserID: "alice"}, false},
+		{"inside default rollout", TargetingContext{UserID: "bob"}, true},
+		{"excluded user", TargetingContext{UserID: "mallory"}, false},
+		{"excluded group wins over listed user", TargetingContext{UserID: "carol", Groups: []string{"blocked"}}, false},
+	}
+
+	e := NewEvaluator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := e.Evaluate(ff, tt.context)
+			if result.Enabled != tt.expected {
+				t.Errorf("expected enabled=%v, got %v (%v)", tt.expected, result.Enabled, result.Reasons)
+			}
+		})
+	}
+}
+
+func TestEvaluateTimeWindow(t *testing.T) {
+	ff := parseFlag(t, `{"id":"Sale","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.TimeWindow","parameters":{
+		"Start":"Mon, 01 Jan 2024 00:00:00 GMT","End":"Tue, 02 Jan 2024 00:00:00 GMT"}}]}}`)
+
+	e := NewEvaluator()
+
+	e.SetTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	if !e.Evaluate(ff, TargetingContext{}).Enabled {
+		t.Error("expected flag to be on inside the window")
+	}
+
+	e.SetTime(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC))
+	if e.Evaluate(ff, TargetingContext{}).Enabled {
+		t.Error("expected flag to be off after the window")
+	}
+}
+
+func TestEvaluateRecurringTimeWindow(t *testing.T) {
+	ff := parseFlag(t, `{"id":"Standup","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.TimeWindow","parameters":{
+		"Start":"Mon, 01 Jan 2024 09:00:00 GMT","End":"Mon, 01 Jan 2024 09:15:00 GMT",
+		"Recurrence":{"Pattern":{"Type":"Weekly","DaysOfWeek":["Monday"]},"Range":{"Type":"NoEnd"}}}}]}}`)
+
+	e := NewEvaluator()
+
+	e.SetTime(time.Date(2024, 3, 4, 9, 5, 0, 0, time.UTC))
+	if !e.Evaluate(ff, TargetingContext{}).Enabled {
+		t.Error("expected flag to be on during a later occurrence")
+	}
+
+	e.SetTime(time.Date(2024, 3, 5, 9, 5, 0, 0, time.UTC))
+	if e.Evaluate(ff, TargetingContext{}).Enabled {
+		t.Error("expected flag to be off between occurrences")
+	}
+}
+
+func TestEvaluateRequirementType(t *testing.T) {
+	filters := `[{"name":"Microsoft.Percentage","parameters":{"Value":50}},{"name":"Microsoft.Percentage","parameters":{"Value":50}}]`
+
+	tests := []struct {
+		requirementType string
+		probability     float64
+	}{
+		{"", 0.75},
+		{"Any", 0.75},
+		{"All", 0.25},
+	}
+
+	e := NewEvaluator()
+	for _, tt := range tests {
+		ff := parseFlag(t, `{"id":"Split","enabled":true,"conditions":{"requirement_type":"`+tt.requirementType+`","client_filters":`+filters+`}}`)
+		result := e.Evaluate(ff, TargetingContext{})
+		if math.Abs(result.Probability-tt.probability) > 1e-9 {
+			t.Errorf("requirement type %q: expected probability %v, got %v", tt.requirementType, tt.probability, result.Probability)
+		}
+	}
+}
+
+func TestEvaluateDisabledAndUnfiltered(t *testing.T) {
+	e := NewEvaluator()
+
+	if result := e.Evaluate(&validator.FeatureFlag{ID: "Off"}, TargetingContext{}); result.Enabled || result.Probability != 0 {
+		t.Errorf("expected disabled flag to be off, got %+v", result)
+	}
+
+	if result := e.Evaluate(&validator.FeatureFlag{ID: "On", Enabled: true}, TargetingContext{}); !result.Enabled || result.Probability != 1 {
+		t.Errorf("expected unfiltered flag to be on, got %+v", result)
+	}
+}
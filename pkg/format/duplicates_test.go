@@ -0,0 +1,87 @@
+package format
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode_DuplicateKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		f        Format
+		expected []DuplicateKey
+	}{
+		{
+			name:    "json",
+			content: "{\n  \"db\": {\n    \"host\": \"a\",\n    \"port\": 1,\n    \"host\": \"b\"\n  },\n  \"db\": {}\n}",
+			f:       JSON,
+			expected: []DuplicateKey{
+				{Path: "db.host", First: Position{Line: 3, Column: 5}, Second: Position{Line: 5, Column: 5}},
+				{Path: "db", First: Position{Line: 2, Column: 3}, Second: Position{Line: 7, Column: 3}},
+			},
+		},
+		{
+			name:    "yaml",
+			content: "db:\n  host: a\n  host: b\nlist:\n  - name: x\n    name: y\n",
+			f:       YAML,
+			expected: []DuplicateKey{
+				{Path: "db.host", First: Position{Line: 2, Column: 3}, Second: Position{Line: 3, Column: 3}},
+				{Path: "list.0.name", First: Position{Line: 5, Column: 5}, Second: Position{Line: 6, Column: 5}},
+			},
+		},
+		{
+			name:    "env",
+			content: "DB__HOST=a\nexport DB__HOST=b\n",
+			f:       Env,
+			expected: []DuplicateKey{
+				{Path: "DB.HOST", First: Position{Line: 1, Column: 1}, Second: Position{Line: 2, Column: 8}},
+			},
+		},
+		{
+			name:    "properties",
+			content: "db.host=a\n  db.host = b\n",
+			f:       Properties,
+			expected: []DuplicateKey{
+				{Path: "db.host", First: Position{Line: 1, Column: 1}, Second: Position{Line: 2, Column: 3}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.content), tt.f)
+
+			var duplicates *DuplicateKeysError
+			if !errors.As(err, &duplicates) {
+				t.Fatalf("expected DuplicateKeysError, got %v", err)
+			}
+			if !reflect.DeepEqual(duplicates.Duplicates, tt.expected) {
+				t.Errorf("Duplicates = %+v, expected %+v", duplicates.Duplicates, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecode_YAMLMergeIsNotDuplicate(t *testing.T) {
+	content := "base: &base\n  host: a\ndb:\n  <<: *base\n  host: b\n"
+	if _, err := Decode([]byte(content), YAML); err != nil {
+		t.Errorf("expected overriding a merged key to be allowed, got %v", err)
+	}
+}
+
+func TestReadFile_DuplicateKeysNameTheFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"a": 1, "a": 2}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadFile(path)
+	if err == nil || !strings.Contains(err.Error(), "duplicate keys in "+path+"\n  a at line 1, column 10 (first defined at line 1, column 2)") {
+		t.Errorf("ReadFile() error = %v", err)
+	}
+}
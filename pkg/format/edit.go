@@ -0,0 +1,197 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SetValue replaces the value at a path with a string. The content is edited in place, so
+// comments, key order and formatting elsewhere in the file are kept.
+func SetValue(content []byte, f Format, path []string, value string) ([]byte, error) {
+	var edited []byte
+	var err error
+	switch f {
+	case JSON:
+		edited, err = setJSONC(content, path, value)
+	case YAML:
+		edited, err = setYAML(content, path, value)
+	case TOML:
+		edited, err = setTOML(content, path, value)
+	case INI, Env, Properties:
+		edited, err = setFlat(content, f, path, value)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkEdit(edited, f, path)
+}
+
+// RemoveValue removes the value at a path, along with the sections it leaves empty. The
+// content is edited in place.
+func RemoveValue(content []byte, f Format, path []string) ([]byte, error) {
+	var edited []byte
+	var err error
+	switch f {
+	case JSON:
+		edited, err = removeJSONC(content, path)
+	case YAML:
+		edited, err = removeYAML(content, path)
+	case TOML:
+		edited, err = removeTOML(content, path)
+	default:
+		return nil, fmt.Errorf("values cannot be removed from %s files in place", f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if edited, err = checkEdit(edited, f, path); err != nil {
+		return nil, err
+	}
+
+	// Sections emptied by the removal go too, innermost first
+	if len(path) > 1 {
+		data, err := Decode(edited, f)
+		if err != nil {
+			return nil, err
+		}
+		if section, ok := lookupPath(data, path[:len(path)-1]).(map[string]interface{}); ok && len(section) == 0 {
+			return RemoveValue(edited, f, path[:len(path)-1])
+		}
+	}
+	return edited, nil
+}
+
+// AddValue adds a value at a path that does not exist yet. Its section is added at the end
+// of the file when it is missing. The content is edited in place, and the value is written
+// in the style of the file.
+func AddValue(content []byte, f Format, path []string, value interface{}) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("missing key")
+	}
+
+	var edited []byte
+	var err error
+	switch f {
+	case JSON:
+		edited, err = addJSONC(content, path, value)
+	case YAML:
+		edited, err = addYAML(content, path, value)
+	case TOML:
+		edited, err = addTOML(content, path, value)
+	default:
+		return nil, fmt.Errorf("values cannot be added to %s files in place", f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkEdit(edited, f, path)
+}
+
+// checkEdit makes sure edited content still parses, so a failed edit never reaches the file
+func checkEdit(edited []byte, f Format, path []string) ([]byte, error) {
+	if _, err := Decode(edited, f); err != nil {
+		return nil, fmt.Errorf("editing %s would leave an invalid file: %w", strings.Join(path, "."), err)
+	}
+	return edited, nil
+}
+
+// lookupPath returns the value at a path in decoded data, or nil when there is none
+func lookupPath(data interface{}, path []string) interface{} {
+	current := data
+	for _, part := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[part]
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil
+			}
+			current = node[index]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// notFoundError reports a path that is not in the file
+func notFoundError(path []string) error {
+	return fmt.Errorf("%s not found", strings.Join(path, "."))
+}
+
+// splice replaces content[start:end] with text
+func splice(content []byte, start, end int, text string) []byte {
+	result := make([]byte, 0, len(content)-(end-start)+len(text))
+	result = append(result, content[:start]...)
+	result = append(result, text...)
+	return append(result, content[end:]...)
+}
+
+// lineBreak returns the line ending the content uses
+func lineBreak(content []byte) string {
+	if bytes.Contains(content, []byte("\r\n")) {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// lineStart returns the offset of the start of the line holding offset
+func lineStart(content []byte, offset int) int {
+	return bytes.LastIndexByte(content[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset of the line break ending the line holding offset, or the end of the content
+func lineEnd(content []byte, offset int) int {
+	if i := bytes.IndexByte(content[offset:], '\n'); i >= 0 {
+		return offset + i
+	}
+	return len(content)
+}
+
+// lineIndent returns the whitespace the line holding offset starts with
+func lineIndent(content []byte, offset int) string {
+	start := lineStart(content, offset)
+	end := start
+	for end < len(content) && (content[end] == ' ' || content[end] == '\t') {
+		end++
+	}
+	return string(content[start:end])
+}
+
+// firstOnLine reports whether only whitespace comes before offset on its line
+func firstOnLine(content []byte, offset int) bool {
+	return len(bytes.TrimSpace(content[lineStart(content, offset):offset])) == 0
+}
+
+// wholeLines widens content[start:end] to the lines it is on when nothing but whitespace, or
+// a comment starting with the given marker, shares them
+func wholeLines(content []byte, start, end int, comment string) (int, int) {
+	if !firstOnLine(content, start) {
+		return start, end
+	}
+
+	after := lineEnd(content, end)
+	rest := bytes.TrimSpace(content[end:after])
+	if len(rest) > 0 && (comment == "" || !bytes.HasPrefix(rest, []byte(comment))) {
+		return start, end
+	}
+	if after < len(content) {
+		after++
+	}
+	return lineStart(content, start), after
+}
+
+// quoteString writes a string as a JSON string, which YAML and TOML basic strings also accept
+func quoteString(value string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,259 @@
+package format
+
+import (
+	"testing"
+)
+
+func TestSetValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		content  string
+		path     []string
+		expected string
+	}{
+		{
+			name:   "jsonc keeps comments and trailing commas",
+			format: JSON,
+			content: `{
+  // Database settings
+  "Database": {
+    "Password": "hunter2", // rotate monthly
+    "Host": "db",
+  },
+}`,
+			path: []string{"Database", "Password"},
+			expected: `{
+  // Database settings
+  "Database": {
+    "Password": "{\"uri\":\"https://vault/secrets/pw\"}", // rotate monthly
+    "Host": "db",
+  },
+}`,
+		},
+		{
+			name:     "yaml",
+			format:   YAML,
+			content:  "# Database settings\nDatabase:\n  Password: hunter2 # rotate monthly\n  Host: db\n",
+			path:     []string{"Database", "Password"},
+			expected: "# Database settings\nDatabase:\n  Password: '{\"uri\":\"https://vault/secrets/pw\"}' # rotate monthly\n  Host: db\n",
+		},
+		{
+			name:     "toml",
+			format:   TOML,
+			content:  "# Database settings\n[Database]\nPassword = 'hunter2' # rotate monthly\nHost = \"db\"\n",
+			path:     []string{"Database", "Password"},
+			expected: "# Database settings\n[Database]\nPassword = \"{\\\"uri\\\":\\\"https://vault/secrets/pw\\\"}\" # rotate monthly\nHost = \"db\"\n",
+		},
+		{
+			name:     "env",
+			format:   Env,
+			content:  "# Database settings\nexport Database__Password = hunter2 # rotate monthly\nDatabase__Host=db\n",
+			path:     []string{"Database", "Password"},
+			expected: "# Database settings\nexport Database__Password = \"{\\\"uri\\\":\\\"https://vault/secrets/pw\\\"}\" # rotate monthly\nDatabase__Host=db\n",
+		},
+		{
+			name:     "ini",
+			format:   INI,
+			content:  "; Database settings\n[Database]\nPassword = hunter2\nHost = db\n",
+			path:     []string{"Database", "Password"},
+			expected: "; Database settings\n[Database]\nPassword = {\"uri\":\"https://vault/secrets/pw\"}\nHost = db\n",
+		},
+		{
+			name:     "properties",
+			format:   Properties,
+			content:  "# Database settings\nDatabase.Password: hunter2\nDatabase.Host=db\n",
+			path:     []string{"Database", "Password"},
+			expected: "# Database settings\nDatabase.Password: {\"uri\"\\:\"https\\://vault/secrets/pw\"}\nDatabase.Host=db\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited, err := SetValue([]byte(tt.content), tt.format, tt.path, `{"uri":"https://vault/secrets/pw"}`)
+			if err != nil {
+				t.Fatalf("SetValue() error = %v", err)
+			}
+			if string(edited) != tt.expected {
+				t.Errorf("SetValue() =\n%s\nexpected\n%s", edited, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRemoveValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		content  string
+		path     []string
+		expected string
+	}{
+		{
+			name:   "jsonc last member",
+			format: JSON,
+			content: `{
+  "Port": 8080,
+  // Toggles
+  "Features": {
+    "Beta": true, // remove once shipped
+    "Dark": false,
+  },
+}`,
+			path: []string{"Features", "Dark"},
+			expected: `{
+  "Port": 8080,
+  // Toggles
+  "Features": {
+    "Beta": true, // remove once shipped
+  },
+}`,
+		},
+		{
+			name:   "jsonc prunes the emptied section",
+			format: JSON,
+			content: `{
+  "Features": {
+    "Beta": true
+  },
+  "Port": 8080
+}`,
+			path: []string{"Features", "Beta"},
+			expected: `{
+  "Port": 8080
+}`,
+		},
+		{
+			name:     "jsonc member of a single-line object",
+			format:   JSON,
+			content:  `{"checkout": {"enabled": false, "name": "x"}}`,
+			path:     []string{"checkout", "enabled"},
+			expected: `{"checkout": {"name": "x"}}`,
+		},
+		{
+			name:     "yaml",
+			format:   YAML,
+			content:  "Port: 8080\nFeatures:\n  Beta: true # remove once shipped\n  Dark: false\n",
+			path:     []string{"Features", "Beta"},
+			expected: "Port: 8080\nFeatures:\n  Dark: false\n",
+		},
+		{
+			name:     "toml prunes the emptied table",
+			format:   TOML,
+			content:  "Port = 8080\n\n[Features]\nBeta = true\n\n[Logging]\nLevel = \"info\"\n",
+			path:     []string{"Features", "Beta"},
+			expected: "Port = 8080\n\n[Logging]\nLevel = \"info\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited, err := RemoveValue([]byte(tt.content), tt.format, tt.path)
+			if err != nil {
+				t.Fatalf("RemoveValue() error = %v", err)
+			}
+			if string(edited) != tt.expected {
+				t.Errorf("RemoveValue() =\n%s\nexpected\n%s", edited, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddValue(t *testing.T) {
+	flag := map[string]interface{}{"enabled": true, "description": "New checkout"}
+
+	tests := []struct {
+		name     string
+		format   Format
+		content  string
+		expected string
+	}{
+		{
+			name:   "jsonc into an existing section",
+			format: JSON,
+			content: `{
+    // Flags
+    "featureFlags": {
+        "Beta": true, // shipped
+    },
+}`,
+			expected: `{
+    // Flags
+    "featureFlags": {
+        "Beta": true, // shipped
+        "Checkout": {
+            "description": "New checkout",
+            "enabled": true
+        },
+    },
+}`,
+		},
+		{
+			name:   "jsonc creates the section",
+			format: JSON,
+			content: `{
+  "Port": 8080 // default
+}`,
+			expected: `{
+  "Port": 8080, // default
+  "featureFlags": {
+    "Checkout": {
+      "description": "New checkout",
+      "enabled": true
+    }
+  }
+}`,
+		},
+		{
+			name:     "yaml into an existing section",
+			format:   YAML,
+			content:  "featureFlags:\n    Beta: true # shipped\nPort: 8080\n",
+			expected: "featureFlags:\n    Beta: true # shipped\n    Checkout:\n        description: New checkout\n        enabled: true\nPort: 8080\n",
+		},
+		{
+			name:     "yaml creates the section",
+			format:   YAML,
+			content:  "Port: 8080\n",
+			expected: "Port: 8080\nfeatureFlags:\n  Checkout:\n    description: New checkout\n    enabled: true\n",
+		},
+		{
+			name:     "toml into an existing table",
+			format:   TOML,
+			content:  "[featureFlags]\nBeta = true # shipped\n\n[Logging]\nLevel = \"info\"\n",
+			expected: "[featureFlags]\nBeta = true # shipped\nCheckout = { description = \"New checkout\", enabled = true }\n\n[Logging]\nLevel = \"info\"\n",
+		},
+		{
+			name:     "toml creates the table",
+			format:   TOML,
+			content:  "Port = 8080\n",
+			expected: "Port = 8080\n\n[featureFlags]\nCheckout = { description = \"New checkout\", enabled = true }\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edited, err := AddValue([]byte(tt.content), tt.format, []string{"featureFlags", "Checkout"}, flag)
+			if err != nil {
+				t.Fatalf("AddValue() error = %v", err)
+			}
+			if string(edited) != tt.expected {
+				t.Errorf("AddValue() =\n%s\nexpected\n%s", edited, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	if _, err := SetValue([]byte(`{"Port": 8080}`), JSON, []string{"Host"}, "db"); err == nil {
+		t.Errorf("SetValue() of a missing key should fail")
+	}
+	if _, err := AddValue([]byte(`{"featureFlags": {"Beta": true}}`), JSON, []string{"featureFlags", "Beta"}, true); err == nil {
+		t.Errorf("AddValue() of an existing key should fail")
+	}
+	if _, err := RemoveValue([]byte("Port=8080\n"), Env, []string{"Port"}); err == nil {
+		t.Errorf("RemoveValue() from a .env file should fail")
+	}
+	if _, err := SetValue([]byte("Key = \"line one\nline two\"\n"), Env, []string{"Key"}, "value"); err == nil {
+		t.Errorf("SetValue() of a multiline .env value should fail")
+	}
+}
@@ -0,0 +1,183 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// decodeEnv parses a .env file of NAME=value lines. A double underscore in a name separates
+// sections, so Database__Host is read as the key Database.Host. Lines may start with export,
+// and # starts a comment. Unquoted values end at a # preceded by a space.
+// Single-quoted values are literal; double-quoted values understand \n, \r, \t, \", \\ and
+// \$. Quoted values may span several lines. Variables such as ${HOME} are not expanded.
+func decodeEnv(content []byte) (interface{}, Positions, error) {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+
+	var entries []flatEntry
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimLeft(lines[i], " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") || strings.HasPrefix(line, "export\t") {
+			line = strings.TrimLeft(line[len("export"):], " \t")
+		}
+		column := len(lines[i]) - len(line) + 1
+
+		separator := strings.IndexByte(line, '=')
+		if separator < 0 {
+			return nil, nil, fmt.Errorf("line %d: expected NAME=value", lineNumber)
+		}
+		name := strings.TrimRight(line[:separator], " \t")
+		if !isEnvName(name) {
+			return nil, nil, fmt.Errorf("line %d: invalid variable name %q", lineNumber, name)
+		}
+
+		rest := strings.TrimLeft(line[separator+1:], " \t")
+		var value string
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quote := rest[0]
+			body := rest[1:]
+
+			var quoted strings.Builder
+			for {
+				end := closingQuote(body, quote)
+				if end >= 0 {
+					quoted.WriteString(body[:end])
+					if after := strings.TrimSpace(body[end+1:]); after != "" && !strings.HasPrefix(after, "#") {
+						return nil, nil, fmt.Errorf("line %d: unexpected %q after the closing quote", i+1, after)
+					}
+					break
+				}
+
+				quoted.WriteString(body)
+				quoted.WriteByte('\n')
+				i++
+				if i >= len(lines) {
+					return nil, nil, fmt.Errorf("line %d: unterminated quoted value", lineNumber)
+				}
+				body = lines[i]
+			}
+
+			value = quoted.String()
+			if quote == '"' {
+				value = unescapeEnv(value)
+			}
+		} else {
+			value = strings.TrimRight(stripEnvComment(rest), " \t")
+		}
+
+		entries = append(entries, flatEntry{
+			key:      strings.ReplaceAll(name, "__", "."),
+			value:    value,
+			position: Position{Line: lineNumber, Column: column},
+		})
+	}
+
+	return nestEntries(entries)
+}
+
+// closingQuote returns the index of the quote that ends a quoted value, or -1 when it is on a
+// later line. Backslashes only escape quotes inside double quotes.
+func closingQuote(body string, quote byte) int {
+	for i := 0; i < len(body); i++ {
+		switch body[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripEnvComment removes a # comment that follows an unquoted value
+func stripEnvComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return value[:i]
+		}
+	}
+	return value
+}
+
+// unescapeEnv expands the escapes of a double-quoted value. Other backslashes are kept.
+func unescapeEnv(value string) string {
+	var result strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			result.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		switch value[i] {
+		case 'n':
+			result.WriteByte('\n')
+		case 'r':
+			result.WriteByte('\r')
+		case 't':
+			result.WriteByte('\t')
+		case '"', '\\', '$':
+			result.WriteByte(value[i])
+		default:
+			result.WriteByte('\\')
+			result.WriteByte(value[i])
+		}
+	}
+	return result.String()
+}
+
+// isEnvName reports whether a name can be used as a variable in a .env file
+func isEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isIdentifierByte(name[i]) && name[i] != '.' && name[i] != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// EnvName converts a configuration key to the environment variable name configuration
+// providers read as the same key, e.g. database.host becomes database__host
+func EnvName(key string) string {
+	return strings.NewReplacer(".", "__", ":", "__").Replace(key)
+}
+
+// encodeEnv writes nested configuration as NAME=value lines, sorted by name. Values that are
+// not plain words are double-quoted, with newlines, quotes, backslashes and $ escaped.
+func encodeEnv(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, entry := range flattenEntries(data) {
+		name := EnvName(entry.key)
+		if !isEnvName(name) {
+			return nil, fmt.Errorf("failed to write .env: %s cannot be written as a variable name", entry.key)
+		}
+		fmt.Fprintf(&buf, "%s=%s\n", name, quoteEnv(entry.value))
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteEnv returns a value as it is written in a .env file
+func quoteEnv(value string) string {
+	plain := true
+	for i := 0; i < len(value); i++ {
+		if !isIdentifierByte(value[i]) && !strings.ContainsRune("./:@,+-=%", rune(value[i])) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return value
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + replacer.Replace(value) + `"`
+}
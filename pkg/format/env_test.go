@@ -0,0 +1,99 @@
+package format
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode_Env(t *testing.T) {
+	content := `# Service settings
+Database__Host=localhost
+export Database__Port=5432
+Api__Url=https://api.example.com/v1#anchor # the API
+Greeting='Hello $USER'
+Message="line one\nline \"two\""
+Certificate="-----BEGIN-----
+abc
+-----END-----"
+Empty=
+`
+
+	data, positions, err := DecodeWithPositions([]byte(content), Env)
+	if err != nil {
+		t.Fatalf("DecodeWithPositions() error = %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"Database": map[string]interface{}{
+			"Host": "localhost",
+			"Port": "5432",
+		},
+		"Api":         map[string]interface{}{"Url": "https://api.example.com/v1#anchor"},
+		"Greeting":    "Hello $USER",
+		"Message":     "line one\nline \"two\"",
+		"Certificate": "-----BEGIN-----\nabc\n-----END-----",
+		"Empty":       "",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("data = %#v, expected %#v", data, expected)
+	}
+
+	if location := positions.Location(".env", "Database.Port"); location != ".env:3:8" {
+		t.Errorf("Location() = %q, expected .env:3:8", location)
+	}
+}
+
+func TestDecode_EnvErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"missing equals", "A=1\nNAME\n", "line 2: expected NAME=value"},
+		{"invalid name", "1A=x\n", "invalid variable name"},
+		{"unterminated quote", "A=\"one\ntwo\n", "line 1: unterminated quoted value"},
+		{"text after quote", "A='one' two\n", "after the closing quote"},
+		{"value and section", "A=1\nA__B=2\n", "A.B cannot be set because A has a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.content), Env)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Decode() error = %v, expected it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncode_Env(t *testing.T) {
+	data := map[string]interface{}{
+		"Database": map[string]interface{}{"Host": "db.example.com", "Port": float64(5432)},
+		"Message":  "say \"hi\"\nto $USER",
+		"Servers":  []interface{}{"web1", "web2"},
+	}
+
+	content, err := Encode(data, Env)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	expected := `Database__Host=db.example.com
+Database__Port=5432
+Message="say \"hi\"\nto \$USER"
+Servers__0=web1
+Servers__1=web2
+`
+	if string(content) != expected {
+		t.Errorf("Encode() = %q, expected %q", content, expected)
+	}
+
+	decoded, err := Decode(content, Env)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if message := decoded.(map[string]interface{})["Message"]; message != data["Message"] {
+		t.Errorf("round trip Message = %q, expected %q", message, data["Message"])
+	}
+}
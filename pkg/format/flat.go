@@ -0,0 +1,202 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// flatEntry is a key/value pair read from a flat format, in dot notation
+type flatEntry struct {
+	key      string
+	value    string
+	position Position
+}
+
+// nestEntries builds nested maps from flat entries, so flat formats feed Flattener.Flatten
+// like any other. Keys with an empty segment, such as .appconfig.featureflag/Beta, are kept
+// whole at the top level, which flattens to the same key.
+func nestEntries(entries []flatEntry) (interface{}, Positions, error) {
+	result := make(map[string]interface{})
+	positions := make(Positions)
+
+	var duplicates []DuplicateKey
+	for _, entry := range entries {
+		if first, exists := positions[entry.key]; exists {
+			duplicates = append(duplicates, DuplicateKey{Path: entry.key, First: first, Second: entry.position})
+			continue
+		}
+		positions[entry.key] = entry.position
+
+		parts := strings.Split(entry.key, ".")
+		for _, part := range parts {
+			if part == "" {
+				parts = []string{entry.key}
+				break
+			}
+		}
+
+		current := result
+		for i, part := range parts[:len(parts)-1] {
+			child, exists := current[part]
+			if !exists {
+				child = make(map[string]interface{})
+				current[part] = child
+			}
+
+			section, ok := child.(map[string]interface{})
+			if !ok {
+				parent := strings.Join(parts[:i+1], ".")
+				return nil, nil, fmt.Errorf("line %d: %s cannot be set because %s has a value (line %d)",
+					entry.position.Line, entry.key, parent, positions[parent].Line)
+			}
+			current = section
+		}
+
+		leaf := parts[len(parts)-1]
+		if _, exists := current[leaf]; exists {
+			return nil, nil, fmt.Errorf("line %d: %s cannot have a value because it has nested keys", entry.position.Line, entry.key)
+		}
+		current[leaf] = entry.value
+	}
+
+	if len(duplicates) > 0 {
+		return nil, nil, &DuplicateKeysError{Duplicates: duplicates}
+	}
+	return result, positions, nil
+}
+
+// flattenEntries turns nested data back into sorted dot-notation key/value pairs for the
+// flat writers. Scalars are written the way Flattener.Flatten writes them.
+func flattenEntries(data map[string]interface{}) []flatEntry {
+	var entries []flatEntry
+
+	var walk func(value interface{}, path string)
+	walk = func(value interface{}, path string) {
+		switch v := value.(type) {
+		case nil:
+			return
+		case map[string]interface{}:
+			for key, child := range v {
+				walk(child, joinPath(path, key))
+			}
+		case []interface{}:
+			for i, child := range v {
+				walk(child, joinPath(path, strconv.Itoa(i)))
+			}
+		case string:
+			entries = append(entries, flatEntry{key: path, value: v})
+		case bool:
+			entries = append(entries, flatEntry{key: path, value: strconv.FormatBool(v)})
+		case float64:
+			entries = append(entries, flatEntry{key: path, value: strconv.FormatFloat(v, 'f', -1, 64)})
+		case json.Number:
+			entries = append(entries, flatEntry{key: path, value: v.String()})
+		default:
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				entries = append(entries, flatEntry{key: path, value: strconv.FormatInt(rv.Int(), 10)})
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				entries = append(entries, flatEntry{key: path, value: strconv.FormatUint(rv.Uint(), 10)})
+			default:
+				entries = append(entries, flatEntry{key: path, value: fmt.Sprintf("%v", v)})
+			}
+		}
+	}
+	walk(data, "")
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return entries
+}
+
+// setFlat replaces the value of a key in an INI, .env or .properties file. Values spanning
+// several lines are not edited in place.
+func setFlat(content []byte, f Format, path []string, value string) ([]byte, error) {
+	_, positions, err := DecodeWithPositions(content, f)
+	if err != nil {
+		return nil, err
+	}
+	position, ok := positions[strings.Join(path, ".")]
+	if !ok {
+		return nil, notFoundError(path)
+	}
+
+	start := 0
+	for line := 1; line < position.Line; line++ {
+		start = lineEnd(content, start) + 1
+	}
+	end := lineEnd(content, start)
+	if end > start && content[end-1] == '\r' {
+		end--
+	}
+	line := string(content[start:end])
+	multiline := fmt.Errorf("%s spans several lines and cannot be edited in place", strings.Join(path, "."))
+
+	var valueStart, valueEnd int
+	var text string
+	switch f {
+	case Env:
+		separator := strings.IndexByte(line, '=')
+		valueStart = separator + 1 + len(line[separator+1:]) - len(strings.TrimLeft(line[separator+1:], " \t"))
+		rest := line[valueStart:]
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			closing := closingQuote(rest[1:], rest[0])
+			if closing < 0 {
+				return nil, multiline
+			}
+			valueEnd = valueStart + closing + 2
+		} else {
+			valueEnd = valueStart + len(strings.TrimRight(stripEnvComment(rest), " \t"))
+		}
+		text = quoteEnv(value)
+
+	case INI:
+		separator := strings.IndexAny(line, "=:")
+		valueStart = separator + 1 + len(line[separator+1:]) - len(strings.TrimLeft(line[separator+1:], " \t"))
+		valueEnd = len(strings.TrimRight(line, " \t"))
+		if valueEnd < valueStart {
+			valueEnd = valueStart
+		}
+		next := lineEnd(content, start) + 1
+		if next < len(content) {
+			following := string(content[next:lineEnd(content, next)])
+			if strings.TrimSpace(following) != "" && iniIndent(following) > iniIndent(line) {
+				return nil, multiline
+			}
+		}
+		if text, err = quoteINI(value); err != nil {
+			return nil, fmt.Errorf("%s %w", strings.Join(path, "."), err)
+		}
+
+	case Properties:
+		if endsWithEscape(line) {
+			return nil, multiline
+		}
+		keyEnd := len(line)
+		for j := position.Column - 1; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if strings.IndexByte("=: \t\f", line[j]) >= 0 {
+				keyEnd = j
+				break
+			}
+		}
+		valueStart = len(line) - len(strings.TrimLeft(line[keyEnd:], " \t\f"))
+		if valueStart < len(line) && (line[valueStart] == '=' || line[valueStart] == ':') {
+			valueStart = len(line) - len(strings.TrimLeft(line[valueStart+1:], " \t\f"))
+		}
+		valueEnd = len(line)
+		text = escapeProperties(value, false)
+
+	default:
+		return nil, fmt.Errorf("unsupported format %q", f)
+	}
+
+	return splice(content, start+valueStart, start+valueEnd, text), nil
+}
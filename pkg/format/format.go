@@ -0,0 +1,241 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+// Format is a configuration file format
+type Format string
+
+const (
+	// JSON is plain JSON, the default format
+	JSON Format = "json"
+	// YAML is a single YAML document
+	YAML Format = "yaml"
+	// Env is a .env file of NAME=value lines
+	Env Format = "env"
+	// Properties is a Java .properties file
+	Properties Format = "properties"
+	// TOML is a TOML document
+	TOML Format = "toml"
+	// INI is an INI file with [section] headers
+	INI Format = "ini"
+)
+
+// Position is where a value starts in a source file, counting from 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Positions maps the dot-notation path of every value in a file to where it starts
+type Positions map[string]Position
+
+// Location returns where the value of a flattened key is defined, as file:line:column, or
+// an empty string when it is not known. Feature flag settings are found in the featureFlags
+// section or a feature management section.
+func (p Positions) Location(file, key string) string {
+	paths := []string{key}
+	if validator.IsFeatureFlagSetting(key) {
+		name := validator.FeatureFlagName(key)
+		paths = []string{validator.FeatureFlagsSection + "." + name}
+		for _, section := range validator.FeatureManagementSections {
+			paths = append(paths, section+"."+name)
+		}
+	}
+
+	for _, path := range paths {
+		if pos, ok := p[path]; ok {
+			return fmt.Sprintf("%s:%d:%d", file, pos.Line, pos.Column)
+		}
+	}
+	return ""
+}
+
+// DuplicateKey is a key defined twice in the same object
+type DuplicateKey struct {
+	Path   string
+	First  Position
+	Second Position
+}
+
+// DuplicateKeysError reports every key defined more than once in a file. Decoders return it
+// unwrapped instead of silently keeping the last value.
+type DuplicateKeysError struct {
+	File       string
+	Duplicates []DuplicateKey
+}
+
+// Error implements the error interface
+func (e *DuplicateKeysError) Error() string {
+	message := "duplicate keys"
+	if e.File != "" {
+		message += " in " + e.File
+	}
+
+	for _, duplicate := range e.Duplicates {
+		message += fmt.Sprintf("\n  %s at line %d, column %d (first defined at line %d, column %d)",
+			duplicate.Path, duplicate.Second.Line, duplicate.Second.Column, duplicate.First.Line, duplicate.First.Column)
+	}
+	return message
+}
+
+// Formats lists the supported formats in the order they are shown in help text
+var Formats = []Format{JSON, YAML, TOML, INI, Env, Properties}
+
+// extensions maps file extensions to the format they hold
+var extensions = map[string]Format{
+	".json":       JSON,
+	".jsonc":      JSON,
+	".yaml":       YAML,
+	".yml":        YAML,
+	".toml":       TOML,
+	".ini":        INI,
+	".cfg":        INI,
+	".env":        Env,
+	".properties": Properties,
+}
+
+// HoldsFeatureFlags reports whether a format can hold the featureFlags section. Formats whose
+// values are all strings cannot, as flags need booleans.
+func (f Format) HoldsFeatureFlags() bool {
+	return f == JSON || f == YAML || f == TOML
+}
+
+// Parse parses a format name, as given to --format
+func Parse(name string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(name, string(f)) {
+			return f, nil
+		}
+	}
+
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unsupported format %q: expected one of %s", name, strings.Join(names, ", "))
+}
+
+// Detect returns the format of a file from its extension, defaulting to JSON. Files named
+// like .env.local are .env files.
+func Detect(path string) Format {
+	if f, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return f
+	}
+	if strings.HasPrefix(strings.ToLower(filepath.Base(path)), ".env.") {
+		return Env
+	}
+	return JSON
+}
+
+// Decode parses configuration content into nested maps, slices and scalars, the structure
+// Flattener.Flatten expects. JSON may contain comments and trailing commas.
+func Decode(content []byte, f Format) (interface{}, error) {
+	data, _, err := DecodeWithPositions(content, f)
+	return data, err
+}
+
+// DecodeWithPositions parses configuration content like Decode, and also returns where
+// every value starts
+func DecodeWithPositions(content []byte, f Format) (interface{}, Positions, error) {
+	switch f {
+	case JSON:
+		data, positions, err := decodeJSONC(content)
+		return data, positions, parseError("JSON", err)
+	case YAML:
+		return decodeYAML(content)
+	case TOML:
+		return decodeTOML(content)
+	case INI:
+		data, positions, err := decodeINI(content)
+		return data, positions, parseError("INI", err)
+	case Env:
+		data, positions, err := decodeEnv(content)
+		return data, positions, parseError(".env", err)
+	case Properties:
+		data, positions, err := decodeProperties(content)
+		return data, positions, parseError(".properties", err)
+	default:
+		return nil, nil, fmt.Errorf("unsupported format %q", f)
+	}
+}
+
+// parseError wraps a decoder error, leaving a DuplicateKeysError unwrapped so the file name
+// can be added to it
+func parseError(name string, err error) error {
+	var duplicates *DuplicateKeysError
+	if err != nil && !errors.As(err, &duplicates) {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return err
+}
+
+// Encode writes nested configuration in the given format
+func Encode(data map[string]interface{}, f Format) ([]byte, error) {
+	switch f {
+	case JSON:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(data); err != nil {
+			return nil, fmt.Errorf("failed to write JSON: %w", err)
+		}
+		return buf.Bytes(), nil
+	case YAML:
+		return encodeYAML(data)
+	case TOML:
+		return encodeTOML(data)
+	case INI:
+		return encodeINI(data)
+	case Env:
+		return encodeEnv(data)
+	case Properties:
+		return encodeProperties(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", f)
+	}
+}
+
+// ReadFile reads a configuration file in the format given by its extension
+func ReadFile(path string) (interface{}, error) {
+	data, _, err := ReadFileWithPositions(path)
+	return data, err
+}
+
+// ReadFileWithPositions reads a configuration file like ReadFile, and also returns where
+// every value starts
+func ReadFileWithPositions(path string) (interface{}, Positions, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, positions, err := DecodeWithPositions(content, Detect(path))
+	var duplicates *DuplicateKeysError
+	if errors.As(err, &duplicates) {
+		duplicates.File = path
+	}
+	return data, positions, err
+}
+
+// WriteFile writes nested configuration to a file in the given format
+func WriteFile(path string, data map[string]interface{}, f Format) error {
+	content, err := Encode(data, f)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
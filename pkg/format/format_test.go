@@ -0,0 +1,33 @@
+package format
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := map[string]Format{
+		"config.json":     JSON,
+		"config.yaml":     YAML,
+		"config.YML":      YAML,
+		"appsettings":     JSON,
+		"dir.yaml/config": JSON,
+		".env":            Env,
+		"dir/.env.local":  Env,
+		"app.properties":  Properties,
+		"pyproject.toml":  TOML,
+		"setup.cfg":       INI,
+	}
+
+	for path, expected := range tests {
+		if result := Detect(path); result != expected {
+			t.Errorf("Detect(%q) = %s, expected %s", path, result, expected)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if f, err := Parse("YAML"); err != nil || f != YAML {
+		t.Errorf("Parse(YAML) = %s, %v", f, err)
+	}
+	if _, err := Parse("xml"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,135 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// decodeINI parses an INI file. A [section] header, which may be dotted like [database.pool],
+// holds the keys that follow it, and keys before the first header are top-level. Keys and
+// values are separated by = or :, lines starting with ; or # are comments, and a value wrapped
+// in matching quotes loses them. Lines indented further than a key continue its value on a
+// new line, as in Python's configparser.
+func decodeINI(content []byte) (interface{}, Positions, error) {
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+
+	var entries []flatEntry
+	section := ""
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, nil, fmt.Errorf("line %d: section header is missing ']'", lineNumber)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == "" {
+				return nil, nil, fmt.Errorf("line %d: empty section name", lineNumber)
+			}
+			continue
+		}
+
+		separator := strings.IndexAny(line, "=:")
+		if separator < 0 {
+			return nil, nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		key := strings.TrimSpace(line[:separator])
+		if key == "" {
+			return nil, nil, fmt.Errorf("line %d: missing key", lineNumber)
+		}
+		value := strings.TrimSpace(line[separator+1:])
+
+		// Lines indented further than the key continue its value
+		indent := iniIndent(lines[i])
+		for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && iniIndent(lines[i+1]) > indent {
+			i++
+			value += "\n" + strings.TrimSpace(lines[i])
+		}
+
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		entries = append(entries, flatEntry{
+			key:      joinPath(section, key),
+			value:    value,
+			position: Position{Line: lineNumber, Column: indent + 1},
+		})
+	}
+
+	return nestEntries(entries)
+}
+
+// iniIndent returns the number of spaces and tabs a line starts with
+func iniIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// encodeINI writes nested configuration as an INI file. Top-level values come first, then a
+// section for every group of values, named by their dotted path. Values with surrounding
+// spaces or quotes are quoted, and multiline values are written as indented continuation lines.
+func encodeINI(data map[string]interface{}) ([]byte, error) {
+	sections := make(map[string][]flatEntry)
+	for _, entry := range flattenEntries(data) {
+		section, key := "", entry.key
+		if i := strings.LastIndex(entry.key, "."); i >= 0 {
+			section, key = entry.key[:i], entry.key[i+1:]
+		}
+		if key == "" || strings.ContainsAny(key, "=:") || strings.ContainsAny(key[:1], "[;# \t") || strings.HasSuffix(key, " ") {
+			return nil, fmt.Errorf("failed to write INI: %s cannot be written as a key", entry.key)
+		}
+
+		value, err := quoteINI(entry.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write INI: %s %w", entry.key, err)
+		}
+		sections[section] = append(sections[section], flatEntry{key: key, value: value})
+	}
+
+	names := make([]string, 0, len(sections))
+	for name := range sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		if name != "" {
+			if buf.Len() > 0 {
+				buf.WriteByte('\n')
+			}
+			fmt.Fprintf(&buf, "[%s]\n", name)
+		}
+		for _, entry := range sections[name] {
+			fmt.Fprintf(&buf, "%s = %s\n", entry.key, entry.value)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteINI returns a value as it is written in an INI file
+func quoteINI(value string) (string, error) {
+	if !strings.Contains(value, "\n") {
+		if value != strings.TrimSpace(value) || strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+			return `"` + value + `"`, nil
+		}
+		return value, nil
+	}
+
+	lines := strings.Split(value, "\n")
+	for _, line := range lines {
+		if line == "" || line != strings.TrimSpace(line) {
+			return "", fmt.Errorf("has a multiline value with blank or indented lines, which INI cannot hold")
+		}
+	}
+	if strings.HasPrefix(lines[0], `"`) || strings.HasPrefix(lines[0], "'") {
+		return "", fmt.Errorf("has a multiline value starting with a quote, which INI cannot hold")
+	}
+	return strings.Join(lines, "\n    "), nil
+}
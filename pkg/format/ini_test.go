@@ -0,0 +1,103 @@
+package format
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode_INI(t *testing.T) {
+	content := `; service settings
+name = orders
+
+[database]
+host = localhost
+port: 5432
+greeting = "  padded  "
+description = first line
+    second line
+
+[database.pool]
+	size = 10
+`
+
+	data, positions, err := DecodeWithPositions([]byte(content), INI)
+	if err != nil {
+		t.Fatalf("DecodeWithPositions() error = %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"name": "orders",
+		"database": map[string]interface{}{
+			"host":        "localhost",
+			"port":        "5432",
+			"greeting":    "  padded  ",
+			"description": "first line\nsecond line",
+			"pool":        map[string]interface{}{"size": "10"},
+		},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("data = %#v, expected %#v", data, expected)
+	}
+
+	if location := positions.Location("app.ini", "database.pool.size"); location != "app.ini:12:2" {
+		t.Errorf("Location() = %q, expected app.ini:12:2", location)
+	}
+}
+
+func TestDecode_INIErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"unclosed section", "[database\n", "line 1: section header is missing ']'"},
+		{"missing separator", "[database]\nhost\n", "line 2: expected key = value"},
+		{"duplicate key", "[db]\nhost = a\n[db]\nhost = b\n", "db.host at line 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.content), INI)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Decode() error = %v, expected it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncode_INI(t *testing.T) {
+	data := map[string]interface{}{
+		"name": "orders",
+		"database": map[string]interface{}{
+			"host":        "localhost",
+			"greeting":    "  padded  ",
+			"description": "first line\nsecond line",
+			"pool":        map[string]interface{}{"size": float64(10)},
+		},
+	}
+
+	content, err := Encode(data, INI)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	expected := `name = orders
+
+[database]
+description = first line
+    second line
+greeting = "  padded  "
+host = localhost
+
+[database.pool]
+size = 10
+`
+	if string(content) != expected {
+		t.Errorf("Encode() = %q, expected %q", content, expected)
+	}
+
+	if _, err := Encode(map[string]interface{}{"a": "one\n\nthree"}, INI); err == nil {
+		t.Error("expected error for a multiline value with a blank line")
+	}
+}
@@ -0,0 +1,564 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsoncParser parses JSON with comments and trailing commas, the dialect of appsettings.json
+// and VS Code settings, and records where every value starts. Values decode to the same
+// types as encoding/json with UseNumber: maps, slices, strings, json.Number, booleans and nil.
+type jsoncParser struct {
+	content    []byte
+	offset     int
+	lineStarts []int
+	positions  Positions
+	duplicates []DuplicateKey
+
+	// spans and objects record the layout of the content for editing; they are nil otherwise
+	spans   map[string]jsoncSpan
+	objects map[string]*jsoncObject
+}
+
+// jsoncSpan is where a value starts and ends in the content, as byte offsets
+type jsoncSpan struct {
+	start, end int
+}
+
+// jsoncObject is where an object and its members are in the content
+type jsoncObject struct {
+	open, close int
+	members     []jsoncMember
+}
+
+// jsoncMember is a property of an object, from the start of its name to the end of its value
+type jsoncMember struct {
+	key      string
+	keyStart int
+	valueEnd int
+}
+
+// newJSONCParser creates a parser for the content
+func newJSONCParser(content []byte) *jsoncParser {
+	p := &jsoncParser{
+		content:    content,
+		lineStarts: []int{0},
+		positions:  make(Positions),
+	}
+	for i, b := range content {
+		if b == '\n' {
+			p.lineStarts = append(p.lineStarts, i+1)
+		}
+	}
+	return p
+}
+
+// decodeJSONC parses JSON that may contain // and /* */ comments and trailing commas
+func decodeJSONC(content []byte) (interface{}, Positions, error) {
+	p := newJSONCParser(content)
+	value, err := p.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, p.positions, nil
+}
+
+// parse parses the whole content as a single value
+func (p *jsoncParser) parse() (interface{}, error) {
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	value, err := p.parseValue("")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.offset < len(p.content) {
+		return nil, p.errorf("unexpected %s after the top-level value", p.describe())
+	}
+
+	if len(p.duplicates) > 0 {
+		return nil, &DuplicateKeysError{Duplicates: p.duplicates}
+	}
+	return value, nil
+}
+
+// position returns the line and column of a byte offset, counting columns in characters
+func (p *jsoncParser) position(offset int) Position {
+	line := sort.Search(len(p.lineStarts), func(i int) bool { return p.lineStarts[i] > offset }) - 1
+	column := utf8.RuneCount(p.content[p.lineStarts[line]:offset]) + 1
+	return Position{Line: line + 1, Column: column}
+}
+
+// errorf returns a parse error at the current offset
+func (p *jsoncParser) errorf(format string, args ...interface{}) error {
+	return p.errorAt(p.offset, format, args...)
+}
+
+// errorAt returns a parse error at the given offset
+func (p *jsoncParser) errorAt(offset int, format string, args ...interface{}) error {
+	pos := p.position(offset)
+	return fmt.Errorf("line %d, column %d: %s", pos.Line, pos.Column, fmt.Sprintf(format, args...))
+}
+
+// describe names the character at the current offset for error messages
+func (p *jsoncParser) describe() string {
+	if p.offset >= len(p.content) {
+		return "end of file"
+	}
+	r, _ := utf8.DecodeRune(p.content[p.offset:])
+	return strconv.QuoteRune(r)
+}
+
+// skipSpace skips whitespace and comments
+func (p *jsoncParser) skipSpace() error {
+	for p.offset < len(p.content) {
+		switch p.content[p.offset] {
+		case ' ', '\t', '\n', '\r':
+			p.offset++
+		case '/':
+			if p.offset+1 >= len(p.content) {
+				return p.errorf("unexpected '/'")
+			}
+			switch p.content[p.offset+1] {
+			case '/':
+				for p.offset < len(p.content) && p.content[p.offset] != '\n' {
+					p.offset++
+				}
+			case '*':
+				start := p.offset
+				p.offset += 2
+				for {
+					if p.offset+1 >= len(p.content) {
+						return p.errorAt(start, "unterminated comment")
+					}
+					if p.content[p.offset] == '*' && p.content[p.offset+1] == '/' {
+						p.offset += 2
+						break
+					}
+					p.offset++
+				}
+			default:
+				return p.errorf("unexpected '/'")
+			}
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseValue parses the value at the current offset, which is not whitespace
+func (p *jsoncParser) parseValue(path string) (interface{}, error) {
+	if path != "" {
+		p.positions[path] = p.position(p.offset)
+	}
+	if p.spans != nil {
+		start := p.offset
+		defer func() { p.spans[path] = jsoncSpan{start: start, end: p.offset} }()
+	}
+	if p.offset >= len(p.content) {
+		return nil, p.errorf("unexpected end of file, expected a value")
+	}
+
+	switch c := p.content[p.offset]; {
+	case c == '{':
+		return p.parseObject(path)
+	case c == '[':
+		return p.parseArray(path)
+	case c == '"':
+		return p.parseString()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case p.hasLiteral("true"):
+		p.offset += len("true")
+		return true, nil
+	case p.hasLiteral("false"):
+		p.offset += len("false")
+		return false, nil
+	case p.hasLiteral("null"):
+		p.offset += len("null")
+		return nil, nil
+	default:
+		return nil, p.errorf("unexpected %s, expected a value", p.describe())
+	}
+}
+
+// hasLiteral reports whether a literal such as true starts at the current offset
+func (p *jsoncParser) hasLiteral(literal string) bool {
+	end := p.offset + len(literal)
+	if end > len(p.content) || string(p.content[p.offset:end]) != literal {
+		return false
+	}
+	// The literal must not run into an identifier, as in "trueish"
+	return end == len(p.content) || !isIdentifierByte(p.content[end])
+}
+
+// parseObject parses an object, allowing a trailing comma. Keys defined twice are recorded
+// as duplicates rather than letting the last one win.
+func (p *jsoncParser) parseObject(path string) (interface{}, error) {
+	var object *jsoncObject
+	if p.objects != nil {
+		object = &jsoncObject{open: p.offset}
+		p.objects[path] = object
+	}
+
+	p.offset++ // {
+	result := make(map[string]interface{})
+	keyPositions := make(map[string]Position)
+
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.offset < len(p.content) && p.content[p.offset] == '}' {
+			if object != nil {
+				object.close = p.offset
+			}
+			p.offset++
+			return result, nil
+		}
+		if p.offset >= len(p.content) || p.content[p.offset] != '"' {
+			return nil, p.errorf("unexpected %s, expected a property name or '}'", p.describe())
+		}
+
+		keyStart := p.offset
+		keyPosition := p.position(p.offset)
+		key, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		if first, exists := keyPositions[key]; exists {
+			p.duplicates = append(p.duplicates, DuplicateKey{Path: joinPath(path, key), First: first, Second: keyPosition})
+		} else {
+			keyPositions[key] = keyPosition
+		}
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.offset >= len(p.content) || p.content[p.offset] != ':' {
+			return nil, p.errorf("unexpected %s, expected ':'", p.describe())
+		}
+		p.offset++
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+
+		value, err := p.parseValue(joinPath(path, key))
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+		if object != nil {
+			object.members = append(object.members, jsoncMember{key: key, keyStart: keyStart, valueEnd: p.offset})
+		}
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.offset < len(p.content) && p.content[p.offset] == ',' {
+			p.offset++
+			continue
+		}
+		if p.offset < len(p.content) && p.content[p.offset] == '}' {
+			if object != nil {
+				object.close = p.offset
+			}
+			p.offset++
+			return result, nil
+		}
+		return nil, p.errorf("unexpected %s, expected ',' or '}'", p.describe())
+	}
+}
+
+// parseArray parses an array, allowing a trailing comma
+func (p *jsoncParser) parseArray(path string) (interface{}, error) {
+	p.offset++ // [
+	result := make([]interface{}, 0)
+
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.offset < len(p.content) && p.content[p.offset] == ']' {
+			p.offset++
+			return result, nil
+		}
+
+		value, err := p.parseValue(joinPath(path, strconv.Itoa(len(result))))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.offset < len(p.content) && p.content[p.offset] == ',' {
+			p.offset++
+			continue
+		}
+		if p.offset < len(p.content) && p.content[p.offset] == ']' {
+			p.offset++
+			return result, nil
+		}
+		return nil, p.errorf("unexpected %s, expected ',' or ']'", p.describe())
+	}
+}
+
+// parseString parses a quoted string, leaving escape handling to encoding/json
+func (p *jsoncParser) parseString() (string, error) {
+	start := p.offset
+	p.offset++ // opening quote
+
+	for p.offset < len(p.content) {
+		switch p.content[p.offset] {
+		case '\\':
+			p.offset += 2
+		case '"':
+			p.offset++
+			var value string
+			if err := json.Unmarshal(p.content[start:p.offset], &value); err != nil {
+				return "", p.errorAt(start, "invalid string: %v", err)
+			}
+			return value, nil
+		case '\n':
+			return "", p.errorAt(start, "unterminated string")
+		default:
+			p.offset++
+		}
+	}
+
+	return "", p.errorAt(start, "unterminated string")
+}
+
+// parseNumber parses a number, keeping its text as written so large integers and trailing
+// zeros survive flattening
+func (p *jsoncParser) parseNumber() (interface{}, error) {
+	start := p.offset
+	for p.offset < len(p.content) && isNumberByte(p.content[p.offset]) {
+		p.offset++
+	}
+
+	var value json.Number
+	if err := json.Unmarshal(p.content[start:p.offset], &value); err != nil {
+		return nil, p.errorAt(start, "invalid number %q", p.content[start:p.offset])
+	}
+	return value, nil
+}
+
+// isNumberByte reports whether a byte can be part of a JSON number
+func isNumberByte(b byte) bool {
+	return (b >= '0' && b <= '9') || b == '-' || b == '+' || b == '.' || b == 'e' || b == 'E'
+}
+
+// isIdentifierByte reports whether a byte continues a bare word
+func isIdentifierByte(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') || b == '_'
+}
+
+// joinPath joins a parent path and a key with dot notation, like the flattener
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// parseJSONCLayout parses content and records where every value, object and member is
+func parseJSONCLayout(content []byte) (*jsoncParser, error) {
+	p := newJSONCParser(content)
+	p.spans = make(map[string]jsoncSpan)
+	p.objects = make(map[string]*jsoncObject)
+	if _, err := p.parse(); err != nil {
+		return nil, parseError("JSON", err)
+	}
+	return p, nil
+}
+
+// commaAfter returns the offset of the comma following offset, past whitespace and
+// comments, or -1 when the next token is not a comma
+func (p *jsoncParser) commaAfter(offset int) int {
+	p.offset = offset
+	if err := p.skipSpace(); err != nil || p.offset >= len(p.content) || p.content[p.offset] != ',' {
+		return -1
+	}
+	return p.offset
+}
+
+// indentUnit returns the indentation the file uses for one level of nesting
+func (p *jsoncParser) indentUnit() string {
+	root, ok := p.objects[""]
+	if ok && len(root.members) > 0 && firstOnLine(p.content, root.members[0].keyStart) {
+		if indent := lineIndent(p.content, root.members[0].keyStart); indent != "" {
+			return indent
+		}
+	}
+	return "  "
+}
+
+// setJSONC replaces the value at path with a JSON string
+func setJSONC(content []byte, path []string, value string) ([]byte, error) {
+	p, err := parseJSONCLayout(content)
+	if err != nil {
+		return nil, err
+	}
+
+	span, ok := p.spans[strings.Join(path, ".")]
+	if !ok || len(path) == 0 {
+		return nil, notFoundError(path)
+	}
+	return splice(content, span.start, span.end, quoteString(value)), nil
+}
+
+// removeJSONC removes the member at path from its object, with its comma and, when it had
+// them to itself, its lines
+func removeJSONC(content []byte, path []string) ([]byte, error) {
+	p, err := parseJSONCLayout(content)
+	if err != nil {
+		return nil, err
+	}
+
+	object, ok := p.objects[strings.Join(path[:len(path)-1], ".")]
+	if !ok {
+		return nil, notFoundError(path)
+	}
+	index := -1
+	for i, member := range object.members {
+		if member.key == path[len(path)-1] {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, notFoundError(path)
+	}
+
+	member := object.members[index]
+	if comma := p.commaAfter(member.valueEnd); comma >= 0 {
+		start, end := wholeLines(content, member.keyStart, comma+1, "//")
+		if start == member.keyStart {
+			// Within a line, the space after the comma goes too
+			for end < len(content) && (content[end] == ' ' || content[end] == '\t') {
+				end++
+			}
+		}
+		return splice(content, start, end, ""), nil
+	}
+	if index > 0 {
+		// The last member gives up the comma before it instead
+		if comma := p.commaAfter(object.members[index-1].valueEnd); comma >= 0 {
+			return splice(content, comma, member.valueEnd, ""), nil
+		}
+	}
+	start, end := wholeLines(content, member.keyStart, member.valueEnd, "//")
+	return splice(content, start, end, ""), nil
+}
+
+// addJSONC adds a member at path after the last member of its object, indented like it
+func addJSONC(content []byte, path []string, value interface{}) ([]byte, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		content = []byte("{}" + lineBreak(content))
+	}
+
+	p, err := parseJSONCLayout(content)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := strings.Join(path[:len(path)-1], ".")
+	object, ok := p.objects[parent]
+	if !ok {
+		if _, exists := p.spans[parent]; exists {
+			return nil, fmt.Errorf("%s is not an object", parent)
+		}
+		return addJSONC(content, path[:len(path)-1], map[string]interface{}{path[len(path)-1]: value})
+	}
+
+	key := path[len(path)-1]
+	for _, member := range object.members {
+		if member.key == key {
+			return nil, fmt.Errorf("%s already exists", strings.Join(path, "."))
+		}
+	}
+
+	newline := lineBreak(content)
+	unit := p.indentUnit()
+
+	if len(object.members) == 0 {
+		outer := lineIndent(content, object.open)
+		inner := outer + unit
+		text, err := jsoncMemberText(key, value, inner, unit, newline)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(content[object.open+1:object.close])) == 0 {
+			return splice(content, object.open+1, object.close, newline+inner+text+newline+outer), nil
+		}
+		return splice(content, object.open+1, object.open+1, newline+inner+text+","), nil
+	}
+
+	last := object.members[len(object.members)-1]
+	if !firstOnLine(content, last.keyStart) {
+		// A single-line object stays on one line
+		text, err := jsoncMemberText(key, value, "", "", "")
+		if err != nil {
+			return nil, err
+		}
+		return splice(content, last.valueEnd, last.valueEnd, ", "+text), nil
+	}
+
+	indent := lineIndent(content, last.keyStart)
+	text, err := jsoncMemberText(key, value, indent, unit, newline)
+	if err != nil {
+		return nil, err
+	}
+
+	// A trailing comma stays trailing; otherwise the last member gets a comma, and a
+	// comment at the end of its line stays with it
+	comma := p.commaAfter(last.valueEnd)
+	after := last.valueEnd
+	if comma >= 0 {
+		after = comma + 1
+	}
+	insertAt := lineEnd(content, after)
+	if insertAt > object.close {
+		insertAt = after
+	} else if insertAt > 0 && content[insertAt-1] == '\r' {
+		insertAt--
+	}
+
+	if comma >= 0 {
+		return splice(content, insertAt, insertAt, newline+indent+text+","), nil
+	}
+	edited := splice(content, insertAt, insertAt, newline+indent+text)
+	return splice(edited, last.valueEnd, last.valueEnd, ","), nil
+}
+
+// jsoncMemberText writes a member as "key": value, with nested lines indented under indent.
+// Without a unit the value is written on one line.
+func jsoncMemberText(key string, value interface{}, indent, unit, newline string) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if unit != "" {
+		encoder.SetIndent(indent, unit)
+	}
+	if err := encoder.Encode(value); err != nil {
+		return "", fmt.Errorf("failed to write JSON: %w", err)
+	}
+
+	text := strings.TrimSuffix(buf.String(), "\n")
+	if newline != "\n" {
+		text = strings.ReplaceAll(text, "\n", newline)
+	}
+	return quoteString(key) + ": " + text, nil
+}
@@ -0,0 +1,97 @@
+package format
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode_JSONC(t *testing.T) {
+	content := `{
+  // Logging settings
+  "Logging": {
+    "LogLevel": { "Default": "Information", }, /* trailing comma */
+  },
+  "Servers": ["web1", "web2",],
+  "Port": 8080,
+  "Id": 12345678901234567890,
+  "Ratio": 1.10,
+  "Url": "http://example.com/api", // not a comment inside a string
+  "Enabled": true,
+  "Missing": null,
+}`
+
+	data, positions, err := DecodeWithPositions([]byte(content), JSON)
+	if err != nil {
+		t.Fatalf("DecodeWithPositions() error = %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"Logging": map[string]interface{}{
+			"LogLevel": map[string]interface{}{"Default": "Information"},
+		},
+		"Servers": []interface{}{"web1", "web2"},
+		"Port":    json.Number("8080"),
+		"Id":      json.Number("12345678901234567890"),
+		"Ratio":   json.Number("1.10"),
+		"Url":     "http://example.com/api",
+		"Enabled": true,
+		"Missing": nil,
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("data = %#v", data)
+	}
+
+	tests := map[string]Position{
+		"Logging.LogLevel.Default": {Line: 4, Column: 30},
+		"Servers.1":                {Line: 6, Column: 23},
+		"Port":                     {Line: 7, Column: 11},
+		"Ratio":                    {Line: 9, Column: 12},
+	}
+	for path, pos := range tests {
+		if positions[path] != pos {
+			t.Errorf("positions[%s] = %v, expected %v", path, positions[path], pos)
+		}
+	}
+
+	if location := positions.Location("appsettings.json", "Port"); location != "appsettings.json:7:11" {
+		t.Errorf("Location() = %q", location)
+	}
+}
+
+func TestDecode_JSONCFeatureFlagLocation(t *testing.T) {
+	_, positions, err := DecodeWithPositions([]byte("{\n  \"featureFlags\": {\n    \"Beta\": true\n  }\n}"), JSON)
+	if err != nil {
+		t.Fatalf("DecodeWithPositions() error = %v", err)
+	}
+
+	if location := positions.Location("config.json", ".appconfig.featureflag/Beta"); location != "config.json:3:13" {
+		t.Errorf("Location() = %q", location)
+	}
+}
+
+func TestDecode_JSONCErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"missing comma", "{\n  \"a\": 1\n  \"b\": 2\n}", "line 3, column 3: unexpected '\"', expected ',' or '}'"},
+		{"unterminated comment", "{ /* open", "line 1, column 3: unterminated comment"},
+		{"unterminated string", "{\"a\": \"open\n}", "line 1, column 7: unterminated string"},
+		{"bad literal", "{\"a\": trueish}", "expected a value"},
+		{"trailing content", "{} {}", "after the top-level value"},
+		{"empty", "  // nothing\n", "unexpected end of file"},
+		{"invalid number", "{\"a\": 1.2.3}", "invalid number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.content), JSON)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Decode() error = %v, expected %q", err, tt.expected)
+			}
+		})
+	}
+}
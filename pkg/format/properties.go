@@ -0,0 +1,174 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// decodeProperties parses a Java .properties file with the rules of java.util.Properties.
+// Lines starting with # or ! are comments, a key ends at the first unescaped =, : or
+// whitespace, and a line ending in a backslash continues on the next one. Escapes such as
+// \n, \t and \uXXXX are expanded. Keys already use dot notation. The file is read as UTF-8.
+func decodeProperties(content []byte) (interface{}, Positions, error) {
+	text := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(string(content))
+	lines := strings.Split(text, "\n")
+
+	var entries []flatEntry
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		column := len(lines[i]) - len(line) + 1
+
+		// Join continuation lines, dropping their leading whitespace
+		for endsWithEscape(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		if endsWithEscape(line) {
+			line = line[:len(line)-1]
+		}
+
+		keyEnd := len(line)
+		for j := 0; j < len(line); j++ {
+			if line[j] == '\\' {
+				j++
+				continue
+			}
+			if strings.IndexByte("=: \t\f", line[j]) >= 0 {
+				keyEnd = j
+				break
+			}
+		}
+
+		rest := strings.TrimLeft(line[keyEnd:], " \t\f")
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+
+		key, err := unescapeProperties(line[:keyEnd])
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if key == "" {
+			return nil, nil, fmt.Errorf("line %d: missing key", lineNumber)
+		}
+		value, err := unescapeProperties(rest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		entries = append(entries, flatEntry{key: key, value: value, position: Position{Line: lineNumber, Column: column}})
+	}
+
+	return nestEntries(entries)
+}
+
+// endsWithEscape reports whether a line ends in an odd number of backslashes, which
+// continues it on the next line
+func endsWithEscape(line string) bool {
+	count := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+// unescapeProperties expands the escapes of a key or value. A backslash before any other
+// character stands for that character.
+func unescapeProperties(value string) (string, error) {
+	if !strings.Contains(value, `\`) {
+		return value, nil
+	}
+
+	var units []uint16
+	for _, r := range value {
+		units = utf16.AppendRune(units, r)
+	}
+
+	var result []uint16
+	for i := 0; i < len(units); i++ {
+		if units[i] != '\\' || i+1 == len(units) {
+			result = append(result, units[i])
+			continue
+		}
+
+		i++
+		switch units[i] {
+		case 't':
+			result = append(result, '\t')
+		case 'n':
+			result = append(result, '\n')
+		case 'r':
+			result = append(result, '\r')
+		case 'f':
+			result = append(result, '\f')
+		case 'u':
+			if i+5 > len(units) {
+				return "", fmt.Errorf("malformed \\uXXXX escape")
+			}
+			code, err := strconv.ParseUint(string(utf16.Decode(units[i+1:i+5])), 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\uXXXX escape")
+			}
+			// Characters outside the Basic Multilingual Plane are written as two escapes,
+			// which decode back to one character below
+			result = append(result, uint16(code))
+			i += 4
+		default:
+			result = append(result, units[i])
+		}
+	}
+
+	return string(utf16.Decode(result)), nil
+}
+
+// encodeProperties writes nested configuration as key=value lines, sorted by key, escaped
+// like java.util.Properties.store: separators, comment characters and backslashes are
+// escaped, and characters outside printable ASCII are written as \uXXXX so the file reads
+// the same as ISO-8859-1 or UTF-8
+func encodeProperties(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, entry := range flattenEntries(data) {
+		fmt.Fprintf(&buf, "%s=%s\n", escapeProperties(entry.key, true), escapeProperties(entry.value, false))
+	}
+	return buf.Bytes(), nil
+}
+
+// escapeProperties escapes a key or value. Every space in a key is escaped, but only a
+// leading space in a value.
+func escapeProperties(value string, isKey bool) string {
+	var result strings.Builder
+	for i, r := range value {
+		switch {
+		case r == ' ':
+			if isKey || i == 0 {
+				result.WriteByte('\\')
+			}
+			result.WriteByte(' ')
+		case r == '\\' || r == '=' || r == ':' || r == '#' || r == '!':
+			result.WriteByte('\\')
+			result.WriteRune(r)
+		case r == '\t':
+			result.WriteString(`\t`)
+		case r == '\n':
+			result.WriteString(`\n`)
+		case r == '\r':
+			result.WriteString(`\r`)
+		case r == '\f':
+			result.WriteString(`\f`)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16.AppendRune(nil, r) {
+				fmt.Fprintf(&result, `\u%04X`, unit)
+			}
+		default:
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
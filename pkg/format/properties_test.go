@@ -0,0 +1,107 @@
+package format
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode_Properties(t *testing.T) {
+	content := `# Spring settings
+! also a comment
+spring.datasource.url = jdbc:postgresql://localhost:5432/app
+spring.datasource.username:admin
+server.port 8080
+app.greeting=Gr\u00FC\u00DFe \uD83D\uDE00
+app.path=C:\\temp\\logs
+app.description=first line \
+    second line
+app.key\ with\ spaces=value
+app.multiline=one\ntwo
+`
+
+	data, positions, err := DecodeWithPositions([]byte(content), Properties)
+	if err != nil {
+		t.Fatalf("DecodeWithPositions() error = %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"spring": map[string]interface{}{
+			"datasource": map[string]interface{}{
+				"url":      "jdbc:postgresql://localhost:5432/app",
+				"username": "admin",
+			},
+		},
+		"server": map[string]interface{}{"port": "8080"},
+		"app": map[string]interface{}{
+			"greeting":        "Grüße 😀",
+			"path":            `C:\temp\logs`,
+			"description":     "first line second line",
+			"key with spaces": "value",
+			"multiline":       "one\ntwo",
+		},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("data = %#v, expected %#v", data, expected)
+	}
+
+	if location := positions.Location("application.properties", "server.port"); location != "application.properties:5:1" {
+		t.Errorf("Location() = %q, expected application.properties:5:1", location)
+	}
+}
+
+func TestDecode_PropertiesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"malformed unicode", "a=\\u12\n", "line 1: malformed \\uXXXX escape"},
+		{"missing key", "=value\n", "line 1: missing key"},
+		{"value and section", "a.b=1\na.b.c=2\n", "a.b.c cannot be set because a.b has a value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.content), Properties)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Decode() error = %v, expected it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncode_Properties(t *testing.T) {
+	data := map[string]interface{}{
+		"app": map[string]interface{}{
+			"greeting":  "Grüße 😀",
+			"url":       "http://host:80/?a=b#top",
+			"padded":    "  indented",
+			"multiline": "one\ntwo",
+		},
+		"key with space": "value",
+	}
+
+	content, err := Encode(data, Properties)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	expected := `app.greeting=Gr\u00FC\u00DFe \uD83D\uDE00
+app.multiline=one\ntwo
+app.padded=\  indented
+app.url=http\://host\:80/?a\=b\#top
+key\ with\ space=value
+`
+	if string(content) != expected {
+		t.Errorf("Encode() = %q, expected %q", content, expected)
+	}
+
+	decoded, err := Decode(content, Properties)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, data) {
+		t.Errorf("round trip = %#v, expected %#v", decoded, data)
+	}
+}
@@ -0,0 +1,558 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlLayouts are the TOML date and time types, keyed by the name of the location the TOML
+// decoder gives them. Offset date-times use the time's own location.
+var tomlLayouts = map[string]string{
+	"datetime-local": "2006-01-02T15:04:05.999999999",
+	"date-local":     "2006-01-02",
+	"time-local":     "15:04:05.999999999",
+}
+
+// decodeTOML parses a TOML document. Tables and arrays of tables become nested maps and
+// lists, and date and time values become strings in their TOML form, so a local date stays
+// 2024-01-02 rather than gaining a time and a zone. Duplicate keys are errors in TOML itself.
+func decodeTOML(content []byte) (interface{}, Positions, error) {
+	var data map[string]interface{}
+	if _, err := toml.Decode(string(content), &data); err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, nil, fmt.Errorf("failed to parse TOML: line %d, column %d: %s",
+				parseErr.Position.Line, parseErr.Position.Col, parseErr.Message)
+		}
+		return nil, nil, fmt.Errorf("failed to parse TOML: %w", err)
+	}
+
+	// The TOML decoder does not report where keys are defined
+	return tomlValue(data), make(Positions), nil
+}
+
+// tomlValue converts decoded TOML into plain maps, slices and scalars
+func tomlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = tomlValue(child)
+		}
+		return v
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, table := range v {
+			result[i] = tomlValue(table)
+		}
+		return result
+	case []interface{}:
+		for i, child := range v {
+			v[i] = tomlValue(child)
+		}
+		return v
+	case time.Time:
+		if layout, ok := tomlLayouts[v.Location().String()]; ok {
+			return v.Format(layout)
+		}
+		return v.Format(time.RFC3339Nano)
+	default:
+		return v
+	}
+}
+
+// tomlDatetime is a date or time written bare rather than as a quoted string
+type tomlDatetime string
+
+// MarshalTOML implements toml.Marshaler
+func (d tomlDatetime) MarshalTOML() ([]byte, error) {
+	return []byte(d), nil
+}
+
+// isTOMLDatetime reports whether a string is exactly a TOML date or time, as the decoder
+// writes them, so writing it bare reads back the same string
+func isTOMLDatetime(value string) bool {
+	layouts := []string{time.RFC3339Nano}
+	for _, layout := range tomlLayouts {
+		layouts = append(layouts, layout)
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil && t.Format(layout) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// tomlEncodable prepares nested configuration for the TOML encoder: null values are left
+// out, since TOML has none, whole numbers are written as integers rather than 5432.0, and
+// dates and times are written bare
+func tomlEncodable(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			if child != nil {
+				result[key] = tomlEncodable(child)
+			}
+		}
+		return result
+	case []interface{}:
+		// A list of tables is written as an array of tables
+		tables := make([]map[string]interface{}, 0, len(v))
+		for _, child := range v {
+			if table, ok := child.(map[string]interface{}); ok {
+				tables = append(tables, tomlEncodable(table).(map[string]interface{}))
+			}
+		}
+		if len(tables) == len(v) && len(v) > 0 {
+			return tables
+		}
+
+		result := make([]interface{}, 0, len(v))
+		for _, child := range v {
+			if child != nil {
+				result = append(result, tomlEncodable(child))
+			}
+		}
+		return result
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return v
+	case json.Number:
+		if integer, err := v.Int64(); err == nil {
+			return integer
+		}
+		if number, err := v.Float64(); err == nil {
+			return number
+		}
+		return v.String()
+	case string:
+		if isTOMLDatetime(v) {
+			return tomlDatetime(v)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// encodeTOML writes nested configuration as a TOML document, with sections as tables
+func encodeTOML(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := toml.NewEncoder(&buf)
+	encoder.Indent = ""
+	if err := encoder.Encode(tomlEncodable(data)); err != nil {
+		return nil, fmt.Errorf("failed to write TOML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// tomlEntry is a key/value pair of a TOML document, from the start of its key to the end of its value
+type tomlEntry struct {
+	table      []string
+	path       []string
+	start      int
+	valueStart int
+	valueEnd   int
+	// inArray is true for keys of an array of tables, which a path cannot address
+	inArray bool
+}
+
+// tomlTable is a [table] or [[array of tables]] header line
+type tomlTable struct {
+	path       []string
+	start, end int
+	array      bool
+}
+
+// scanTOML finds where the keys and table headers of a TOML document are. Values are
+// skipped over rather than decoded; decodeTOML checks them.
+func scanTOML(content []byte) ([]tomlEntry, []tomlTable, error) {
+	var entries []tomlEntry
+	var tables []tomlTable
+	var table []string
+	inArray := false
+
+	for i := 0; i < len(content); {
+		switch content[i] {
+		case ' ', '\t', '\r', '\n':
+			i++
+		case '#':
+			i = lineEnd(content, i)
+		case '[':
+			array := i+1 < len(content) && content[i+1] == '['
+			j := i + 1
+			if array {
+				j++
+			}
+			keys, j, err := tomlKey(content, j)
+			if err != nil {
+				return nil, nil, err
+			}
+			closing := "]"
+			if array {
+				closing = "]]"
+			}
+			if !bytes.HasPrefix(content[j:], []byte(closing)) {
+				return nil, nil, fmt.Errorf("failed to parse TOML: table header at offset %d is missing %s", i, closing)
+			}
+			tables = append(tables, tomlTable{path: keys, start: i, end: lineEnd(content, j), array: array})
+			table, inArray = keys, array
+			i = j + len(closing)
+		default:
+			keys, j, err := tomlKey(content, i)
+			if err != nil {
+				return nil, nil, err
+			}
+			if j >= len(content) || content[j] != '=' {
+				return nil, nil, fmt.Errorf("failed to parse TOML: expected '=' after key at offset %d", i)
+			}
+			j++
+			for j < len(content) && (content[j] == ' ' || content[j] == '\t') {
+				j++
+			}
+			end, err := tomlValueEnd(content, j)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			path := append(append([]string{}, table...), keys...)
+			entries = append(entries, tomlEntry{table: table, path: path, start: i, valueStart: j, valueEnd: end, inArray: inArray})
+			i = end
+		}
+	}
+
+	return entries, tables, nil
+}
+
+// tomlKey reads a bare, quoted or dotted key, returning its parts and the offset after it
+func tomlKey(content []byte, i int) ([]string, int, error) {
+	var keys []string
+	for {
+		for i < len(content) && (content[i] == ' ' || content[i] == '\t') {
+			i++
+		}
+		if i >= len(content) {
+			return nil, i, fmt.Errorf("failed to parse TOML: unexpected end of file, expected a key")
+		}
+
+		switch content[i] {
+		case '"', '\'':
+			end, err := tomlValueEnd(content, i)
+			if err != nil {
+				return nil, i, err
+			}
+			key := string(content[i+1 : end-1])
+			if content[i] == '"' {
+				if err := json.Unmarshal(content[i:end], &key); err != nil {
+					return nil, i, fmt.Errorf("failed to parse TOML: invalid key %s", content[i:end])
+				}
+			}
+			keys = append(keys, key)
+			i = end
+		default:
+			start := i
+			for i < len(content) && (isIdentifierByte(content[i]) || content[i] == '-') {
+				i++
+			}
+			if i == start {
+				return nil, i, fmt.Errorf("failed to parse TOML: unexpected %q, expected a key", content[i])
+			}
+			keys = append(keys, string(content[start:i]))
+		}
+
+		for i < len(content) && (content[i] == ' ' || content[i] == '\t') {
+			i++
+		}
+		if i >= len(content) || content[i] != '.' {
+			return keys, i, nil
+		}
+		i++
+	}
+}
+
+// tomlValueEnd returns the offset just after the value starting at i
+func tomlValueEnd(content []byte, i int) (int, error) {
+	switch {
+	case bytes.HasPrefix(content[i:], []byte(`"""`)), bytes.HasPrefix(content[i:], []byte(`'''`)):
+		delimiter := content[i : i+3]
+		for j := i + 3; j < len(content); j++ {
+			if content[j] == '\\' && delimiter[0] == '"' {
+				j++
+				continue
+			}
+			if bytes.HasPrefix(content[j:], delimiter) {
+				// Up to two quotes may end the string before the delimiter
+				end := j + 3
+				for extra := 0; extra < 2 && end < len(content) && content[end] == delimiter[0]; extra++ {
+					end++
+				}
+				return end, nil
+			}
+		}
+		return 0, fmt.Errorf("failed to parse TOML: unterminated multi-line string at offset %d", i)
+
+	case content[i] == '"' || content[i] == '\'':
+		for j := i + 1; j < len(content) && content[j] != '\n'; j++ {
+			if content[j] == '\\' && content[i] == '"' {
+				j++
+				continue
+			}
+			if content[j] == content[i] {
+				return j + 1, nil
+			}
+		}
+		return 0, fmt.Errorf("failed to parse TOML: unterminated string at offset %d", i)
+
+	case content[i] == '[' || content[i] == '{':
+		depth := 0
+		for j := i; j < len(content); {
+			switch content[j] {
+			case '[', '{':
+				depth++
+				j++
+			case ']', '}':
+				depth--
+				j++
+				if depth == 0 {
+					return j, nil
+				}
+			case '"', '\'':
+				end, err := tomlValueEnd(content, j)
+				if err != nil {
+					return 0, err
+				}
+				j = end
+			case '#':
+				j = lineEnd(content, j)
+			default:
+				j++
+			}
+		}
+		return 0, fmt.Errorf("failed to parse TOML: unterminated array or table at offset %d", i)
+
+	default:
+		j := i
+		for j < len(content) && !bytes.ContainsRune([]byte(" \t\r\n,]}#"), rune(content[j])) {
+			j++
+		}
+		return j, nil
+	}
+}
+
+// tomlKeyText writes key parts as a dotted key, quoting parts that are not bare words
+func tomlKeyText(keys []string) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key
+		bare := key != ""
+		for j := 0; j < len(key); j++ {
+			if !isIdentifierByte(key[j]) && key[j] != '-' {
+				bare = false
+			}
+		}
+		if !bare {
+			parts[i] = quoteString(key)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// tomlInline writes a value on one line, with tables as inline tables
+func tomlInline(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return quoteString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			text, err := tomlInline(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return "{}", nil
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fields := make([]string, len(keys))
+		for i, key := range keys {
+			text, err := tomlInline(v[key])
+			if err != nil {
+				return "", err
+			}
+			fields[i] = tomlKeyText([]string{key}) + " = " + text
+		}
+		return "{ " + strings.Join(fields, ", ") + " }", nil
+	default:
+		return "", fmt.Errorf("failed to write TOML: %v cannot be written", value)
+	}
+}
+
+// samePath reports whether two key paths are equal
+func samePath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// setTOML replaces the value of the key at path with a string
+func setTOML(content []byte, path []string, value string) ([]byte, error) {
+	entries, _, err := scanTOML(content)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.inArray && samePath(entry.path, path) {
+			return splice(content, entry.valueStart, entry.valueEnd, quoteString(value)), nil
+		}
+	}
+	return nil, notFoundError(path)
+}
+
+// removeTOML removes the key at path, or the table at path with everything up to the next header
+func removeTOML(content []byte, path []string) ([]byte, error) {
+	entries, tables, err := scanTOML(content)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.inArray && samePath(entry.path, path) {
+			start, end := wholeLines(content, entry.start, entry.valueEnd, "#")
+			return splice(content, start, end, ""), nil
+		}
+	}
+
+	for i, table := range tables {
+		if !table.array && samePath(table.path, path) {
+			end := len(content)
+			if i+1 < len(tables) {
+				end = lineStart(content, tables[i+1].start)
+			}
+			return splice(content, lineStart(content, table.start), end, ""), nil
+		}
+	}
+	return nil, notFoundError(path)
+}
+
+// addTOML adds a key at path after the last key of its table, or at the end of the file
+// under a new table header when the table has no keys or header yet
+func addTOML(content []byte, path []string, value interface{}) ([]byte, error) {
+	entries, tables, err := scanTOML(content)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := path[:len(path)-1]
+	for _, entry := range entries {
+		if entry.inArray {
+			continue
+		}
+		if samePath(entry.path, path) {
+			return nil, fmt.Errorf("%s already exists", strings.Join(path, "."))
+		}
+		if samePath(entry.path, parent) {
+			return nil, fmt.Errorf("%s is not a table; add %s by hand", strings.Join(parent, "."), path[len(path)-1])
+		}
+	}
+
+	text, err := tomlInline(value)
+	if err != nil {
+		return nil, err
+	}
+	newline := lineBreak(content)
+
+	// insertLine adds a key line after the line ending at offset
+	insertLine := func(offset int, keys []string) []byte {
+		if offset > 0 && offset <= len(content) && content[offset-1] == '\r' {
+			offset--
+		}
+		return splice(content, offset, offset, newline+tomlKeyText(keys)+" = "+text)
+	}
+
+	// The table's own header, followed by its keys
+	for _, table := range tables {
+		if table.array || !samePath(table.path, parent) || len(parent) == 0 {
+			continue
+		}
+		insertAt := table.end
+		for _, entry := range entries {
+			if !entry.inArray && entry.start > table.start && samePath(entry.table, parent) {
+				insertAt = lineEnd(content, entry.valueEnd)
+			}
+		}
+		return insertLine(insertAt, path[len(path)-1:]), nil
+	}
+
+	// Keys of the table written as dotted keys in another table, or top-level keys
+	last := -1
+	for i, entry := range entries {
+		inParent := len(entry.path) > len(parent) && samePath(entry.path[:len(parent)], parent) && len(entry.table) <= len(parent)
+		if !entry.inArray && inParent {
+			last = i
+		}
+	}
+	if last >= 0 {
+		entry := entries[last]
+		return insertLine(lineEnd(content, entry.valueEnd), path[len(entry.table):]), nil
+	}
+	if len(parent) == 0 {
+		line := tomlKeyText(path) + " = " + text + newline
+		if len(tables) > 0 {
+			start := lineStart(content, tables[0].start)
+			return splice(content, start, start, line+newline), nil
+		}
+		return splice(content, 0, 0, line), nil
+	}
+
+	var buf bytes.Buffer
+	buf.Write(content)
+	if buf.Len() > 0 {
+		if !bytes.HasSuffix(content, []byte("\n")) {
+			buf.WriteString(newline)
+		}
+		buf.WriteString(newline)
+	}
+	buf.WriteString("[" + tomlKeyText(parent) + "]" + newline)
+	buf.WriteString(tomlKeyText(path[len(path)-1:]) + " = " + text + newline)
+	return buf.Bytes(), nil
+}
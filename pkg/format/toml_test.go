@@ -0,0 +1,105 @@
+package format
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode_TOML(t *testing.T) {
+	content := `
+title = "service"
+started = 1979-05-27T07:32:00-08:00
+
+[database]
+host = "localhost"
+port = 5432
+ssl = true
+ratio = 0.5
+backup_day = 2024-01-02
+window = 07:30:00
+since = 2024-01-02T10:00:00
+
+[database.pool]
+size = 10
+
+[[servers]]
+name = "web1"
+
+[[servers]]
+name = "web2"
+tags = ["a", "b"]
+`
+
+	data, err := Decode([]byte(content), TOML)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"title":   "service",
+		"started": "1979-05-27T07:32:00-08:00",
+		"database": map[string]interface{}{
+			"host":       "localhost",
+			"port":       int64(5432),
+			"ssl":        true,
+			"ratio":      0.5,
+			"backup_day": "2024-01-02",
+			"window":     "07:30:00",
+			"since":      "2024-01-02T10:00:00",
+			"pool":       map[string]interface{}{"size": int64(10)},
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"name": "web1"},
+			map[string]interface{}{"name": "web2", "tags": []interface{}{"a", "b"}},
+		},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("data = %#v, expected %#v", data, expected)
+	}
+}
+
+func TestDecode_TOMLErrors(t *testing.T) {
+	_, err := Decode([]byte("a = 1\na = 2\n"), TOML)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Decode() error = %v, expected a duplicate key error on line 2", err)
+	}
+}
+
+func TestEncode_TOML(t *testing.T) {
+	data := map[string]interface{}{
+		"title": "service",
+		"database": map[string]interface{}{
+			"host":       "localhost",
+			"backup_day": "2024-01-02",
+			"password":   nil,
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"name": "web1"},
+			map[string]interface{}{"name": "web2"},
+		},
+	}
+
+	content, err := Encode(data, TOML)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	for _, expected := range []string{"[database]", "backup_day = 2024-01-02\n", "[[servers]]"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Encode() = %s, expected it to contain %q", content, expected)
+		}
+	}
+
+	decoded, err := Decode(content, TOML)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	database := decoded.(map[string]interface{})["database"].(map[string]interface{})
+	if database["backup_day"] != "2024-01-02" || database["host"] != "localhost" {
+		t.Errorf("round trip database = %#v", database)
+	}
+	if _, exists := database["password"]; exists {
+		t.Error("expected null password to be left out")
+	}
+}
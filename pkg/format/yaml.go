@@ -0,0 +1,575 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML parses a single YAML document. Aliases and merge keys are expanded, and
+// scalars keep the type their tag gives them, so "5432" stays a string while 5432 is a
+// number. Timestamps are kept as written rather than reformatted.
+func decodeYAML(content []byte) (interface{}, Positions, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+
+	var document yaml.Node
+	if err := decoder.Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("failed to parse YAML: empty document")
+		}
+		return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		return nil, nil, fmt.Errorf("failed to parse YAML: multiple documents are not supported (second document at line %d)", next.Line)
+	}
+
+	positions := make(Positions)
+	expander := &yamlExpander{expanding: make(map[*yaml.Node]bool)}
+	value, err := expander.value(&document, "", positions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	if duplicates := yamlDuplicates(&document, "", make(map[*yaml.Node]bool)); len(duplicates) > 0 {
+		return nil, nil, &DuplicateKeysError{Duplicates: duplicates}
+	}
+	return value, positions, nil
+}
+
+// maxYAMLAliasNodes caps how many nodes aliases may expand into, so a small document cannot
+// fan out into an unbounded value
+const maxYAMLAliasNodes = 100000
+
+// yamlExpander converts YAML nodes into plain maps, slices and scalars, expanding aliases
+type yamlExpander struct {
+	// expanding holds the anchored nodes whose aliases are being expanded, to catch cycles
+	expanding map[*yaml.Node]bool
+	// aliasNodes counts the nodes produced through aliases
+	aliasNodes int
+}
+
+// value converts a YAML node and records where the node at path starts
+func (e *yamlExpander) value(node *yaml.Node, path string, positions Positions) (interface{}, error) {
+	if len(e.expanding) > 0 {
+		e.aliasNodes++
+		if e.aliasNodes > maxYAMLAliasNodes {
+			return nil, fmt.Errorf("line %d: aliases expand to more than %d nodes", node.Line, maxYAMLAliasNodes)
+		}
+	}
+	if path != "" {
+		positions[path] = Position{Line: node.Line, Column: node.Column}
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return e.value(node.Content[0], path, positions)
+
+	case yaml.AliasNode:
+		// Positions inside an alias point at the anchored node it repeats
+		var value interface{}
+		err := e.expand(node, func(anchored *yaml.Node) error {
+			var err error
+			value, err = e.value(anchored, path, positions)
+			return err
+		})
+		return value, err
+
+	case yaml.MappingNode:
+		result := make(map[string]interface{})
+		if err := e.mapping(node, path, positions, result, make(map[string]bool)); err != nil {
+			return nil, err
+		}
+		return result, nil
+
+	case yaml.SequenceNode:
+		result := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := e.value(item, joinPath(path, strconv.Itoa(len(result))), positions)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		return result, nil
+
+	case yaml.ScalarNode:
+		return yamlScalar(node)
+	}
+
+	return nil, fmt.Errorf("line %d: unsupported YAML node", node.Line)
+}
+
+// expand calls visit with the node an alias refers to, failing when the alias is reached
+// again while its anchored node is being expanded. Other nodes are visited as they are.
+func (e *yamlExpander) expand(node *yaml.Node, visit func(*yaml.Node) error) error {
+	if node.Kind != yaml.AliasNode {
+		return visit(node)
+	}
+	if e.expanding[node.Alias] {
+		return fmt.Errorf("line %d: alias *%s refers to itself", node.Line, node.Value)
+	}
+
+	e.expanding[node.Alias] = true
+	defer delete(e.expanding, node.Alias)
+	return visit(node.Alias)
+}
+
+// mapping adds the entries of a mapping node to result. Keys in explicit set are the mapping's own keys, which merged mappings (<<) never override.
+func (e *yamlExpander) mapping(node *yaml.Node, path string, positions Positions, result map[string]interface{}, explicit map[string]bool) error {
+	var merges []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+
+		if keyNode.Kind == yaml.ScalarNode && keyNode.ShortTag() == "!!merge" {
+			merges = append(merges, valueNode)
+			continue
+		}
+		if keyNode.Kind != yaml.ScalarNode {
+			return fmt.Errorf("line %d: mapping keys must be scalars", keyNode.Line)
+		}
+
+		value, err := e.value(valueNode, joinPath(path, keyNode.Value), positions)
+		if err != nil {
+			return err
+		}
+		result[keyNode.Value] = value
+		explicit[keyNode.Value] = true
+	}
+
+	// Merged mappings only fill in keys the mapping does not set itself; in a list of
+	// merges the earlier ones win
+	for _, merge := range merges {
+		err := e.expand(merge, func(merge *yaml.Node) error {
+			sources := []*yaml.Node{merge}
+			if merge.Kind == yaml.SequenceNode {
+				sources = merge.Content
+			}
+
+			for _, source := range sources {
+				err := e.expand(source, func(source *yaml.Node) error {
+					if source.Kind != yaml.MappingNode {
+						return fmt.Errorf("line %d: merge (<<) needs a mapping or a list of mappings", source.Line)
+					}
+
+					merged := make(map[string]interface{})
+					mergedPositions := make(Positions)
+					if err := e.mapping(source, path, mergedPositions, merged, make(map[string]bool)); err != nil {
+						return err
+					}
+					for key, value := range merged {
+						if !explicit[key] {
+							result[key] = value
+							explicit[key] = true
+						}
+					}
+					for mergedPath, pos := range mergedPositions {
+						if _, exists := positions[mergedPath]; !exists {
+							positions[mergedPath] = pos
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// yamlDuplicates finds keys defined twice in the same mapping. Keys brought in by merge
+// keys (<<) are defaults, not duplicates. Anchored nodes are checked once.
+func yamlDuplicates(node *yaml.Node, path string, seen map[*yaml.Node]bool) []DuplicateKey {
+	if seen[node] {
+		return nil
+	}
+	seen[node] = true
+
+	var duplicates []DuplicateKey
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			duplicates = append(duplicates, yamlDuplicates(child, path, seen)...)
+		}
+
+	case yaml.MappingNode:
+		keyPositions := make(map[string]Position)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyNode, valueNode := node.Content[i], node.Content[i+1]
+			if keyNode.ShortTag() == "!!merge" {
+				continue
+			}
+
+			keyPosition := Position{Line: keyNode.Line, Column: keyNode.Column}
+			if first, exists := keyPositions[keyNode.Value]; exists {
+				duplicates = append(duplicates, DuplicateKey{Path: joinPath(path, keyNode.Value), First: first, Second: keyPosition})
+			} else {
+				keyPositions[keyNode.Value] = keyPosition
+			}
+			duplicates = append(duplicates, yamlDuplicates(valueNode, joinPath(path, keyNode.Value), seen)...)
+		}
+
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			duplicates = append(duplicates, yamlDuplicates(item, joinPath(path, strconv.Itoa(i)), seen)...)
+		}
+	}
+
+	return duplicates
+}
+
+// yamlScalar converts a scalar node to a string, number, boolean or nil
+func yamlScalar(node *yaml.Node) (interface{}, error) {
+	switch node.ShortTag() {
+	case "!!int", "!!float", "!!bool", "!!null":
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		return value, nil
+	default:
+		// Strings, timestamps and binary values are kept exactly as written
+		return node.Value, nil
+	}
+}
+
+// encodeYAML writes nested configuration as a YAML document
+func encodeYAML(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(data); err != nil {
+		return nil, fmt.Errorf("failed to write YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlLayout parses content into its node tree for editing. An empty document has no root.
+func yamlLayout(content []byte) (*yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	return document.Content[0], nil
+}
+
+// yamlFind returns the node at path, the collection holding it and, inside a mapping, its key
+func yamlFind(root *yaml.Node, path []string) (parent, key, value *yaml.Node) {
+	value = root
+	for _, part := range path {
+		if value == nil {
+			return nil, nil, nil
+		}
+		parent, key = value, nil
+
+		switch value.Kind {
+		case yaml.MappingNode:
+			var found *yaml.Node
+			for i := 0; i+1 < len(parent.Content); i += 2 {
+				if parent.Content[i].Value == part && parent.Content[i].ShortTag() != "!!merge" {
+					key, found = parent.Content[i], parent.Content[i+1]
+				}
+			}
+			value = found
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(parent.Content) {
+				return nil, nil, nil
+			}
+			value = parent.Content[index]
+		default:
+			return nil, nil, nil
+		}
+	}
+	return parent, key, value
+}
+
+// yamlOffset converts the line and column of a node into a byte offset
+func yamlOffset(content []byte, line, column int) int {
+	offset := 0
+	for i := 1; i < line && offset < len(content); i++ {
+		offset = lineEnd(content, offset) + 1
+	}
+	for i := 1; i < column && offset < len(content); i++ {
+		_, size := utf8.DecodeRune(content[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// yamlScalarEnd returns where a single-line scalar starting at offset ends
+func yamlScalarEnd(content []byte, start int, node *yaml.Node) (int, error) {
+	end := lineEnd(content, start)
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		for i := start + 1; i < end; i++ {
+			switch content[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, nil
+			}
+		}
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		for i := start + 1; i < end; i++ {
+			if content[i] == '\'' {
+				if i+1 < end && content[i+1] == '\'' {
+					i++
+					continue
+				}
+				return i + 1, nil
+			}
+		}
+	case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0:
+		text := string(content[start:end])
+		for i := 1; i < len(text); i++ {
+			if text[i] == '#' && (text[i-1] == ' ' || text[i-1] == '\t') {
+				text = text[:i]
+				break
+			}
+		}
+		text = strings.TrimRight(text, " \t\r")
+		if text == node.Value {
+			return start + len(text), nil
+		}
+	}
+	return 0, fmt.Errorf("line %d: the value does not fit on one line; edit it by hand", node.Line)
+}
+
+// yamlBlockEnd returns where the block of a mapping key starting at offset ends: after the
+// last line indented deeper than the key, or a list item at the key's own indentation
+func yamlBlockEnd(content []byte, start, indent int) int {
+	end := lineEnd(content, start)
+	if end < len(content) {
+		end++
+	}
+
+	for next := end; next < len(content); {
+		lineEndOffset := lineEnd(content, next)
+		line := string(content[next:lineEndOffset])
+		trimmed := strings.TrimSpace(line)
+		if lineEndOffset < len(content) {
+			lineEndOffset++
+		}
+
+		if trimmed != "" {
+			lineIndentation := len(line) - len(strings.TrimLeft(line, " "))
+			listItem := lineIndentation == indent && (trimmed == "-" || strings.HasPrefix(trimmed, "- "))
+			if lineIndentation <= indent && !listItem {
+				break
+			}
+			end = lineEndOffset
+		}
+		next = lineEndOffset
+	}
+	return end
+}
+
+// yamlIndentUnit returns the indentation the file uses for one level of nesting
+func yamlIndentUnit(root *yaml.Node) int {
+	if root != nil && root.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+			if value.Kind == yaml.MappingNode && value.Style&yaml.FlowStyle == 0 && len(value.Content) > 0 {
+				if unit := value.Content[0].Column - key.Column; unit > 0 {
+					return unit
+				}
+			}
+		}
+	}
+	return 2
+}
+
+// yamlBlock writes a mapping as YAML lines indented by indent spaces
+func yamlBlock(value map[string]interface{}, indent, unit int, newline string) (string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(unit)
+	if err := encoder.Encode(value); err != nil {
+		return "", fmt.Errorf("failed to write YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to write YAML: %w", err)
+	}
+
+	var text strings.Builder
+	prefix := strings.Repeat(" ", indent)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if line != "" {
+			text.WriteString(prefix)
+		}
+		text.WriteString(line + newline)
+	}
+	return text.String(), nil
+}
+
+// yamlString writes a string as a YAML scalar on one line
+func yamlString(value string) string {
+	out, err := yaml.Marshal(value)
+	text := strings.TrimSuffix(string(out), "\n")
+	if err != nil || strings.Contains(text, "\n") {
+		return quoteString(value)
+	}
+	return text
+}
+
+// setYAML replaces the scalar at path with a string
+func setYAML(content []byte, path []string, value string) ([]byte, error) {
+	root, err := yamlLayout(content)
+	if err != nil {
+		return nil, err
+	}
+
+	_, _, node := yamlFind(root, path)
+	if node == nil || len(path) == 0 {
+		return nil, notFoundError(path)
+	}
+	if node.Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("%s is not a single value", strings.Join(path, "."))
+	}
+
+	start := yamlOffset(content, node.Line, node.Column)
+	if start < len(content) && (content[start] == '!' || content[start] == '&') {
+		return nil, fmt.Errorf("line %d: the value has a tag or anchor; edit it by hand", node.Line)
+	}
+	end, err := yamlScalarEnd(content, start, node)
+	if err != nil {
+		return nil, err
+	}
+	return splice(content, start, end, yamlString(value)), nil
+}
+
+// removeYAML removes the key at path and the lines of its value from a block mapping
+func removeYAML(content []byte, path []string) ([]byte, error) {
+	root, err := yamlLayout(content)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, key, _ := yamlFind(root, path)
+	if key == nil {
+		return nil, notFoundError(path)
+	}
+	start := yamlOffset(content, key.Line, key.Column)
+	if parent.Style&yaml.FlowStyle != 0 || !firstOnLine(content, start) {
+		return nil, fmt.Errorf("line %d: %s is not on a line of its own; remove it by hand", key.Line, strings.Join(path, "."))
+	}
+
+	return splice(content, lineStart(content, start), yamlBlockEnd(content, start, key.Column-1), ""), nil
+}
+
+// addYAML adds a key at path after the last key of its mapping, indented like it
+func addYAML(content []byte, path []string, value interface{}) ([]byte, error) {
+	root, err := yamlLayout(content)
+	if err != nil {
+		return nil, err
+	}
+
+	newline := lineBreak(content)
+	unit := yamlIndentUnit(root)
+	name := path[len(path)-1]
+	entry := map[string]interface{}{name: value}
+
+	if root == nil {
+		// An empty file gets the whole path
+		for i := len(path) - 2; i >= 0; i-- {
+			entry = map[string]interface{}{path[i]: entry}
+		}
+		text, err := yamlBlock(entry, 0, unit, newline)
+		if err != nil {
+			return nil, err
+		}
+		return append(append([]byte{}, content...), text...), nil
+	}
+
+	_, parentKey, parent := yamlFind(root, path[:len(path)-1])
+	if parent == nil {
+		return addYAML(content, path[:len(path)-1], entry)
+	}
+	if _, _, existing := yamlFind(root, path); existing != nil {
+		return nil, fmt.Errorf("%s already exists", strings.Join(path, "."))
+	}
+
+	if parent.Kind == yaml.MappingNode && len(parent.Content) > 0 {
+		if parent.Style&yaml.FlowStyle != 0 {
+			return nil, fmt.Errorf("line %d: %s is a flow mapping; add %s by hand", parent.Line, strings.Join(path[:len(path)-1], "."), name)
+		}
+
+		first, last := parent.Content[0], parent.Content[len(parent.Content)-2]
+		text, err := yamlBlock(entry, first.Column-1, unit, newline)
+		if err != nil {
+			return nil, err
+		}
+
+		insertAt := yamlBlockEnd(content, yamlOffset(content, last.Line, last.Column), last.Column-1)
+		if insertAt == len(content) && insertAt > 0 && content[insertAt-1] != '\n' {
+			text = newline + text
+		}
+		return splice(content, insertAt, insertAt, text), nil
+	}
+
+	// An empty section, written as "key:", "key: ~" or "key: {}", becomes a block mapping
+	empty := parent.Kind == yaml.ScalarNode && parent.ShortTag() == "!!null" ||
+		parent.Kind == yaml.MappingNode && len(parent.Content) == 0
+	if parentKey == nil || !empty {
+		return nil, fmt.Errorf("%s is not a mapping", strings.Join(path[:len(path)-1], "."))
+	}
+
+	keyStart := yamlOffset(content, parentKey.Line, parentKey.Column)
+	keyLineEnd := lineEnd(content, keyStart)
+	colon := -1
+	if parentKey.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		if keyEnd, err := yamlScalarEnd(content, keyStart, parentKey); err == nil {
+			colon = bytes.IndexByte(content[keyEnd:keyLineEnd], ':') + keyEnd
+		}
+	} else if i := bytes.IndexByte(content[keyStart:keyLineEnd], ':'); i >= 0 {
+		colon = keyStart + i
+	}
+	if colon < 0 {
+		return nil, fmt.Errorf("line %d: add %s under %s by hand", parentKey.Line, name, parentKey.Value)
+	}
+
+	rest := strings.TrimSpace(string(content[colon+1 : keyLineEnd]))
+	comment := ""
+	if i := strings.Index(rest, "#"); i >= 0 {
+		rest, comment = strings.TrimSpace(rest[:i]), " "+rest[i:]
+	}
+	if rest != "" && rest != "~" && rest != "null" && rest != "{}" {
+		return nil, fmt.Errorf("line %d: add %s under %s by hand", parentKey.Line, name, parentKey.Value)
+	}
+
+	text, err := yamlBlock(entry, parentKey.Column-1+unit, unit, newline)
+	if err != nil {
+		return nil, err
+	}
+	if keyLineEnd < len(content) {
+		keyLineEnd++
+	}
+	return splice(content, colon+1, keyLineEnd, comment+newline+text), nil
+}
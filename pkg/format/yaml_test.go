@@ -0,0 +1,118 @@
+package format
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode_YAML(t *testing.T) {
+	content := `
+defaults: &defaults
+  timeout: 30
+  retries: 3
+database:
+  <<: *defaults
+  host: localhost
+  port: 5432
+  version: "5432"
+  ssl: true
+  password: null
+  since: 2024-01-02
+  timeout: 60
+servers:
+  - web1
+  - 0755
+`
+
+	data, err := Decode([]byte(content), YAML)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+
+	database := data.(map[string]interface{})["database"].(map[string]interface{})
+	expected := map[string]interface{}{
+		"timeout":  60,
+		"retries":  3,
+		"host":     "localhost",
+		"port":     5432,
+		"version":  "5432",
+		"ssl":      true,
+		"password": nil,
+		"since":    "2024-01-02",
+	}
+	if !reflect.DeepEqual(database, expected) {
+		t.Errorf("database = %#v, expected %#v", database, expected)
+	}
+
+	servers := data.(map[string]interface{})["servers"].([]interface{})
+	if servers[0] != "web1" || servers[1] != 493 {
+		t.Errorf("servers = %#v", servers)
+	}
+}
+
+func TestDecode_YAMLErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"multiple documents", "a: 1\n---\nb: 2\n", "multiple documents"},
+		{"empty", "", "empty document"},
+		{"invalid", "a: [1, 2\n", "failed to parse YAML"},
+		{"alias cycle", "a: &x [*x]\n", "alias *x refers to itself"},
+		{"merge cycle", "a: &x\n  b: 1\n  <<: *x\n", "alias *x refers to itself"},
+		{"alias fan-out", yamlLaughs(9), "aliases expand to more than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode([]byte(tt.content), YAML)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Decode() error = %v, expected %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+// yamlLaughs builds a document whose aliases expand to 10^levels nodes
+func yamlLaughs(levels int) string {
+	var builder strings.Builder
+	builder.WriteString("l0: &l0 [lol]\n")
+	for level := 1; level <= levels; level++ {
+		fmt.Fprintf(&builder, "l%d: &l%d [", level, level)
+		for i := 0; i < 10; i++ {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+			fmt.Fprintf(&builder, "*l%d", level-1)
+		}
+		builder.WriteString("]\n")
+	}
+	return builder.String()
+}
+
+func TestEncode_YAML(t *testing.T) {
+	data := map[string]interface{}{
+		"database": map[string]interface{}{
+			"host": "localhost",
+			"port": "5432",
+			"ssl":  "true",
+		},
+	}
+
+	content, err := Encode(data, YAML)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+
+	// Strings that look like numbers or booleans must stay strings when read back
+	decoded, err := Decode(content, YAML)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, map[string]interface{}(data)) {
+		t.Errorf("round trip = %#v\n%s", decoded, content)
+	}
+}
@@ -13,6 +13,8 @@ import (
 // Flattener handles JSON flattening and unflattening operations
 type Flattener struct{
 	validator *validator.Validator
+	// featureManagement reads top-level feature management sections as feature flags
+	featureManagement bool
 }
 
 // NewFlattener creates a new JSON flattener instance
@@ -22,17 +24,127 @@ func NewFlattener() *Flattener {
 	}
 }
 
-// Flatten converts nested JSON into flat key/value pairs using dot notation
+// NewFlattenerWithValidator creates a new JSON flattener that validates with the given validator
+func NewFlattenerWithValidator(v *validator.Validator) *Flattener {
+	return &Flattener{
+		validator: v,
+	}
+}
+
+// SetFeatureManagement controls whether top-level feature management sections, as written by
+// az appconfig kv export, are converted into feature flag settings. By default they are
+// flattened like any other section.
+func (f *Flattener) SetFeatureManagement(enabled bool) {
+	f.featureManagement = enabled
+}
+
+// Flatten converts nested JSON into flat key/value pairs using dot notation.
+// A top-level featureFlags section is converted into feature flag settings, and so are
+// feature management sections when SetFeatureManagement is enabled.
 func (f *Flattener) Flatten(data interface{}) (map[string]string, error) {
 	result := make(map[string]string)
+
+	if root, ok := data.(map[string]interface{}); ok {
+		rest := make(map[string]interface{}, len(root))
+		for key, value := range root {
+			rest[key] = value
+		}
+
+		if section, ok := root[validator.FeatureFlagsSection]; ok {
+			if err := f.flattenFeatureFlags(section, result); err != nil {
+				return nil, err
+			}
+			delete(rest, validator.FeatureFlagsSection)
+		}
+
+		for _, name := range validator.FeatureManagementSections {
+			section, ok := root[name]
+			if !ok || !f.featureManagement {
+				continue
+			}
+			if err := f.flattenFeatureManagement(name, section, result); err != nil {
+				return nil, err
+			}
+			delete(rest, name)
+		}
+
+		data = rest
+	}
+
 	err := f.flattenRecursive(data, "", result)
 	return result, err
 }
 
+// flattenFeatureManagement converts a feature management section into feature flag settings
+func (f *Flattener) flattenFeatureManagement(name string, section interface{}, result map[string]string) error {
+	flags, err := validator.FeatureFlagsFromFeatureManagement(section)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	for _, ff := range flags {
+		key := validator.FeatureFlagKey(ff.ID)
+		if _, exists := result[key]; exists {
+			return fmt.Errorf("feature flag %s is defined more than once (%s)", ff.ID, name)
+		}
+
+		value, err := validator.FormatFeatureFlagValue(ff)
+		if err != nil {
+			return err
+		}
+		result[key] = value
+	}
+
+	return nil
+}
+
+// FlattenFeatureFlags converts a featureFlags section, or a whole file holding one or a
+// feature management section, into feature flag settings
+func (f *Flattener) FlattenFeatureFlags(data interface{}) (map[string]string, error) {
+	if root, ok := data.(map[string]interface{}); ok && len(root) == 1 {
+		if section, ok := root[validator.FeatureFlagsSection]; ok {
+			data = section
+		}
+		for _, name := range validator.FeatureManagementSections {
+			if section, ok := root[name]; ok {
+				result := make(map[string]string)
+				err := f.flattenFeatureManagement(name, section, result)
+				return result, err
+			}
+		}
+	}
+
+	result := make(map[string]string)
+	err := f.flattenFeatureFlags(data, result)
+	return result, err
+}
+
+// flattenFeatureFlags converts featureFlags section entries into feature flag settings
+func (f *Flattener) flattenFeatureFlags(section interface{}, result map[string]string) error {
+	flags, ok := section.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be an object mapping flag names to flags", validator.FeatureFlagsSection)
+	}
+
+	for name, entry := range flags {
+		ff, err := validator.FeatureFlagFromLocal(name, entry)
+		if err != nil {
+			return err
+		}
+
+		value, err := validator.FormatFeatureFlagValue(ff)
+		if err != nil {
+			return err
+		}
+		result[validator.FeatureFlagKey(name)] = value
+	}
+
+	return nil
+}
+
 // FlattenAndValidate converts nested JSON into flat key/value pairs with validation
 func (f *Flattener) FlattenAndValidate(data interface{}) (map[string]string, []validator.ValidationError, error) {
-	result := make(map[string]string)
-	err := f.flattenRecursive(data, "", result)
+	result, err := f.Flatten(data)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -47,9 +159,14 @@ func (f *Flattener) ValidateConfiguration(config map[string]string) ([]validator
 	return f.validator.ValidateConfiguration(config)
 }
 
-// flattenRecursive recursively flattens nested structures
+// flattenRecursive recursively flattens nested structures. Null values, empty objects and
+// empty arrays become empty strings, so a key that is still in the file is never taken for
+// a deletion.
 func (f *Flattener) flattenRecursive(data interface{}, prefix string, result map[string]string) error {
 	if data == nil {
+		if prefix != "" {
+			result[prefix] = ""
+		}
 		return nil
 	}
 
@@ -77,6 +194,10 @@ func (f *Flattener) flattenRecursive(data interface{}, prefix string, result map
 
 // flattenMap flattens a map structure
 func (f *Flattener) flattenMap(v reflect.Value, prefix string, result map[string]string) error {
+	if v.Len() == 0 && prefix != "" {
+		result[prefix] = ""
+		return nil
+	}
 	for _, key := range v.MapKeys() {
 		keyStr := f.formatKey(key)
 		newPrefix := f.joinKeys(prefix, keyStr)
@@ -96,6 +217,10 @@ func (f *Flattener) flattenMap(v reflect.Value, prefix string, result map[string
 
 // flattenSlice flattens an array/slice structure
 func (f *Flattener) flattenSlice(v reflect.Value, prefix string, result map[string]string) error {
+	if v.Len() == 0 && prefix != "" {
+		result[prefix] = ""
+		return nil
+	}
 	for i := 0; i < v.Len(); i++ {
 		newPrefix := f.joinKeys(prefix, strconv.Itoa(i))
 		value := v.Index(i)
@@ -153,6 +278,14 @@ func (f *Flattener) Unflatten(flat map[string]string) (map[string]interface{}, e
 	result := make(map[string]interface{})
 
 	for key, value := range flat {
+		// Feature flags go back into the featureFlags section
+		if validator.IsFeatureFlagSetting(key) {
+			if err := f.unflattenFeatureFlag(result, key, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		parts := strings.Split(key, ".")
 		err := f.unflattenRecursive(result, parts, value)
 		if err != nil {
@@ -163,6 +296,31 @@ func (f *Flattener) Unflatten(flat map[string]string) (map[string]interface{}, e
 	return result, nil
 }
 
+// unflattenFeatureFlag adds a feature flag setting to the featureFlags section
+func (f *Flattener) unflattenFeatureFlag(result map[string]interface{}, key, value string) error {
+	ff, err := validator.ParseFeatureFlagValue(value)
+	if err != nil {
+		return fmt.Errorf("feature flag %s: %w", key, err)
+	}
+
+	return f.AddFeatureFlag(result, ff)
+}
+
+// AddFeatureFlag adds or replaces a feature flag in the featureFlags section of nested JSON
+func (f *Flattener) AddFeatureFlag(data map[string]interface{}, ff *validator.FeatureFlag) error {
+	if _, exists := data[validator.FeatureFlagsSection]; !exists {
+		data[validator.FeatureFlagsSection] = make(map[string]interface{})
+	}
+
+	section, ok := data[validator.FeatureFlagsSection].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("type conflict at key %s", validator.FeatureFlagsSection)
+	}
+
+	section[ff.ID] = validator.FeatureFlagToLocal(ff)
+	return nil
+}
+
 // unflattenRecursive recursively builds nested structure from flat keys
 func (f *Flattener) unflattenRecursive(current map[string]interface{}, parts []string, value string) error {
 	if len(parts) == 0 {
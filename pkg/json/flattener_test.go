@@ -1,6 +1,7 @@
 package json
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 )
@@ -37,8 +38,8 @@ func TestFlattener_Flatten(t *testing.T) {
 				},
 			},
 			expected: map[string]string{
-				"app.name":    "test",
-				"app.version": "1.0.0",
+				"app.name":      "test",
+				"app.version":   "1.0.0",
 				"database.host": "localhost",
 				"database.port": "5432",
 			},
@@ -67,6 +68,34 @@ func TestFlattener_Flatten(t *testing.T) {
 				"rate":    "3.14",
 			},
 		},
+		{
+			name: "null and empty values",
+			input: map[string]interface{}{
+				"app": map[string]interface{}{
+					"motd":    nil,
+					"title":   "",
+					"servers": []interface{}{},
+					"limits":  map[string]interface{}{},
+				},
+			},
+			expected: map[string]string{
+				"app.motd":    "",
+				"app.title":   "",
+				"app.servers": "",
+				"app.limits":  "",
+			},
+		},
+		{
+			name: "numbers as written",
+			input: map[string]interface{}{
+				"id":    json.Number("12345678901234567890"),
+				"ratio": json.Number("1.10"),
+			},
+			expected: map[string]string{
+				"id":    "12345678901234567890",
+				"ratio": "1.10",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -106,8 +135,8 @@ func TestFlattener_Unflatten(t *testing.T) {
 		{
 			name: "nested structure",
 			input: map[string]string{
-				"app.name":    "test",
-				"app.version": "1.0.0",
+				"app.name":      "test",
+				"app.version":   "1.0.0",
 				"database.host": "localhost",
 				"database.port": "5432",
 			},
@@ -161,7 +190,7 @@ func TestFlattener_FlattenAndValidate(t *testing.T) {
 					"beta_features": "false",
 				},
 				"secrets": map[string]interface{}{
-					"api_key": "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/api-key)",
+					"api_key":     "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/api-key)",
 					"db_password": "https://myvault.vault.azure.net/secrets/db-password",
 				},
 			},
@@ -225,12 +254,12 @@ func TestFlattener_ValidateConfiguration(t *testing.T) {
 	flattener := NewFlattener()
 
 	config := map[string]string{
-		"database.host":                   "localhost",
-		"feature.new_ui":                  "true",
-		"enable.beta_features":            "false",
-		"secrets.api_key":                 "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/api-key)",
-		"secrets.invalid_kv":              "@Microsoft.KeyVault(SecretUri=https://example.com/secrets/mysecret)",
-		"regular.setting":                 "some_value",
+		"database.host":        "localhost",
+		"feature.new_ui":       "true",
+		"enable.beta_features": "false",
+		"secrets.api_key":      "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/api-key)",
+		"secrets.invalid_kv":   "@Microsoft.KeyVault(SecretUri=https://example.com/secrets/mysecret)",
+		"regular.setting":      "some_value",
 	}
 
 	errors, err := flattener.ValidateConfiguration(config)
@@ -251,3 +280,144 @@ func TestFlattener_ValidateConfiguration(t *testing.T) {
 		}
 	}
 }
+
+func TestFlattener_FeatureFlags(t *testing.T) {
+	flattener := NewFlattener()
+
+	input := map[string]interface{}{
+		"app": map[string]interface{}{
+			"name": "test",
+		},
+		"featureFlags": map[string]interface{}{
+			"Beta": true,
+			"NewCheckout": map[string]interface{}{
+				"enabled":     false,
+				"description": "New checkout flow",
+			},
+		},
+	}
+
+	flat, err := flattener.Flatten(input)
+	if err != nil {
+		t.Fatalf("Flatten() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"app.name":                           "test",
+		".appconfig.featureflag/Beta":        `{"id":"Beta","description":"","enabled":true,"conditions":{"client_filters":[]}}`,
+		".appconfig.featureflag/NewCheckout": `{"id":"NewCheckout","description":"New checkout flow","enabled":false,"conditions":{"client_filters":[]}}`,
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("Flatten() = %v, expected %v", flat, expected)
+	}
+
+	// Downloading must produce the same shape again
+	structured, err := flattener.Unflatten(flat)
+	if err != nil {
+		t.Fatalf("Unflatten() error = %v", err)
+	}
+	if !reflect.DeepEqual(structured, input) {
+		t.Errorf("Unflatten() = %v, expected %v", structured, input)
+	}
+}
+
+func TestFlattener_FeatureManagement(t *testing.T) {
+	flattener := NewFlattener()
+
+	input := map[string]interface{}{
+		"app": map[string]interface{}{"name": "test"},
+		"FeatureManagement": map[string]interface{}{
+			"Beta": true,
+			"Checkout": map[string]interface{}{
+				"EnabledFor": []interface{}{map[string]interface{}{"Name": "AlwaysOn"}},
+			},
+		},
+	}
+
+	// Without opting in, feature management sections are plain settings
+	flat, err := flattener.Flatten(input)
+	if err != nil {
+		t.Fatalf("Flatten() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"app.name":               "test",
+		"FeatureManagement.Beta": "true",
+		"FeatureManagement.Checkout.EnabledFor.0.Name": "AlwaysOn",
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("Flatten() = %v, expected %v", flat, expected)
+	}
+
+	flattener.SetFeatureManagement(true)
+	flat, err = flattener.Flatten(input)
+	if err != nil {
+		t.Fatalf("Flatten() error = %v", err)
+	}
+
+	expected = map[string]string{
+		"app.name":                        "test",
+		".appconfig.featureflag/Beta":     `{"id":"Beta","description":"","enabled":true,"conditions":{"client_filters":[]}}`,
+		".appconfig.featureflag/Checkout": `{"id":"Checkout","description":"","enabled":true,"conditions":{"client_filters":[]}}`,
+	}
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("Flatten() = %v, expected %v", flat, expected)
+	}
+
+	// A flag may not be defined both in featureFlags and a feature management section
+	input["featureFlags"] = map[string]interface{}{"Beta": false}
+	if _, err := flattener.Flatten(input); err == nil {
+		t.Errorf("expected an error for a flag defined twice")
+	}
+}
+
+func TestFlattener_FlattenFeatureFlags(t *testing.T) {
+	flattener := NewFlattener()
+
+	tests := []struct {
+		name     string
+		input    interface{}
+		hasError bool
+	}{
+		{
+			name:  "bare flags map",
+			input: map[string]interface{}{"Beta": true},
+		},
+		{
+			name:  "file with featureFlags section",
+			input: map[string]interface{}{"featureFlags": map[string]interface{}{"Beta": true}},
+		},
+		{
+			name:  "file with FeatureManagement section",
+			input: map[string]interface{}{"FeatureManagement": map[string]interface{}{"Beta": true}},
+		},
+		{
+			name:     "invalid flag entry",
+			input:    map[string]interface{}{"Beta": "yes"},
+			hasError: true,
+		},
+		{
+			name:     "mismatched id",
+			input:    map[string]interface{}{"Beta": map[string]interface{}{"id": "Other", "enabled": true}},
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := flattener.FlattenFeatureFlags(tt.input)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FlattenFeatureFlags() error = %v", err)
+			}
+			if _, ok := result[".appconfig.featureflag/Beta"]; !ok || len(result) != 1 {
+				t.Errorf("FlattenFeatureFlags() = %v, expected a single Beta flag", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+// Mode controls how sensitive values are shown
+type Mode string
+
+const (
+	// ModeMask replaces sensitive values with a fixed mask
+	ModeMask Mode = "mask"
+	// ModeHash replaces sensitive values with a short keyed hash, so equal values can still be
+	// recognised within one run. The key is random per redactor, so hashes cannot be compared
+	// across runs or looked up in a dictionary.
+	ModeHash Mode = "hash"
+)
+
+// Mask is shown in place of a sensitive value in mask mode
+const Mask = "********"
+
+// SensitiveTag marks a setting as sensitive when its value is true
+const SensitiveTag = "sensitive"
+
+// Options controls which values are sensitive and how they are shown
+type Options struct {
+	Mode Mode
+	// Patterns are case-insensitive key globs, where * matches any characters, that are always sensitive
+	Patterns []string
+}
+
+// Redactor hides sensitive values in output. A value is sensitive when its key matches a
+// pattern, its setting carries the sensitive tag, or it looks like a secret to the validator.
+type Redactor struct {
+	validator *validator.Validator
+	mode      Mode
+	patterns  []*regexp.Regexp
+	hashKey   []byte
+}
+
+// NewRedactor creates a redactor that detects secrets with the given validator
+func NewRedactor(v *validator.Validator, options Options) (*Redactor, error) {
+	mode := options.Mode
+	switch mode {
+	case "":
+		mode = ModeMask
+	case ModeMask, ModeHash:
+	default:
+		return nil, fmt.Errorf("invalid redaction mode %q: expected mask or hash", options.Mode)
+	}
+
+	redactor := &Redactor{
+		validator: v,
+		mode:      mode,
+	}
+
+	if mode == ModeHash {
+		redactor.hashKey = make([]byte, 32)
+		if _, err := rand.Read(redactor.hashKey); err != nil {
+			return nil, fmt.Errorf("failed to generate redaction hash key: %w", err)
+		}
+	}
+
+	for _, pattern := range options.Patterns {
+		expr := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensitive key pattern %q: %w", pattern, err)
+		}
+		redactor.patterns = append(redactor.patterns, re)
+	}
+
+	return redactor, nil
+}
+
+// IsSensitive reports whether any of a setting's values must be hidden
+func (r *Redactor) IsSensitive(key string, tags map[string]string, values ...string) bool {
+	if strings.EqualFold(tags[SensitiveTag], "true") {
+		return true
+	}
+
+	for _, pattern := range r.patterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+
+	for _, value := range values {
+		if _, ok := r.validator.DetectSecret(key, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Value returns how a sensitive value is shown
+func (r *Redactor) Value(value string) string {
+	if value == "" {
+		return ""
+	}
+	if r.mode == ModeHash {
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(value))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil)[:6])
+	}
+	return Mask
+}
+
+// Redact returns a value as it may be shown, and whether it was hidden
+func (r *Redactor) Redact(key, value string, tags map[string]string) (string, bool) {
+	if !r.IsSensitive(key, tags, value) {
+		return value, false
+	}
+	return r.Value(value), true
+}
+
+// Text hides every occurrence of a sensitive value inside free text, such as an error message
+func (r *Redactor) Text(key, value, text string) string {
+	if value == "" || !r.IsSensitive(key, nil, value) {
+		return text
+	}
+	return strings.ReplaceAll(text, value, r.Value(value))
+}
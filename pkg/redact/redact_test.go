@@ -0,0 +1,96 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	redactor, err := NewRedactor(validator.NewValidator(), Options{Patterns: []string{"internal.*"}})
+	if err != nil {
+		t.Fatalf("NewRedactor failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		tags     map[string]string
+		expected string
+	}{
+		{"plain value", "app.name", "demo", nil, "demo"},
+		{"secret key name", "database.password", "hunter2", nil, Mask},
+		{"secret shape", "storage.connection", "AccountName=acct;AccountKey=bm90LWEta2V5", nil, Mask},
+		{"key pattern", "Internal.Url", "https://internal.example.com", nil, Mask},
+		{"sensitive tag", "app.motd", "hello", map[string]string{SensitiveTag: "true"}, Mask},
+		{"sensitive tag off", "app.motd", "hello", map[string]string{SensitiveTag: "false"}, "hello"},
+		{"key vault reference", "database.password", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db)", nil, "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, redacted := redactor.Redact(tt.key, tt.value, tt.tags)
+			if result != tt.expected {
+				t.Errorf("Redact() = %q, expected %q", result, tt.expected)
+			}
+			if redacted != (tt.expected != tt.value) {
+				t.Errorf("Redact() redacted = %v", redacted)
+			}
+		})
+	}
+}
+
+func TestRedactor_HashMode(t *testing.T) {
+	redactor, err := NewRedactor(validator.NewValidator(), Options{Mode: ModeHash})
+	if err != nil {
+		t.Fatalf("NewRedactor failed: %v", err)
+	}
+
+	first := redactor.Value("hunter2")
+	if !strings.HasPrefix(first, "hmac:") || strings.Contains(first, "hunter2") {
+		t.Errorf("expected a hash, got %q", first)
+	}
+	if redactor.Value("hunter2") != first {
+		t.Errorf("expected equal values to hash alike")
+	}
+	if redactor.Value("hunter3") == first {
+		t.Errorf("expected different values to hash differently")
+	}
+	if redactor.Value("") != "" {
+		t.Errorf("expected empty values to stay empty")
+	}
+
+	// Each redactor uses its own key, so hashes from another run do not match
+	other, err := NewRedactor(validator.NewValidator(), Options{Mode: ModeHash})
+	if err != nil {
+		t.Fatalf("NewRedactor failed: %v", err)
+	}
+	if other.Value("hunter2") == first {
+		t.Errorf("expected hashes to differ between redactors")
+	}
+}
+
+func TestRedactor_Text(t *testing.T) {
+	redactor, err := NewRedactor(validator.NewValidator(), Options{})
+	if err != nil {
+		t.Fatalf("NewRedactor failed: %v", err)
+	}
+
+	message := redactor.Text("database.password", "hunter2", "invalid value: hunter2")
+	if message != "invalid value: "+Mask {
+		t.Errorf("Text() = %q", message)
+	}
+
+	message = redactor.Text("app.name", "demo", "invalid value: demo")
+	if message != "invalid value: demo" {
+		t.Errorf("expected non-sensitive text to be unchanged, got %q", message)
+	}
+}
+
+func TestNewRedactor_InvalidMode(t *testing.T) {
+	if _, err := NewRedactor(validator.NewValidator(), Options{Mode: "blur"}); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
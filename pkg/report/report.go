@@ -0,0 +1,358 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+// FlagState describes whether a flag still depends on anything
+type FlagState string
+
+const (
+	StateAlwaysOn    FlagState = "always on"
+	StateAlwaysOff   FlagState = "always off"
+	StateConditional FlagState = "conditional"
+)
+
+// ANSI color codes for terminal output
+const (
+	colorReset     = "\033[0m"
+	colorGray      = "\033[90m"
+	colorBoldGreen = "\033[1;32m"
+	colorBoldBlue  = "\033[1;34m"
+	colorBoldCyan  = "\033[1;36m"
+	colorYellow    = "\033[33m"
+)
+
+// colorize adds ANSI color to text
+func colorize(text, color string) string {
+	return color + text + colorReset
+}
+
+// Flag is a feature flag to include in the report
+type Flag struct {
+	Flag         *validator.FeatureFlag
+	Label        string
+	LastModified time.Time
+}
+
+// Finding is a flag that looks ready to be cleaned up, and why
+type Finding struct {
+	Name         string     `json:"name"`
+	Label        string     `json:"label,omitempty"`
+	State        FlagState  `json:"state"`
+	LastModified *time.Time `json:"last_modified,omitempty"`
+	UnchangedFor int        `json:"unchanged_days,omitempty"`
+	References   []string   `json:"references,omitempty"`
+	Reasons      []string   `json:"reasons"`
+}
+
+// Options controls what the report considers stale
+type Options struct {
+	// StaleAfter reports flags unchanged for longer than this; zero disables the check
+	StaleAfter time.Duration
+	// References maps flag names to the files that mention them; nil disables the check
+	References map[string][]string
+}
+
+// Reporter finds stale feature flags
+type Reporter struct {
+	now func() time.Time
+}
+
+// NewReporter creates a new stale flag reporter
+func NewReporter() *Reporter {
+	return &Reporter{
+		now: time.Now,
+	}
+}
+
+// Analyze returns the flags that are always on or off, unchanged for too long, or unreferenced in code
+func (r *Reporter) Analyze(flags []Flag, options Options) []Finding {
+	now := r.now()
+	var findings []Finding
+
+	for _, flag := range flags {
+		finding := Finding{
+			Name:  flag.Flag.ID,
+			Label: flag.Label,
+			State: r.flagState(flag.Flag, now),
+		}
+
+		if finding.State != StateConditional {
+			finding.Reasons = append(finding.Reasons, string(finding.State))
+		}
+
+		if !flag.LastModified.IsZero() {
+			lastModified := flag.LastModified
+			finding.LastModified = &lastModified
+			finding.UnchangedFor = int(now.Sub(lastModified).Hours() / 24)
+
+			if options.StaleAfter > 0 && now.Sub(lastModified) > options.StaleAfter {
+				finding.Reasons = append(finding.Reasons, fmt.Sprintf("unchanged for %d days", finding.UnchangedFor))
+			}
+		}
+
+		if options.References != nil {
+			finding.References = options.References[flag.Flag.ID]
+			if len(finding.References) == 0 {
+				finding.Reasons = append(finding.Reasons, "not referenced in code")
+			}
+		}
+
+		if len(finding.Reasons) > 0 {
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Name != findings[j].Name {
+			return findings[i].Name < findings[j].Name
+		}
+		return findings[i].Label < findings[j].Label
+	})
+
+	return findings
+}
+
+// flagState classifies a flag by whether its filters can still change its outcome
+func (r *Reporter) flagState(ff *validator.FeatureFlag, now time.Time) FlagState {
+	if !ff.Enabled {
+		return StateAlwaysOff
+	}
+
+	// Variants still split users even when the flag itself is always on
+	if allocation := ff.Allocation; allocation != nil &&
+		(len(allocation.User) > 0 || len(allocation.Group) > 0 || len(allocation.Percentile) > 0) {
+		return StateConditional
+	}
+
+	filters := ff.Conditions.ClientFilters
+	if len(filters) == 0 {
+		return StateAlwaysOn
+	}
+
+	requireAll := strings.EqualFold(ff.Conditions.RequirementType, "All")
+	allOn, anyOn, allOff, anyOff := true, false, true, false
+	for _, filter := range filters {
+		on, off := filterAlwaysOn(filter, now), filterAlwaysOff(filter, now)
+		allOn, anyOn = allOn && on, anyOn || on
+		allOff, anyOff = allOff && off, anyOff || off
+	}
+
+	switch {
+	case requireAll && allOn, !requireAll && anyOn:
+		return StateAlwaysOn
+	case requireAll && anyOff, !requireAll && allOff:
+		return StateAlwaysOff
+	default:
+		return StateConditional
+	}
+}
+
+// filterAlwaysOn reports whether a filter passes for everyone from now on
+func filterAlwaysOn(filter validator.ClientFilter, now time.Time) bool {
+	switch {
+	case filter.Percentage != nil:
+		return filter.Percentage.Value >= 100
+	case filter.TimeWindow != nil:
+		start, startErr := validator.ParseFilterTime(filter.TimeWindow.Start)
+		return filter.TimeWindow.End == "" && (filter.TimeWindow.Start == "" || startErr == nil && !now.Before(start))
+	case filter.Targeting != nil:
+		audience := filter.Targeting.Audience
+		return audience.DefaultRolloutPercentage >= 100 && audience.Exclusion == nil
+	default:
+		return false
+	}
+}
+
+// filterAlwaysOff reports whether a filter never passes again
+func filterAlwaysOff(filter validator.ClientFilter, now time.Time) bool {
+	switch {
+	case filter.Percentage != nil:
+		return filter.Percentage.Value <= 0
+	case filter.TimeWindow != nil:
+		ended, err := filter.TimeWindow.EndedAt(now)
+		return err == nil && ended
+	case filter.Targeting != nil:
+		audience := filter.Targeting.Audience
+		if len(audience.Users) > 0 || audience.DefaultRolloutPercentage > 0 {
+			return false
+		}
+		for _, group := range audience.Groups {
+			if group.RolloutPercentage > 0 {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// skippedDirs are directories never scanned for flag references
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"bin":          true,
+	"obj":          true,
+	"dist":         true,
+}
+
+// maxScanFileSize is the largest file scanned for flag references
+const maxScanFileSize = 1 << 20
+
+// ScanReferences searches a source tree for whole-word mentions of flag names and returns
+// the files mentioning each name. Files listed in exclude, such as the configuration file
+// itself, are not scanned.
+func ScanReferences(root string, names []string, exclude []string) (map[string][]string, error) {
+	patterns := make(map[string]*regexp.Regexp, len(names))
+	for _, name := range names {
+		patterns[name] = regexp.MustCompile(`(^|[^A-Za-z0-9_])` + regexp.QuoteMeta(name) + `($|[^A-Za-z0-9_])`)
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, path := range exclude {
+		if path == "" {
+			continue
+		}
+		if absolute, err := filepath.Abs(path); err == nil {
+			excluded[absolute] = true
+		}
+	}
+
+	references := make(map[string][]string, len(names))
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != root && (skippedDirs[entry.Name()] || strings.HasPrefix(entry.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if absolute, err := filepath.Abs(path); err == nil && excluded[absolute] {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxScanFileSize {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if bytes.IndexByte(content, 0) >= 0 {
+			return nil // binary file
+		}
+
+		for name, pattern := range patterns {
+			if pattern.Match(content) {
+				references[name] = append(references[name], path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	return references, nil
+}
+
+// FormatConsole formats findings for console output with colors
+func (r *Reporter) FormatConsole(findings []Finding) string {
+	if len(findings) == 0 {
+		return colorize("✨ No stale feature flags found.", colorBoldGreen)
+	}
+
+	output := colorize("🧹 Stale Feature Flags", colorBoldCyan) + "\n"
+	output += colorize(strings.Repeat("═", 60), colorGray) + "\n\n"
+
+	for _, finding := range findings {
+		name := finding.Name
+		if finding.Label != "" {
+			name += colorize(fmt.Sprintf(" [%s]", finding.Label), colorGray)
+		}
+		output += fmt.Sprintf("🚩 %s\n", colorize(name, colorBoldBlue))
+		output += fmt.Sprintf("   %s\n", colorize(strings.Join(finding.Reasons, ", "), colorYellow))
+
+		if finding.LastModified != nil {
+			output += fmt.Sprintf("   Last modified: %s\n", finding.LastModified.Format("2006-01-02"))
+		}
+		if len(finding.References) > 0 {
+			output += fmt.Sprintf("   Referenced in: %s\n", strings.Join(finding.References, ", "))
+		}
+		output += "\n"
+	}
+
+	output += colorize(strings.Repeat("═", 60), colorGray) + "\n"
+	output += fmt.Sprintf("%d flags can probably be cleaned up\n", len(findings))
+	return output
+}
+
+// FormatJSON formats findings as JSON for machine-readable output
+func (r *Reporter) FormatJSON(findings []Finding) ([]byte, error) {
+	if findings == nil {
+		findings = []Finding{}
+	}
+
+	output := struct {
+		Flags []Finding `json:"flags"`
+		Total int       `json:"total"`
+	}{
+		Flags: findings,
+		Total: len(findings),
+	}
+
+	return json.MarshalIndent(output, "", "  ")
+}
+
+// FormatMarkdown formats findings as a markdown table, for issues and pull requests
+func (r *Reporter) FormatMarkdown(findings []Finding) string {
+	var writer strings.Builder
+
+	fmt.Fprintln(&writer, "## Stale feature flags")
+	fmt.Fprintln(&writer)
+	if len(findings) == 0 {
+		fmt.Fprintln(&writer, "No stale feature flags found.")
+		return writer.String()
+	}
+
+	fmt.Fprintln(&writer, "| Flag | Label | State | Last modified | Why |")
+	fmt.Fprintln(&writer, "|------|-------|-------|---------------|-----|")
+	for _, finding := range findings {
+		lastModified := "-"
+		if finding.LastModified != nil {
+			lastModified = finding.LastModified.Format("2006-01-02")
+		}
+		fmt.Fprintf(&writer, "| `%s` | %s | %s | %s | %s |\n",
+			finding.Name, markdownCell(finding.Label), finding.State, lastModified, markdownCell(strings.Join(finding.Reasons, ", ")))
+	}
+
+	return writer.String()
+}
+
+// markdownCell escapes a value for a markdown table cell
+func markdownCell(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(value, "|", `\|`)
+}
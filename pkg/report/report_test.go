@@ -0,0 +1,99 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+func parseFlag(t *testing.T, value string) *validator.FeatureFlag {
+	t.Helper()
+	ff, err := validator.ParseFeatureFlagValue(value)
+	if err != nil {
+		t.Fatalf("failed to parse flag: %v", err)
+	}
+	return ff
+}
+
+func TestReporter_Analyze(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	reporter := NewReporter()
+	reporter.now = func() time.Time { return now }
+
+	flags := []Flag{
+		{Flag: parseFlag(t, `{"id":"On","enabled":true}`), LastModified: now.AddDate(0, 0, -10)},
+		{Flag: parseFlag(t, `{"id":"Off","enabled":false}`), LastModified: now.AddDate(0, 0, -10)},
+		{Flag: parseFlag(t, `{"id":"Rollout","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.Percentage","parameters":{"Value":30}}]}}`), LastModified: now.AddDate(0, 0, -200)},
+		{Flag: parseFlag(t, `{"id":"Full","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.Targeting","parameters":{"Audience":{"DefaultRolloutPercentage":100}}}]}}`), LastModified: now.AddDate(0, 0, -1)},
+		{Flag: parseFlag(t, `{"id":"Ended","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.TimeWindow","parameters":{"End":"Mon, 01 Jan 2024 00:00:00 GMT"}}]}}`)},
+		{Flag: parseFlag(t, `{"id":"Active","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.Targeting","parameters":{"Audience":{"Users":["alice"]}}}]}}`), LastModified: now.AddDate(0, 0, -1)},
+	}
+
+	findings := reporter.Analyze(flags, Options{
+		StaleAfter: 90 * 24 * time.Hour,
+		References: map[string][]string{"On": {"app.go"}, "Off": {"app.go"}, "Full": {"app.go"}, "Ended": {"app.go"}},
+	})
+
+	expected := map[string][]string{
+		"Ended":   {"always off"},
+		"Full":    {"always on"},
+		"Off":     {"always off"},
+		"On":      {"always on"},
+		"Rollout": {"unchanged for 200 days", "not referenced in code"},
+		"Active":  {"not referenced in code"},
+	}
+
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+	for _, finding := range findings {
+		if !reflect.DeepEqual(finding.Reasons, expected[finding.Name]) {
+			t.Errorf("flag %s: expected reasons %v, got %v", finding.Name, expected[finding.Name], finding.Reasons)
+		}
+	}
+}
+
+func TestScanReferences(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"src/app.cs":          `if (await featureManager.IsEnabledAsync("Beta")) {}`,
+		"src/other.cs":        `var betaTester = "BetaTester";`,
+		"config.json":         `{"featureFlags":{"Beta":true,"Unused":true}}`,
+		"node_modules/lib.js": `isEnabled("Unused")`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	references, err := ScanReferences(root, []string{"Beta", "Unused"}, []string{filepath.Join(root, "config.json")})
+	if err != nil {
+		t.Fatalf("ScanReferences() error = %v", err)
+	}
+
+	if len(references["Beta"]) != 1 || !strings.HasSuffix(references["Beta"][0], "app.cs") {
+		t.Errorf("expected Beta to be referenced only in app.cs, got %v", references["Beta"])
+	}
+	if len(references["Unused"]) != 0 {
+		t.Errorf("expected Unused to be unreferenced, got %v", references["Unused"])
+	}
+}
+
+func TestReporter_FormatMarkdown(t *testing.T) {
+	reporter := NewReporter()
+	markdown := reporter.FormatMarkdown([]Finding{{Name: "Beta", State: StateAlwaysOn, Reasons: []string{"always on"}}})
+
+	if !strings.Contains(markdown, "| `Beta` | - | always on | - | always on |") {
+		t.Errorf("unexpected markdown:\n%s", markdown)
+	}
+}
@@ -0,0 +1,270 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+)
+
+// CanaryOptions controls a canary apply
+type CanaryOptions struct {
+	// CanaryLabel is the label the changes are written to first
+	CanaryLabel string
+	// HealthURL is polled while the canary is live; any non-2xx response fails the canary
+	HealthURL string
+	// SettleTime is how long the app gets to load the canary values before HealthURL is
+	// checked, so a health check that passed before the app saw them does not count
+	SettleTime time.Duration
+	// HealthyFor is how long HealthURL must stay healthy before promoting
+	HealthyFor time.Duration
+	// Timeout bounds the health check after SettleTime, including waiting for the endpoint
+	// to come up
+	Timeout time.Duration
+	// PollInterval is the delay between health checks
+	PollInterval time.Duration
+	// Confirm is asked whether to promote when no HealthURL is set
+	Confirm func() bool
+	// SentinelKey, when set, is written with SentinelValue under the canary label after the
+	// canary values, so apps that refresh on the sentinel load them
+	SentinelKey   string
+	SentinelValue string
+}
+
+// CanaryLabel derives the default canary label for a label
+func CanaryLabel(label string) string {
+	if label == "" {
+		return "canary"
+	}
+	return label + "-canary"
+}
+
+// ApplyCanary writes the added and updated values to the canary label, waits for the
+// canary to be confirmed or to stay healthy, then applies the changes to their real
+// labels. The canary label, including its sentinel, is cleaned up afterwards, and
+// immediately if the canary fails.
+// Deletions are only applied during promotion, as a label cannot express a missing key.
+func (e *Engine) ApplyCanary(ctx context.Context, changes []diff.Change, strict bool, opts CanaryOptions) error {
+	if len(changes) == 0 {
+		return nil // Nothing to do
+	}
+
+	if opts.CanaryLabel == "" {
+		return fmt.Errorf("canary label is required")
+	}
+	for _, change := range changes {
+		if change.Label == opts.CanaryLabel {
+			return fmt.Errorf("canary label %s is also the label of %s; choose another canary label", opts.CanaryLabel, change.Key)
+		}
+	}
+
+	// Settings already under the canary label are put back afterwards rather than deleted
+	existing, err := e.azureClient.FetchAll(ctx, opts.CanaryLabel)
+	if err != nil {
+		return fmt.Errorf("failed to read canary label %s: %w", opts.CanaryLabel, err)
+	}
+
+	canaryChanges := e.canaryChanges(changes, opts.CanaryLabel)
+	var sentinel []diff.Change
+	if opts.SentinelKey != "" {
+		sentinel = append(sentinel, sentinelChange(opts.SentinelKey, opts.CanaryLabel, opts.SentinelValue, existing))
+	}
+	cleanup := canaryCleanup(append(canaryChanges, sentinel...), existing)
+
+	fmt.Printf("Writing %d changes to canary label %s...\n", len(canaryChanges), opts.CanaryLabel)
+	if err := e.ApplyChanges(ctx, canaryChanges, strict); err != nil {
+		return e.abortCanary(ctx, cleanup, fmt.Errorf("failed to write canary changes: %w", err))
+	}
+	// The sentinel goes last so apps do not refresh before all canary values are written
+	if err := e.ApplyChanges(ctx, sentinel, false); err != nil {
+		return e.abortCanary(ctx, cleanup, fmt.Errorf("failed to bump sentinel key %s on canary label: %w", opts.SentinelKey, err))
+	}
+
+	if opts.HealthURL != "" {
+		fmt.Printf("Waiting for %s to stay healthy for %v...\n", opts.HealthURL, opts.HealthyFor)
+		if err := e.waitHealthy(ctx, opts); err != nil {
+			return e.abortCanary(ctx, cleanup, fmt.Errorf("canary health check failed: %w", err))
+		}
+	} else if opts.Confirm == nil || !opts.Confirm() {
+		return e.abortCanary(ctx, cleanup, fmt.Errorf("canary was not confirmed"))
+	}
+
+	fmt.Println("Promoting canary changes...")
+	if err := e.ApplyChanges(ctx, changes, strict); err != nil {
+		// Keep the canary in place: clients reading it still see the intended values
+		return fmt.Errorf("failed to promote canary changes (canary label %s left in place): %w", opts.CanaryLabel, err)
+	}
+
+	if err := e.cleanupCanary(ctx, cleanup); err != nil {
+		return fmt.Errorf("changes promoted, but failed to clean up canary label %s: %w", opts.CanaryLabel, err)
+	}
+
+	return nil
+}
+
+// canaryChanges converts added and updated values into additions under the canary label
+func (e *Engine) canaryChanges(changes []diff.Change, canaryLabel string) []diff.Change {
+	var canary []diff.Change
+
+	for _, change := range changes {
+		if change.Type == diff.ChangeTypeDelete {
+			continue
+		}
+
+		canary = append(canary, diff.Change{
+			Type:        diff.ChangeTypeAdd,
+			Key:         change.Key,
+			NewValue:    change.NewValue,
+			Label:       canaryLabel,
+			Tags:        change.Tags,
+			WriteTags:   change.WriteTags,
+			ContentType: change.ContentType,
+		})
+	}
+
+	return canary
+}
+
+// canaryCleanup returns the changes that undo the canary: settings this run added to the
+// canary label are deleted, and settings it overwrote get their previous value back
+func canaryCleanup(canaryChanges []diff.Change, existing []azure.ConfigItem) []diff.Change {
+	previous := make(map[string]azure.ConfigItem, len(existing))
+	for _, item := range existing {
+		previous[item.Key] = item
+	}
+
+	cleanup := make([]diff.Change, len(canaryChanges))
+	for i, change := range canaryChanges {
+		if item, ok := previous[change.Key]; ok {
+			cleanup[i] = diff.Change{
+				Type:        diff.ChangeTypeUpdate,
+				Key:         change.Key,
+				OldValue:    change.NewValue,
+				NewValue:    item.Value,
+				Label:       change.Label,
+				Tags:        item.Tags,
+				WriteTags:   true,
+				ContentType: item.ContentType,
+			}
+			continue
+		}
+
+		cleanup[i] = diff.Change{
+			Type:     diff.ChangeTypeDelete,
+			Key:      change.Key,
+			OldValue: change.NewValue,
+			Label:    change.Label,
+		}
+	}
+	return cleanup
+}
+
+// abortCanary cleans up the canary label and returns the original failure
+func (e *Engine) abortCanary(ctx context.Context, cleanup []diff.Change, cause error) error {
+	fmt.Println("Cleaning up canary label...")
+	if err := e.cleanupCanary(ctx, cleanup); err != nil {
+		return fmt.Errorf("%w (cleanup also failed: %v)", cause, err)
+	}
+	return cause
+}
+
+// cleanupCanary applies the cleanup returned by canaryCleanup
+func (e *Engine) cleanupCanary(ctx context.Context, cleanup []diff.Change) error {
+	// Use a fresh context so cleanup still runs when the caller's context was cancelled
+	return e.ApplyChanges(context.WithoutCancel(ctx), cleanup, true)
+}
+
+// waitHealthy gives the app opts.SettleTime to load the canary values, then polls the
+// health URL until it has been healthy for opts.HealthyFor. Any unhealthy response after
+// the first healthy one fails immediately; before that, the endpoint is given until
+// opts.Timeout to come up.
+func (e *Engine) waitHealthy(ctx context.Context, opts CanaryOptions) error {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = opts.HealthyFor + time.Minute
+	}
+
+	if opts.SettleTime > 0 {
+		fmt.Printf("Giving the app %v to load the canary values...\n", opts.SettleTime)
+		select {
+		case <-e.clock.After(opts.SettleTime):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	deadline := e.clock.Now().Add(timeout)
+	client := &http.Client{Timeout: interval * 5}
+	var healthySince time.Time
+
+	for {
+		err := e.checkHealth(ctx, client, opts.HealthURL)
+		now := e.clock.Now()
+		switch {
+		case err == nil && healthySince.IsZero():
+			healthySince = now
+		case err != nil && !healthySince.IsZero():
+			return err
+		}
+
+		if !healthySince.IsZero() && now.Sub(healthySince) >= opts.HealthyFor {
+			return nil
+		}
+
+		if !now.Before(deadline) {
+			if err != nil {
+				return fmt.Errorf("timed out: %w", err)
+			}
+			return fmt.Errorf("timed out after %v", timeout)
+		}
+
+		select {
+		case <-e.clock.After(interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// checkHealth performs a single health check request
+func (e *Engine) checkHealth(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid health URL: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health endpoint returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+// clock tells the time and waits, so health checks can be tested without sleeping
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the system clock
+type realClock struct{}
+
+// Now implements clock
+func (realClock) Now() time.Time { return time.Now() }
+
+// After implements clock
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
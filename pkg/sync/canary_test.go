@@ -0,0 +1,171 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+)
+
+func TestCanaryLabel(t *testing.T) {
+	tests := []struct {
+		label    string
+		expected string
+	}{
+		{"production", "production-canary"},
+		{"", "canary"},
+	}
+
+	for _, tt := range tests {
+		if result := CanaryLabel(tt.label); result != tt.expected {
+			t.Errorf("CanaryLabel(%q) = %q, expected %q", tt.label, result, tt.expected)
+		}
+	}
+}
+
+// fakeClock advances its time by the requested duration whenever it is waited on
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestWaitHealthy(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int // status returned for each successive request, the last one repeats
+		hasError bool
+	}{
+		{
+			name:     "healthy throughout",
+			statuses: []int{http.StatusOK},
+			hasError: false,
+		},
+		{
+			name:     "comes up late",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			hasError: false,
+		},
+		{
+			name:     "fails after becoming healthy",
+			statuses: []int{http.StatusOK, http.StatusInternalServerError},
+			hasError: true,
+		},
+		{
+			name:     "never healthy",
+			statuses: []int{http.StatusServiceUnavailable},
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(atomic.AddInt32(&requests, 1)) - 1
+				if i >= len(tt.statuses) {
+					i = len(tt.statuses) - 1
+				}
+				w.WriteHeader(tt.statuses[i])
+			}))
+			defer server.Close()
+
+			engine := NewEngine(nil)
+			engine.clock = &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+			err := engine.waitHealthy(context.Background(), CanaryOptions{
+				HealthURL:    server.URL,
+				HealthyFor:   30 * time.Second,
+				Timeout:      2 * time.Minute,
+				PollInterval: 10 * time.Second,
+			})
+
+			if tt.hasError && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestWaitHealthy_SettleTime(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+
+	var firstCheck time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if firstCheck.IsZero() {
+			firstCheck = clock.Now()
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	engine := NewEngine(nil)
+	engine.clock = clock
+
+	err := engine.waitHealthy(context.Background(), CanaryOptions{
+		HealthURL:    server.URL,
+		SettleTime:   time.Minute,
+		HealthyFor:   30 * time.Second,
+		PollInterval: 10 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Health reported before the app could load the canary values does not count
+	if firstCheck.Sub(start) < time.Minute {
+		t.Errorf("first health check after %v, expected none before the 1m settle time", firstCheck.Sub(start))
+	}
+	if elapsed := clock.Now().Sub(start); elapsed < 90*time.Second {
+		t.Errorf("canary promoted after %v, expected at least settle time plus healthy period", elapsed)
+	}
+}
+
+func TestCanaryCleanup(t *testing.T) {
+	canaryChanges := NewEngine(nil).canaryChanges([]diff.Change{
+		{Type: diff.ChangeTypeAdd, Key: "app.new", NewValue: "1", Label: "production"},
+		{Type: diff.ChangeTypeUpdate, Key: "app.name", OldValue: "old", NewValue: "new", Label: "production"},
+		{Type: diff.ChangeTypeDelete, Key: "app.retired", OldValue: "x", Label: "production"},
+	}, "production-canary")
+
+	existing := []azure.ConfigItem{
+		{Key: "app.name", Value: "kept", Label: "production-canary", Tags: map[string]string{"owner": "qa"}},
+		{Key: "app.unrelated", Value: "untouched", Label: "production-canary"},
+	}
+
+	cleanup := canaryCleanup(canaryChanges, existing)
+
+	expected := []diff.Change{
+		{Type: diff.ChangeTypeDelete, Key: "app.new", OldValue: "1", Label: "production-canary"},
+		{Type: diff.ChangeTypeUpdate, Key: "app.name", OldValue: "new", NewValue: "kept", Label: "production-canary",
+			Tags: map[string]string{"owner": "qa"}, WriteTags: true},
+	}
+	if !reflect.DeepEqual(cleanup, expected) {
+		t.Errorf("canaryCleanup() = %+v, expected %+v", cleanup, expected)
+	}
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/chan27-2/appconfigguard/pkg/azure"
 	"github.com/chan27-2/appconfigguard/pkg/diff"
+	"github.com/chan27-2/appconfigguard/pkg/redact"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
 )
 
 // Engine handles synchronization operations
@@ -14,17 +16,27 @@ type Engine struct {
 	azureClient *azure.Client
 	maxRetries  int
 	baseDelay   time.Duration
+	redactor    *redact.Redactor
+	clock       clock
 }
 
 // NewEngine creates a new sync engine
 func NewEngine(azureClient *azure.Client) *Engine {
+	redactor, _ := redact.NewRedactor(validator.NewValidator(), redact.Options{})
 	return &Engine{
 		azureClient: azureClient,
 		maxRetries:  3,
 		baseDelay:   time.Second,
+		redactor:    redactor,
+		clock:       realClock{},
 	}
 }
 
+// SetRedactor changes how sensitive values are hidden in previews; nil shows every value
+func (e *Engine) SetRedactor(redactor *redact.Redactor) {
+	e.redactor = redactor
+}
+
 // ApplyChanges applies the given changes to Azure App Configuration
 func (e *Engine) ApplyChanges(ctx context.Context, changes []diff.Change, strict bool) error {
 	if len(changes) == 0 {
@@ -49,19 +61,31 @@ func (e *Engine) PreviewChanges(changes []diff.Change) {
 	fmt.Println("============================")
 
 	for _, change := range changes {
+		oldValue, newValue := e.truncateValue(change.OldValue), e.truncateValue(change.NewValue)
+		redacted := e.redactor != nil && e.redactor.IsSensitive(change.Key, change.Tags, change.OldValue, change.NewValue)
+		if redacted {
+			oldValue, newValue = e.redactor.Value(change.OldValue), e.redactor.Value(change.NewValue)
+		}
+
 		switch change.Type {
 		case diff.ChangeTypeAdd:
-			fmt.Printf("ADD: %s = %s\n", change.Key, e.truncateValue(change.NewValue))
+			fmt.Printf("ADD: %s = %s\n", change.Key, newValue)
 		case diff.ChangeTypeUpdate:
-			fmt.Printf("UPDATE: %s = %s (was: %s)\n", change.Key, e.truncateValue(change.NewValue), e.truncateValue(change.OldValue))
+			if redacted {
+				fmt.Printf("UPDATE: %s = %s (changed, was: %s)\n", change.Key, newValue, oldValue)
+			} else {
+				fmt.Printf("UPDATE: %s = %s (was: %s)\n", change.Key, newValue, oldValue)
+			}
 		case diff.ChangeTypeDelete:
-			fmt.Printf("DELETE: %s (was: %s)\n", change.Key, e.truncateValue(change.OldValue))
+			fmt.Printf("DELETE: %s (was: %s)\n", change.Key, oldValue)
+		case diff.ChangeTypeRotate:
+			fmt.Printf("ROTATE: %s = %s (was: %s)\n", change.Key, newValue, oldValue)
 		}
 	}
 
 	summary := e.getSummary(changes)
-	fmt.Printf("\nSummary: %d added, %d updated, %d deleted\n",
-		summary.Added, summary.Updated, summary.Deleted)
+	fmt.Printf("\nSummary: %d added, %d updated, %d deleted, %d rotated\n",
+		summary.Added, summary.Updated, summary.Deleted, summary.Rotated)
 }
 
 // convertToOperations converts diff.Changes to azure.ChangeOperations
@@ -70,16 +94,23 @@ func (e *Engine) convertToOperations(changes []diff.Change) []azure.ChangeOperat
 
 	for i, change := range changes {
 		op := azure.ChangeOperation{
-			Key:   change.Key,
-			Label: change.Label,
-			Tags:  change.Tags,
+			Key:         change.Key,
+			Label:       change.Label,
+			ContentType: change.ContentType,
+		}
+		if change.WriteTags {
+			op.Tags = change.Tags
+		}
+		// Feature flags are stored with their own content type
+		if op.ContentType == "" && validator.IsFeatureFlagSetting(change.Key) {
+			op.ContentType = validator.FeatureFlagContentType
 		}
 
 		switch change.Type {
 		case diff.ChangeTypeAdd:
 			op.Operation = "add"
 			op.Value = change.NewValue
-		case diff.ChangeTypeUpdate:
+		case diff.ChangeTypeUpdate, diff.ChangeTypeRotate:
 			op.Operation = "update"
 			op.Value = change.NewValue
 		case diff.ChangeTypeDelete:
@@ -132,6 +163,7 @@ func (e *Engine) getSummary(changes []diff.Change) diff.Summary {
 		Added:   e.countChanges(changes, diff.ChangeTypeAdd),
 		Updated: e.countChanges(changes, diff.ChangeTypeUpdate),
 		Deleted: e.countChanges(changes, diff.ChangeTypeDelete),
+		Rotated: e.countChanges(changes, diff.ChangeTypeRotate),
 		Total:   len(changes),
 	}
 }
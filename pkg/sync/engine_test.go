@@ -0,0 +1,43 @@
+package sync
+
+import (
+	"testing"
+
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+	"github.com/chan27-2/appconfigguard/pkg/validator"
+)
+
+func TestConvertToOperations_Tags(t *testing.T) {
+	tags := map[string]string{"team": "web"}
+	changes := []diff.Change{
+		{Type: diff.ChangeTypeUpdate, Key: "synced", NewValue: "1", Tags: tags},
+		{Type: diff.ChangeTypeUpdate, Key: "promoted", NewValue: "2", Tags: tags, WriteTags: true},
+	}
+
+	operations := NewEngine(nil).convertToOperations(changes)
+
+	// The tags of the existing setting only describe it; they are written when asked for
+	if operations[0].Tags != nil {
+		t.Errorf("synced operation tags = %v, expected none", operations[0].Tags)
+	}
+	if operations[1].Tags["team"] != "web" {
+		t.Errorf("promoted operation tags = %v, expected team=web", operations[1].Tags)
+	}
+}
+
+func TestConvertToOperations_ContentType(t *testing.T) {
+	changes := []diff.Change{
+		{Type: diff.ChangeTypeAdd, Key: validator.FeatureFlagPrefix + "Beta", NewValue: `{"id":"Beta","enabled":true}`},
+		{Type: diff.ChangeTypeAdd, Key: "imported", NewValue: "{}", ContentType: "application/json"},
+		{Type: diff.ChangeTypeAdd, Key: "plain", NewValue: "1"},
+	}
+
+	operations := NewEngine(nil).convertToOperations(changes)
+
+	expected := []string{validator.FeatureFlagContentType, "application/json", ""}
+	for i, operation := range operations {
+		if operation.ContentType != expected[i] {
+			t.Errorf("operation %s content type = %q, expected %q", operation.Key, operation.ContentType, expected[i])
+		}
+	}
+}
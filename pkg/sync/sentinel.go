@@ -0,0 +1,80 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+)
+
+// BumpSentinel writes a new value to the sentinel key so that App Configuration
+// providers watching it refresh their configuration. The sentinel keeps its tags and
+// content type.
+func (e *Engine) BumpSentinel(ctx context.Context, key, label, value string) error {
+	if key == "" {
+		return fmt.Errorf("sentinel key cannot be empty")
+	}
+
+	existing, err := e.azureClient.FetchByKeys(ctx, []string{key}, label)
+	if err != nil {
+		return fmt.Errorf("failed to read sentinel key %s: %w", key, err)
+	}
+
+	return e.ApplyChanges(ctx, []diff.Change{sentinelChange(key, label, value, existing)}, false)
+}
+
+// sentinelChange returns the change that sets the sentinel to value, carrying over the
+// tags and content type of the existing sentinel, if there is one
+func sentinelChange(key, label, value string, existing []azure.ConfigItem) diff.Change {
+	change := diff.Change{
+		Type:     diff.ChangeTypeAdd,
+		Key:      key,
+		NewValue: value,
+		Label:    label,
+	}
+
+	for _, item := range existing {
+		if item.Key == key && item.Label == label {
+			change.Type = diff.ChangeTypeUpdate
+			change.OldValue = item.Value
+			change.Tags = item.Tags
+			change.WriteTags = true
+			change.ContentType = item.ContentType
+		}
+	}
+	return change
+}
+
+// ProtectSentinel removes any deletion of the sentinel key from the changes, so strict
+// mode never deletes the key that applications watch for refreshes
+func ProtectSentinel(changes []diff.Change, key string) []diff.Change {
+	if key == "" {
+		return changes
+	}
+
+	protected := make([]diff.Change, 0, len(changes))
+	for _, change := range changes {
+		if change.Type == diff.ChangeTypeDelete && change.Key == key {
+			continue
+		}
+		protected = append(protected, change)
+	}
+	return protected
+}
+
+// PlanHash returns a short, stable hash identifying a set of changes
+func PlanHash(changes []diff.Change) string {
+	lines := make([]string, len(changes))
+	for i, change := range changes {
+		lines[i] = strings.Join([]string{string(change.Type), change.Key, change.Label, change.NewValue}, "\x00")
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])[:12]
+}
@@ -0,0 +1,79 @@
+package sync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chan27-2/appconfigguard/pkg/azure"
+	"github.com/chan27-2/appconfigguard/pkg/diff"
+)
+
+func TestProtectSentinel(t *testing.T) {
+	changes := []diff.Change{
+		{Type: diff.ChangeTypeDelete, Key: "Sentinel", OldValue: "1"},
+		{Type: diff.ChangeTypeDelete, Key: "app.old"},
+		{Type: diff.ChangeTypeUpdate, Key: "app.name", NewValue: "new"},
+	}
+
+	result := ProtectSentinel(changes, "Sentinel")
+	if len(result) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %v", len(result), result)
+	}
+	for _, change := range result {
+		if change.Key == "Sentinel" {
+			t.Errorf("sentinel deletion was not removed")
+		}
+	}
+
+	if result := ProtectSentinel(changes, ""); len(result) != len(changes) {
+		t.Errorf("expected changes to be untouched without a sentinel key")
+	}
+}
+
+func TestPlanHash(t *testing.T) {
+	a := []diff.Change{
+		{Type: diff.ChangeTypeAdd, Key: "a", NewValue: "1"},
+		{Type: diff.ChangeTypeUpdate, Key: "b", NewValue: "2"},
+	}
+	b := []diff.Change{a[1], a[0]}
+	c := []diff.Change{{Type: diff.ChangeTypeAdd, Key: "a", NewValue: "other"}}
+
+	if PlanHash(a) != PlanHash(b) {
+		t.Errorf("expected hash to be independent of change order")
+	}
+	if PlanHash(a) == PlanHash(c) {
+		t.Errorf("expected different plans to have different hashes")
+	}
+	if len(PlanHash(a)) != 12 {
+		t.Errorf("expected 12 character hash, got %q", PlanHash(a))
+	}
+}
+
+func TestSentinelChange(t *testing.T) {
+	tags := map[string]string{"owner": "platform"}
+	existing := []azure.ConfigItem{
+		{Key: "Sentinel", Value: "old", Label: "production", Tags: tags, ContentType: "text/plain"},
+	}
+
+	change := sentinelChange("Sentinel", "production", "new", existing)
+	expected := diff.Change{
+		Type:        diff.ChangeTypeUpdate,
+		Key:         "Sentinel",
+		OldValue:    "old",
+		NewValue:    "new",
+		Label:       "production",
+		Tags:        tags,
+		WriteTags:   true,
+		ContentType: "text/plain",
+	}
+	if !reflect.DeepEqual(change, expected) {
+		t.Errorf("sentinelChange() = %+v, expected %+v", change, expected)
+	}
+
+	// A missing sentinel is created without tags
+	change = sentinelChange("Sentinel", "staging", "new", nil)
+	expected = diff.Change{Type: diff.ChangeTypeAdd, Key: "Sentinel", NewValue: "new", Label: "staging"}
+	if !reflect.DeepEqual(change, expected) {
+		t.Errorf("sentinelChange() = %+v, expected %+v", change, expected)
+	}
+}
@@ -0,0 +1,175 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// FeatureFlagPrefix is the key prefix App Configuration uses for feature flags
+	FeatureFlagPrefix = ".appconfig.featureflag/"
+
+	// FeatureFlagContentType is the content type of feature flag settings
+	FeatureFlagContentType = "application/vnd.microsoft.appconfig.ff+json;charset=utf-8"
+
+	// FeatureFlagsSection is the top-level section of a local file that holds feature flags
+	FeatureFlagsSection = "featureFlags"
+)
+
+// IsFeatureFlagSetting reports whether a key is a Microsoft feature flag setting
+func IsFeatureFlagSetting(key string) bool {
+	return strings.HasPrefix(key, FeatureFlagPrefix)
+}
+
+// FeatureFlagKey returns the setting key for a feature flag name
+func FeatureFlagKey(name string) string {
+	return FeatureFlagPrefix + name
+}
+
+// FeatureFlagName returns the feature flag name of a feature flag setting key
+func FeatureFlagName(key string) string {
+	return strings.TrimPrefix(key, FeatureFlagPrefix)
+}
+
+// ParseFeatureFlagValue parses a feature flag setting value in the feature management schema
+func ParseFeatureFlagValue(value string) (*FeatureFlag, error) {
+	var ff FeatureFlag
+	if err := json.Unmarshal([]byte(value), &ff); err != nil {
+		return nil, fmt.Errorf("invalid feature flag JSON: %w", err)
+	}
+	return &ff, nil
+}
+
+// FormatFeatureFlagValue renders a feature flag in the canonical feature management schema,
+// so that local and remote values compare equal when they describe the same flag
+func FormatFeatureFlagValue(ff *FeatureFlag) (string, error) {
+	canonical := *ff
+	if canonical.Conditions.ClientFilters == nil {
+		canonical.Conditions.ClientFilters = []ClientFilter{}
+	}
+
+	jsonBytes, err := json.Marshal(canonical)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode feature flag %s: %w", ff.ID, err)
+	}
+	return string(jsonBytes), nil
+}
+
+// NormalizeFeatureFlagValue rewrites a stored feature flag value into its canonical form.
+// Values that cannot be parsed are returned unchanged so validation can report them.
+func NormalizeFeatureFlagValue(value string) string {
+	ff, err := ParseFeatureFlagValue(value)
+	if err != nil {
+		return value
+	}
+
+	normalized, err := FormatFeatureFlagValue(ff)
+	if err != nil {
+		return value
+	}
+	return normalized
+}
+
+// SameFeatureFlag reports whether two feature flag values have the same content, however
+// their JSON is formatted
+func SameFeatureFlag(a, b string) bool {
+	return NormalizeFeatureFlagValue(a) == NormalizeFeatureFlagValue(b)
+}
+
+// FeatureFlagFromLocal builds a feature flag from its entry in the local featureFlags section.
+// The entry is either a boolean or an object with enabled, description and conditions.
+func FeatureFlagFromLocal(name string, entry interface{}) (*FeatureFlag, error) {
+	switch v := entry.(type) {
+	case bool:
+		return &FeatureFlag{ID: name, Enabled: v}, nil
+
+	case map[string]interface{}:
+		jsonBytes, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("feature flag %s: %w", name, err)
+		}
+
+		var ff FeatureFlag
+		if err := json.Unmarshal(jsonBytes, &ff); err != nil {
+			return nil, fmt.Errorf("feature flag %s: %w", name, err)
+		}
+
+		if ff.ID == "" {
+			ff.ID = name
+		} else if ff.ID != name {
+			return nil, fmt.Errorf("feature flag %s: id %q does not match its name", name, ff.ID)
+		}
+		return &ff, nil
+
+	default:
+		return nil, fmt.Errorf("feature flag %s: expected a boolean or an object, got %T", name, entry)
+	}
+}
+
+// FeatureFlagToLocal converts a feature flag into its local featureFlags entry, using the
+// boolean shorthand when the flag has nothing but its enabled state
+func FeatureFlagToLocal(ff *FeatureFlag) interface{} {
+	// Round-trip through JSON so the entry holds plain data for any output format
+	var entry map[string]interface{}
+	jsonBytes, err := json.Marshal(ff)
+	if err != nil || json.Unmarshal(jsonBytes, &entry) != nil {
+		return ff.Enabled
+	}
+
+	delete(entry, "id")
+	if ff.Description == "" {
+		delete(entry, "description")
+	}
+	if len(ff.Conditions.ClientFilters) == 0 && ff.Conditions.RequirementType == "" {
+		delete(entry, "conditions")
+	}
+
+	if len(entry) == 1 {
+		return ff.Enabled
+	}
+	return entry
+}
+
+// FeatureFlagChanges describes, field by field, how a feature flag differs between two values
+func FeatureFlagChanges(oldValue, newValue string) []string {
+	oldFlag, oldErr := ParseFeatureFlagValue(oldValue)
+	newFlag, newErr := ParseFeatureFlagValue(newValue)
+	if oldErr != nil || newErr != nil {
+		return nil
+	}
+
+	var changes []string
+	if oldFlag.Enabled != newFlag.Enabled {
+		changes = append(changes, fmt.Sprintf("enabled: %t → %t", oldFlag.Enabled, newFlag.Enabled))
+	}
+	if oldFlag.Description != newFlag.Description {
+		changes = append(changes, fmt.Sprintf("description: %q → %q", oldFlag.Description, newFlag.Description))
+	}
+	changes = append(changes, describeConditionChanges(oldFlag.Conditions, newFlag.Conditions)...)
+	changes = append(changes, describeVariantChanges(oldFlag, newFlag)...)
+	return changes
+}
+
+// validateFeatureFlagSetting validates a feature flag stored under the feature flag prefix.
+// A missing description is not an error here; ValidateConfiguration reports it as a warning.
+func (v *Validator) validateFeatureFlagSetting(key string, ff *FeatureFlag) error {
+	name := FeatureFlagName(key)
+	if name == "" {
+		return fmt.Errorf("feature flag name cannot be empty")
+	}
+
+	if strings.ContainsAny(name, "%:") {
+		return fmt.Errorf("feature flag name %q cannot contain '%%' or ':'", name)
+	}
+
+	if ff.ID != name {
+		return fmt.Errorf("feature flag id %q does not match key %s", ff.ID, key)
+	}
+
+	if err := v.validateConditions(ff.Conditions); err != nil {
+		return err
+	}
+
+	return v.validateVariants(ff)
+}
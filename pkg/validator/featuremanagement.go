@@ -0,0 +1,171 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FeatureManagementSections are the top-level sections az appconfig kv export writes feature
+// flags to, and az appconfig kv import reads them from
+var FeatureManagementSections = []string{"FeatureManagement", "featureManagement", "feature_management", "feature-management"}
+
+// FeatureFlagsFromFeatureManagement converts a feature management section into feature flags.
+// Flags in the feature management schema are listed under feature_flags; any other entry is
+// a flag in the .NET schema, either a boolean or an object whose EnabledFor lists its
+// filters. A flag enabled only by the AlwaysOn filter is simply enabled.
+func FeatureFlagsFromFeatureManagement(section interface{}) ([]*FeatureFlag, error) {
+	entries, ok := section.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("feature management section must be an object")
+	}
+
+	var flags []*FeatureFlag
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if normalizeSchemaName(name) == "featureflags" {
+			list, ok := entries[name].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of feature flags", name)
+			}
+			for i, entry := range list {
+				ff, err := featureFlagFromSchema(entry)
+				if err != nil {
+					return nil, fmt.Errorf("%s[%d]: %w", name, i, err)
+				}
+				flags = append(flags, ff)
+			}
+			continue
+		}
+
+		ff, err := featureFlagFromDotNet(name, entries[name])
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, ff)
+	}
+
+	return flags, nil
+}
+
+// featureFlagFromSchema converts a flag in the feature management schema, the format of
+// feature flag setting values
+func featureFlagFromSchema(entry interface{}) (*FeatureFlag, error) {
+	jsonBytes, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	ff, err := ParseFeatureFlagValue(string(jsonBytes))
+	if err != nil {
+		return nil, err
+	}
+	if ff.ID == "" {
+		return nil, fmt.Errorf("feature flag has no id")
+	}
+	return ff, nil
+}
+
+// featureFlagFromDotNet converts a flag in the .NET feature management schema
+func featureFlagFromDotNet(name string, entry interface{}) (*FeatureFlag, error) {
+	switch v := entry.(type) {
+	case bool:
+		return &FeatureFlag{ID: name, Enabled: v}, nil
+
+	case map[string]interface{}:
+		ff := &FeatureFlag{ID: name}
+		alwaysOn := false
+		for field, value := range v {
+			switch normalizeSchemaName(field) {
+			case "enabledfor":
+				filters, ok := value.([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("feature flag %s: EnabledFor must be a list of filters", name)
+				}
+				for _, raw := range filters {
+					filter, err := dotNetFilter(raw)
+					if err != nil {
+						return nil, fmt.Errorf("feature flag %s: %w", name, err)
+					}
+					ff.Enabled = true
+					if filter == nil {
+						alwaysOn = true
+					} else {
+						ff.Conditions.ClientFilters = append(ff.Conditions.ClientFilters, *filter)
+					}
+				}
+			case "requirementtype":
+				requirement, ok := value.(string)
+				if !ok {
+					return nil, fmt.Errorf("feature flag %s: RequirementType must be a string", name)
+				}
+				ff.Conditions.RequirementType = requirement
+			default:
+				return nil, fmt.Errorf("feature flag %s: unsupported field %s", name, field)
+			}
+		}
+
+		// AlwaysOn matches everyone, so unless every filter must match, the others never
+		// matter; when they must, AlwaysOn itself is the one that never matters
+		if alwaysOn && !strings.EqualFold(ff.Conditions.RequirementType, "All") {
+			ff.Conditions.ClientFilters = nil
+		}
+		return ff, nil
+
+	default:
+		return nil, fmt.Errorf("feature flag %s: expected a boolean or an object, got %T", name, entry)
+	}
+}
+
+// dotNetFilter converts an EnabledFor entry, returning nil for the AlwaysOn filter
+func dotNetFilter(raw interface{}) (*ClientFilter, error) {
+	entry, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("filter must be an object")
+	}
+
+	stored := make(map[string]interface{}, 2)
+	for field, value := range entry {
+		switch normalizeSchemaName(field) {
+		case "name":
+			stored["name"] = value
+		case "parameters":
+			stored["parameters"] = value
+		}
+	}
+
+	name, _ := stored["name"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("filter has no Name")
+	}
+	if isAlwaysOn(name) {
+		return nil, nil
+	}
+
+	jsonBytes, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+	var filter ClientFilter
+	if err := json.Unmarshal(jsonBytes, &filter); err != nil {
+		return nil, fmt.Errorf("filter %s: %w", name, err)
+	}
+	return &filter, nil
+}
+
+// isAlwaysOn reports whether a filter name is the .NET AlwaysOn filter
+func isAlwaysOn(name string) bool {
+	return strings.EqualFold(name, "AlwaysOn") || strings.EqualFold(name, "On")
+}
+
+// normalizeSchemaName lowercases a field name and drops underscores and dashes, as the
+// feature management libraries accept EnabledFor, enabled_for and enabled-for alike
+func normalizeSchemaName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(name))
+}
@@ -0,0 +1,94 @@
+package validator
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFeatureFlagsFromFeatureManagement(t *testing.T) {
+	var section interface{}
+	content := `{
+		"Beta": true,
+		"Legacy": false,
+		"Checkout": {
+			"EnabledFor": [{"Name": "Microsoft.Percentage", "Parameters": {"Value": 50}}]
+		},
+		"Everyone": {
+			"EnabledFor": [{"Name": "AlwaysOn"}, {"Name": "Microsoft.Percentage", "Parameters": {"Value": 10}}]
+		},
+		"Strict": {
+			"RequirementType": "All",
+			"EnabledFor": [{"Name": "AlwaysOn"}, {"Name": "Microsoft.Percentage", "Parameters": {"Value": 10}}]
+		},
+		"feature_flags": [
+			{"id": "Search", "enabled": true, "conditions": {"client_filters": []}}
+		]
+	}`
+	if err := json.Unmarshal([]byte(content), &section); err != nil {
+		t.Fatalf("invalid test section: %v", err)
+	}
+
+	flags, err := FeatureFlagsFromFeatureManagement(section)
+	if err != nil {
+		t.Fatalf("FeatureFlagsFromFeatureManagement() error = %v", err)
+	}
+
+	expected := []struct {
+		id      string
+		enabled bool
+		filters []string
+	}{
+		{"Beta", true, nil},
+		{"Checkout", true, []string{PercentageFilterName}},
+		{"Everyone", true, nil},
+		{"Legacy", false, nil},
+		{"Strict", true, []string{PercentageFilterName}},
+		{"Search", true, nil},
+	}
+	if len(flags) != len(expected) {
+		t.Fatalf("FeatureFlagsFromFeatureManagement() returned %d flags, expected %d", len(flags), len(expected))
+	}
+	for i, want := range expected {
+		ff := flags[i]
+		var filters []string
+		for _, filter := range ff.Conditions.ClientFilters {
+			filters = append(filters, filter.Name)
+		}
+		if ff.ID != want.id || ff.Enabled != want.enabled || strings.Join(filters, ",") != strings.Join(want.filters, ",") {
+			t.Errorf("flag %d = %s enabled=%v filters=%v, expected %s enabled=%v filters=%v",
+				i, ff.ID, ff.Enabled, filters, want.id, want.enabled, want.filters)
+		}
+	}
+
+	if flags[1].Conditions.ClientFilters[0].Percentage == nil {
+		t.Errorf("Checkout percentage filter was not decoded")
+	}
+}
+
+func TestFeatureFlagsFromFeatureManagement_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"string flag", `{"Beta": "yes"}`, "expected a boolean or an object"},
+		{"unknown field", `{"Beta": {"Enabled": true}}`, "unsupported field Enabled"},
+		{"filter without a name", `{"Beta": {"EnabledFor": [{"Parameters": {}}]}}`, "filter has no Name"},
+		{"schema flag without an id", `{"feature_flags": [{"enabled": true}]}`, "feature flag has no id"},
+		{"not an object", `["Beta"]`, "must be an object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var section interface{}
+			if err := json.Unmarshal([]byte(tt.content), &section); err != nil {
+				t.Fatalf("invalid test section: %v", err)
+			}
+			_, err := FeatureFlagsFromFeatureManagement(section)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FeatureFlagsFromFeatureManagement() error = %v, expected it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
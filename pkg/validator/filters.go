@@ -0,0 +1,589 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the built-in feature management filters
+const (
+	PercentageFilterName = "Microsoft.Percentage"
+	TimeWindowFilterName = "Microsoft.TimeWindow"
+	TargetingFilterName  = "Microsoft.Targeting"
+)
+
+// FeatureFlagConditions holds the client filters that decide whether an enabled flag is on
+type FeatureFlagConditions struct {
+	RequirementType string         `json:"requirement_type,omitempty"`
+	ClientFilters   []ClientFilter `json:"client_filters"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// ClientFilter is a single feature filter. Built-in filters are decoded into their typed
+// parameters; any other filter keeps its parameters as raw JSON values.
+type ClientFilter struct {
+	Name       string
+	Percentage *PercentageFilter
+	TimeWindow *TimeWindowFilter
+	Targeting  *TargetingFilter
+	Parameters map[string]interface{}
+
+	// Extra holds fields of the filter other than its name and parameters
+	Extra map[string]json.RawMessage
+}
+
+// PercentageFilter enables a flag for a percentage of evaluations
+type PercentageFilter struct {
+	Value Percent `json:"Value"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// TimeWindowFilter enables a flag between two RFC1123 times; either bound may be omitted,
+// unless the window repeats on a recurrence
+type TimeWindowFilter struct {
+	Start      string      `json:"Start,omitempty"`
+	End        string      `json:"End,omitempty"`
+	Recurrence *Recurrence `json:"Recurrence,omitempty"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// TargetingFilter enables a flag for an audience of users and groups
+type TargetingFilter struct {
+	Audience Audience `json:"Audience"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Audience describes who a targeting filter applies to
+type Audience struct {
+	Users                    []string           `json:"Users,omitempty"`
+	Groups                   []GroupRollout     `json:"Groups,omitempty"`
+	DefaultRolloutPercentage Percent            `json:"DefaultRolloutPercentage"`
+	Exclusion                *AudienceExclusion `json:"Exclusion,omitempty"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// GroupRollout is the percentage of a group that a targeting filter applies to
+type GroupRollout struct {
+	Name              string  `json:"Name"`
+	RolloutPercentage Percent `json:"RolloutPercentage"`
+}
+
+// AudienceExclusion lists users and groups that are never targeted
+type AudienceExclusion struct {
+	Users  []string `json:"Users,omitempty"`
+	Groups []string `json:"Groups,omitempty"`
+}
+
+// Percent is a percentage that may be stored as a JSON number or a numeric string
+type Percent float64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*p = Percent(number)
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("invalid percentage %s", string(data))
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q", text)
+	}
+	*p = Percent(number)
+	return nil
+}
+
+// String formats the percentage for display
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p), 'f', -1, 64) + "%"
+}
+
+// rawClientFilter is the stored shape of a client filter
+type rawClientFilter struct {
+	Name       string          `json:"name"`
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// filterKind maps a filter name, including the short aliases accepted by the
+// feature management libraries, to its built-in filter name
+func filterKind(name string) string {
+	switch strings.ToLower(name) {
+	case "microsoft.percentage", "percentage", "percentagefilter":
+		return PercentageFilterName
+	case "microsoft.timewindow", "timewindow", "timewindowfilter":
+		return TimeWindowFilterName
+	case "microsoft.targeting", "targeting", "targetingfilter":
+		return TargetingFilterName
+	default:
+		return ""
+	}
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (f *ClientFilter) UnmarshalJSON(data []byte) error {
+	var raw rawClientFilter
+	extra, err := unmarshalWithExtra(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	*f = ClientFilter{Name: raw.Name, Extra: extra}
+	if len(raw.Parameters) == 0 || string(raw.Parameters) == "null" {
+		raw.Parameters = []byte("{}")
+	}
+
+	var target interface{}
+	switch filterKind(raw.Name) {
+	case PercentageFilterName:
+		f.Percentage = &PercentageFilter{}
+		target = f.Percentage
+	case TimeWindowFilterName:
+		f.TimeWindow = &TimeWindowFilter{}
+		target = f.TimeWindow
+	case TargetingFilterName:
+		f.Targeting = &TargetingFilter{}
+		target = f.Targeting
+	default:
+		target = &f.Parameters
+	}
+
+	if err := json.Unmarshal(raw.Parameters, target); err != nil {
+		return fmt.Errorf("invalid parameters for filter %s: %w", raw.Name, err)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (f ClientFilter) MarshalJSON() ([]byte, error) {
+	var parameters interface{}
+	switch {
+	case f.Percentage != nil:
+		parameters = f.Percentage
+	case f.TimeWindow != nil:
+		parameters = f.TimeWindow
+	case f.Targeting != nil:
+		parameters = f.Targeting
+	case f.Parameters != nil:
+		parameters = f.Parameters
+	}
+
+	raw := rawClientFilter{Name: f.Name}
+	if parameters != nil {
+		parametersJSON, err := json.Marshal(parameters)
+		if err != nil {
+			return nil, err
+		}
+		raw.Parameters = parametersJSON
+	}
+	return marshalWithExtra(raw, f.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (c *FeatureFlagConditions) UnmarshalJSON(data []byte) error {
+	type fields FeatureFlagConditions
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*c = FeatureFlagConditions(decoded)
+	c.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (c FeatureFlagConditions) MarshalJSON() ([]byte, error) {
+	type fields FeatureFlagConditions
+	return marshalWithExtra(fields(c), c.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown parameters in Extra
+func (p *PercentageFilter) UnmarshalJSON(data []byte) error {
+	type fields PercentageFilter
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*p = PercentageFilter(decoded)
+	p.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra parameters after the known ones in key order
+func (p PercentageFilter) MarshalJSON() ([]byte, error) {
+	type fields PercentageFilter
+	return marshalWithExtra(fields(p), p.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown parameters in Extra
+func (w *TimeWindowFilter) UnmarshalJSON(data []byte) error {
+	type fields TimeWindowFilter
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*w = TimeWindowFilter(decoded)
+	w.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra parameters after the known ones in key order
+func (w TimeWindowFilter) MarshalJSON() ([]byte, error) {
+	type fields TimeWindowFilter
+	return marshalWithExtra(fields(w), w.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown parameters in Extra
+func (t *TargetingFilter) UnmarshalJSON(data []byte) error {
+	type fields TargetingFilter
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*t = TargetingFilter(decoded)
+	t.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra parameters after the known ones in key order
+func (t TargetingFilter) MarshalJSON() ([]byte, error) {
+	type fields TargetingFilter
+	return marshalWithExtra(fields(t), t.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	type fields Audience
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*a = Audience(decoded)
+	a.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (a Audience) MarshalJSON() ([]byte, error) {
+	type fields Audience
+	return marshalWithExtra(fields(a), a.Extra)
+}
+
+// ParseFilterTime parses a time window bound in RFC1123 format
+func ParseFilterTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC1123, time.RFC1123Z} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an RFC1123 time (e.g. %q)", value, "Mon, 02 Jan 2006 15:04:05 GMT")
+}
+
+// validateConditions validates the client filters of a feature flag
+func (v *Validator) validateConditions(conditions FeatureFlagConditions) error {
+	switch strings.ToLower(conditions.RequirementType) {
+	case "", "any", "all":
+	default:
+		return fmt.Errorf("invalid requirement_type %q: expected Any or All", conditions.RequirementType)
+	}
+
+	for _, filter := range conditions.ClientFilters {
+		if err := v.validateClientFilter(filter); err != nil {
+			return fmt.Errorf("filter %s: %w", filter.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateClientFilter validates the parameters of a built-in filter
+func (v *Validator) validateClientFilter(filter ClientFilter) error {
+	if filter.Name == "" {
+		return fmt.Errorf("filter name cannot be empty")
+	}
+
+	switch {
+	case filter.Percentage != nil:
+		return validatePercent("Value", filter.Percentage.Value)
+
+	case filter.TimeWindow != nil:
+		window := filter.TimeWindow
+		if window.Start == "" && window.End == "" {
+			return fmt.Errorf("time window needs a Start or an End")
+		}
+
+		start, end, err := window.bounds()
+		if err != nil {
+			return err
+		}
+		if window.Start != "" && window.End != "" && !start.Before(end) {
+			return fmt.Errorf("Start must be before End")
+		}
+		if window.Recurrence != nil {
+			return validateRecurrence(window.Recurrence, start, end)
+		}
+
+	case filter.Targeting != nil:
+		audience := filter.Targeting.Audience
+		if err := validatePercent("DefaultRolloutPercentage", audience.DefaultRolloutPercentage); err != nil {
+			return err
+		}
+		for _, group := range audience.Groups {
+			if group.Name == "" {
+				return fmt.Errorf("group name cannot be empty")
+			}
+			if err := validatePercent("RolloutPercentage of group "+group.Name, group.RolloutPercentage); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePercent checks that a percentage is between 0 and 100
+func validatePercent(field string, value Percent) error {
+	if value < 0 || value > 100 {
+		return fmt.Errorf("%s must be between 0 and 100, got %s", field, value)
+	}
+	return nil
+}
+
+// describeConditionChanges describes how the client filters of a flag changed, matching filters by name
+func describeConditionChanges(oldConditions, newConditions FeatureFlagConditions) []string {
+	var changes []string
+
+	if !strings.EqualFold(oldConditions.RequirementType, newConditions.RequirementType) {
+		changes = append(changes, fmt.Sprintf("requirement type: %s → %s",
+			displayRequirement(oldConditions.RequirementType), displayRequirement(newConditions.RequirementType)))
+	}
+
+	oldFilters := filtersByName(oldConditions.ClientFilters)
+	newFilters := filtersByName(newConditions.ClientFilters)
+
+	names := make([]string, 0, len(oldFilters)+len(newFilters))
+	for name := range oldFilters {
+		names = append(names, name)
+	}
+	for name := range newFilters {
+		if _, exists := oldFilters[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oldFilter, hadOld := oldFilters[name]
+		newFilter, hasNew := newFilters[name]
+
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("filter added: %s", describeFilter(newFilter)))
+		case !hasNew:
+			changes = append(changes, fmt.Sprintf("filter removed: %s", describeFilter(oldFilter)))
+		default:
+			changes = append(changes, describeFilterChanges(oldFilter, newFilter)...)
+		}
+	}
+	changes = append(changes, describeExtraChanges("conditions ", oldConditions.Extra, newConditions.Extra)...)
+
+	return changes
+}
+
+// filtersByName indexes filters by their canonical name
+func filtersByName(filters []ClientFilter) map[string]ClientFilter {
+	result := make(map[string]ClientFilter, len(filters))
+	for _, filter := range filters {
+		name := filterKind(filter.Name)
+		if name == "" {
+			name = filter.Name
+		}
+		result[name] = filter
+	}
+	return result
+}
+
+// describeFilter summarizes a filter on a single line
+func describeFilter(filter ClientFilter) string {
+	switch {
+	case filter.Percentage != nil:
+		return fmt.Sprintf("%s (%s)", filter.Name, filter.Percentage.Value)
+	case filter.TimeWindow != nil:
+		return fmt.Sprintf("%s (%s)", filter.Name, describeWindow(filter.TimeWindow))
+	case filter.Targeting != nil:
+		audience := filter.Targeting.Audience
+		return fmt.Sprintf("%s (%d users, %d groups, default %s)",
+			filter.Name, len(audience.Users), len(audience.Groups), audience.DefaultRolloutPercentage)
+	default:
+		return filter.Name
+	}
+}
+
+// describeWindow formats a time window for display
+func describeWindow(window *TimeWindowFilter) string {
+	start, end := window.Start, window.End
+	if start == "" {
+		start = "always"
+	}
+	if end == "" {
+		end = "forever"
+	}
+	if window.Recurrence != nil {
+		return start + " → " + end + ", " + describeRecurrence(window.Recurrence)
+	}
+	return start + " → " + end
+}
+
+// describeFilterChanges describes the differences between two versions of the same filter
+func describeFilterChanges(oldFilter, newFilter ClientFilter) []string {
+	var changes []string
+	name := newFilter.Name
+
+	switch {
+	case oldFilter.Percentage != nil && newFilter.Percentage != nil:
+		if oldFilter.Percentage.Value != newFilter.Percentage.Value {
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", name, oldFilter.Percentage.Value, newFilter.Percentage.Value))
+		}
+		changes = append(changes, describeExtraChanges(name+": ", oldFilter.Percentage.Extra, newFilter.Percentage.Extra)...)
+
+	case oldFilter.TimeWindow != nil && newFilter.TimeWindow != nil:
+		oldWindow, newWindow := *oldFilter.TimeWindow, *newFilter.TimeWindow
+		oldWindow.Extra, newWindow.Extra = nil, nil
+		if !reflect.DeepEqual(oldWindow, newWindow) {
+			changes = append(changes, fmt.Sprintf("%s: %s  ⇒  %s", name, describeWindow(oldFilter.TimeWindow), describeWindow(newFilter.TimeWindow)))
+		}
+		changes = append(changes, describeExtraChanges(name+": ", oldFilter.TimeWindow.Extra, newFilter.TimeWindow.Extra)...)
+
+	case oldFilter.Targeting != nil && newFilter.Targeting != nil:
+		changes = append(changes, describeAudienceChanges(name, oldFilter.Targeting.Audience, newFilter.Targeting.Audience)...)
+		changes = append(changes, describeExtraChanges(name+": ", oldFilter.Targeting.Extra, newFilter.Targeting.Extra)...)
+		changes = append(changes, describeExtraChanges(name+": Audience.", oldFilter.Targeting.Audience.Extra, newFilter.Targeting.Audience.Extra)...)
+
+	default:
+		oldJSON, _ := json.Marshal(oldFilter.Parameters)
+		newJSON, _ := json.Marshal(newFilter.Parameters)
+		if string(oldJSON) != string(newJSON) {
+			changes = append(changes, fmt.Sprintf("%s: parameters changed", name))
+		}
+	}
+	changes = append(changes, describeExtraChanges(name+": ", oldFilter.Extra, newFilter.Extra)...)
+
+	return changes
+}
+
+// describeAudienceChanges lists who was added to or removed from a targeting audience
+func describeAudienceChanges(name string, oldAudience, newAudience Audience) []string {
+	var changes []string
+
+	added, removed := diffStrings(oldAudience.Users, newAudience.Users)
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("%s: users added: %s", name, strings.Join(added, ", ")))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("%s: users removed: %s", name, strings.Join(removed, ", ")))
+	}
+
+	oldGroups := make(map[string]Percent, len(oldAudience.Groups))
+	for _, group := range oldAudience.Groups {
+		oldGroups[group.Name] = group.RolloutPercentage
+	}
+	newGroups := make(map[string]Percent, len(newAudience.Groups))
+	for _, group := range newAudience.Groups {
+		newGroups[group.Name] = group.RolloutPercentage
+		oldPercent, existed := oldGroups[group.Name]
+		switch {
+		case !existed:
+			changes = append(changes, fmt.Sprintf("%s: group added: %s (%s)", name, group.Name, group.RolloutPercentage))
+		case oldPercent != group.RolloutPercentage:
+			changes = append(changes, fmt.Sprintf("%s: group %s: %s → %s", name, group.Name, oldPercent, group.RolloutPercentage))
+		}
+	}
+	for _, group := range oldAudience.Groups {
+		if _, exists := newGroups[group.Name]; !exists {
+			changes = append(changes, fmt.Sprintf("%s: group removed: %s (%s)", name, group.Name, group.RolloutPercentage))
+		}
+	}
+
+	if oldAudience.DefaultRolloutPercentage != newAudience.DefaultRolloutPercentage {
+		changes = append(changes, fmt.Sprintf("%s: default rollout: %s → %s",
+			name, oldAudience.DefaultRolloutPercentage, newAudience.DefaultRolloutPercentage))
+	}
+
+	oldExclusion, newExclusion := AudienceExclusion{}, AudienceExclusion{}
+	if oldAudience.Exclusion != nil {
+		oldExclusion = *oldAudience.Exclusion
+	}
+	if newAudience.Exclusion != nil {
+		newExclusion = *newAudience.Exclusion
+	}
+
+	added, removed = diffStrings(oldExclusion.Users, newExclusion.Users)
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("%s: users excluded: %s", name, strings.Join(added, ", ")))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("%s: users no longer excluded: %s", name, strings.Join(removed, ", ")))
+	}
+
+	added, removed = diffStrings(oldExclusion.Groups, newExclusion.Groups)
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("%s: groups excluded: %s", name, strings.Join(added, ", ")))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("%s: groups no longer excluded: %s", name, strings.Join(removed, ", ")))
+	}
+
+	return changes
+}
+
+// diffStrings returns the values only present in b (added) and only present in a (removed)
+func diffStrings(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, value := range a {
+		inA[value] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, value := range b {
+		inB[value] = true
+		if !inA[value] {
+			added = append(added, value)
+		}
+	}
+	for _, value := range a {
+		if !inB[value] {
+			removed = append(removed, value)
+		}
+	}
+	return added, removed
+}
+
+// displayRequirement formats a requirement type, which defaults to Any
+func displayRequirement(requirementType string) string {
+	if requirementType == "" {
+		return "Any"
+	}
+	return requirementType
+}
@@ -0,0 +1,156 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateFeatureFlagFilters(t *testing.T) {
+	v := NewValidator()
+
+	tests := []struct {
+		name     string
+		filters  string
+		hasError bool
+	}{
+		{
+			name:     "percentage",
+			filters:  `[{"name":"Microsoft.Percentage","parameters":{"Value":50}}]`,
+			hasError: false,
+		},
+		{
+			name:     "percentage as string",
+			filters:  `[{"name":"Microsoft.Percentage","parameters":{"Value":"25"}}]`,
+			hasError: false,
+		},
+		{
+			name:     "percentage out of range",
+			filters:  `[{"name":"Microsoft.Percentage","parameters":{"Value":150}}]`,
+			hasError: true,
+		},
+		{
+			name:     "time window",
+			filters:  `[{"name":"Microsoft.TimeWindow","parameters":{"Start":"Mon, 01 Jan 2024 00:00:00 GMT","End":"Tue, 02 Jan 2024 00:00:00 GMT"}}]`,
+			hasError: false,
+		},
+		{
+			name:     "time window start after end",
+			filters:  `[{"name":"Microsoft.TimeWindow","parameters":{"Start":"Tue, 02 Jan 2024 00:00:00 GMT","End":"Mon, 01 Jan 2024 00:00:00 GMT"}}]`,
+			hasError: true,
+		},
+		{
+			name:     "time window not RFC1123",
+			filters:  `[{"name":"Microsoft.TimeWindow","parameters":{"Start":"2024-01-01T00:00:00Z"}}]`,
+			hasError: true,
+		},
+		{
+			name:     "recurring time window",
+			filters:  `[{"name":"Microsoft.TimeWindow","parameters":{"Start":"Mon, 01 Jan 2024 09:00:00 GMT","End":"Mon, 01 Jan 2024 17:00:00 GMT","Recurrence":{"Pattern":{"Type":"Weekly","DaysOfWeek":["Monday","Friday"]},"Range":{"Type":"Numbered","NumberOfOccurrences":10}}}}]`,
+			hasError: false,
+		},
+		{
+			name:     "recurring time window without End",
+			filters:  `[{"name":"Microsoft.TimeWindow","parameters":{"Start":"Mon, 01 Jan 2024 09:00:00 GMT","Recurrence":{"Pattern":{"Type":"Daily"},"Range":{"Type":"NoEnd"}}}}]`,
+			hasError: true,
+		},
+		{
+			name:     "weekly recurrence without days",
+			filters:  `[{"name":"Microsoft.TimeWindow","parameters":{"Start":"Mon, 01 Jan 2024 09:00:00 GMT","End":"Mon, 01 Jan 2024 17:00:00 GMT","Recurrence":{"Pattern":{"Type":"Weekly"},"Range":{"Type":"NoEnd"}}}}]`,
+			hasError: true,
+		},
+		{
+			name:     "window longer than daily recurrence",
+			filters:  `[{"name":"Microsoft.TimeWindow","parameters":{"Start":"Mon, 01 Jan 2024 00:00:00 GMT","End":"Wed, 03 Jan 2024 00:00:00 GMT","Recurrence":{"Pattern":{"Type":"Daily"},"Range":{"Type":"NoEnd"}}}}]`,
+			hasError: true,
+		},
+		{
+			name:     "recurrence end date before start",
+			filters:  `[{"name":"Microsoft.TimeWindow","parameters":{"Start":"Mon, 01 Jan 2024 09:00:00 GMT","End":"Mon, 01 Jan 2024 17:00:00 GMT","Recurrence":{"Pattern":{"Type":"Daily"},"Range":{"Type":"EndDate","EndDate":"Sun, 31 Dec 2023 00:00:00 GMT"}}}}]`,
+			hasError: true,
+		},
+		{
+			name:     "targeting",
+			filters:  `[{"name":"Microsoft.Targeting","parameters":{"Audience":{"Users":["alice"],"Groups":[{"Name":"beta","RolloutPercentage":20}],"DefaultRolloutPercentage":0,"Exclusion":{"Users":["bob"]}}}}]`,
+			hasError: false,
+		},
+		{
+			name:     "targeting group over 100",
+			filters:  `[{"name":"Microsoft.Targeting","parameters":{"Audience":{"Groups":[{"Name":"beta","RolloutPercentage":101}]}}}]`,
+			hasError: true,
+		},
+		{
+			name:     "custom filter",
+			filters:  `[{"name":"Contoso.Region","parameters":{"Regions":["west"]}}]`,
+			hasError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := `{"id":"Beta","description":"Beta","enabled":true,"conditions":{"client_filters":` + tt.filters + `}}`
+			_, err := v.ValidateAndParseValue(".appconfig.featureflag/Beta", value)
+
+			if tt.hasError && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagChanges_Audience(t *testing.T) {
+	oldValue := `{"id":"Beta","description":"","enabled":true,"conditions":{"client_filters":[
+		{"name":"Microsoft.Targeting","parameters":{"Audience":{"Users":["alice","carol"],"Groups":[{"Name":"beta","RolloutPercentage":10}],"DefaultRolloutPercentage":0}}}]}}`
+	newValue := `{"id":"Beta","description":"","enabled":true,"conditions":{"client_filters":[
+		{"name":"Microsoft.Targeting","parameters":{"Audience":{"Users":["alice","bob"],"Groups":[{"Name":"beta","RolloutPercentage":50},{"Name":"staff","RolloutPercentage":100}],"DefaultRolloutPercentage":5,"Exclusion":{"Users":["mallory"]}}}},
+		{"name":"Microsoft.Percentage","parameters":{"Value":30}}]}}`
+
+	expected := []string{
+		"filter added: Microsoft.Percentage (30%)",
+		"Microsoft.Targeting: users added: bob",
+		"Microsoft.Targeting: users removed: carol",
+		"Microsoft.Targeting: group beta: 10% → 50%",
+		"Microsoft.Targeting: group added: staff (100%)",
+		"Microsoft.Targeting: default rollout: 0% → 5%",
+		"Microsoft.Targeting: users excluded: mallory",
+	}
+
+	if result := FeatureFlagChanges(oldValue, newValue); !reflect.DeepEqual(result, expected) {
+		t.Errorf("FeatureFlagChanges() = %q, expected %q", result, expected)
+	}
+}
+
+func TestTimeWindowRecurrence_RoundTrip(t *testing.T) {
+	value := `{"id":"Standup","description":"","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.TimeWindow","parameters":{"Start":"Mon, 01 Jan 2024 09:00:00 GMT","End":"Mon, 01 Jan 2024 09:15:00 GMT","Recurrence":{"Pattern":{"Type":"Weekly","Interval":2,"DaysOfWeek":["Monday","Thursday"],"FirstDayOfWeek":"Monday"},"Range":{"Type":"EndDate","EndDate":"Mon, 01 Jul 2024 00:00:00 GMT"}}}}]}}`
+
+	ff, err := ParseFeatureFlagValue(value)
+	if err != nil {
+		t.Fatalf("ParseFeatureFlagValue() error = %v", err)
+	}
+	result, err := FormatFeatureFlagValue(ff)
+	if err != nil {
+		t.Fatalf("FormatFeatureFlagValue() error = %v", err)
+	}
+
+	if result != value {
+		t.Errorf("FormatFeatureFlagValue() = %s, expected %s", result, value)
+	}
+}
+
+func TestFeatureFlagChanges_Recurrence(t *testing.T) {
+	oldValue := `{"id":"Standup","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.TimeWindow","parameters":{
+		"Start":"Mon, 01 Jan 2024 09:00:00 GMT","End":"Mon, 01 Jan 2024 09:15:00 GMT","Recurrence":{"Pattern":{"Type":"Daily"},"Range":{"Type":"NoEnd"}}}}]}}`
+	newValue := `{"id":"Standup","enabled":true,"conditions":{"client_filters":[{"name":"Microsoft.TimeWindow","parameters":{
+		"Start":"Mon, 01 Jan 2024 09:00:00 GMT","End":"Mon, 01 Jan 2024 09:15:00 GMT","Recurrence":{"Pattern":{"Type":"Weekly","DaysOfWeek":["Monday"]},"Range":{"Type":"Numbered","NumberOfOccurrences":4}}}}]}}`
+
+	expected := []string{
+		"Microsoft.TimeWindow: Mon, 01 Jan 2024 09:00:00 GMT → Mon, 01 Jan 2024 09:15:00 GMT, every day" +
+			"  ⇒  Mon, 01 Jan 2024 09:00:00 GMT → Mon, 01 Jan 2024 09:15:00 GMT, every week on Monday, 4 times",
+	}
+
+	if result := FeatureFlagChanges(oldValue, newValue); !reflect.DeepEqual(result, expected) {
+		t.Errorf("FeatureFlagChanges() = %q, expected %q", result, expected)
+	}
+}
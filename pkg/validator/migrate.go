@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FeatureFlagMigration describes converting a heuristic boolean flag setting, such as
+// feature.Beta = true, into a real feature flag
+type FeatureFlagMigration struct {
+	// Key is the heuristic setting being migrated
+	Key string
+	// Value is its current value
+	Value string
+	// Flag is the feature flag that replaces it
+	Flag *FeatureFlag
+	// Inverted is true for disable.X and X.disabled keys, whose value is the opposite of the flag state
+	Inverted bool
+}
+
+// FeatureFlagMigrationSkip records a heuristic flag setting that could not be migrated
+type FeatureFlagMigrationSkip struct {
+	Key    string
+	Reason string
+}
+
+// HeuristicFeatureFlag reports whether a setting is a boolean flag recognised by its key
+// pattern rather than stored as a real feature flag, and parses it
+func (v *Validator) HeuristicFeatureFlag(key, value string) (*FeatureFlag, bool) {
+	if IsFeatureFlagSetting(key) {
+		return nil, false
+	}
+
+	ff, err := v.parseFeatureFlag(key, value)
+	if err != nil {
+		return nil, false
+	}
+	return ff, true
+}
+
+// MigratedFeatureFlagName derives a feature flag name from a heuristic flag key: the part of
+// the key matched by the * of the first flag detection pattern it matches, such as Beta for
+// feature.Beta. A pattern with several * keeps the whole key. inverted is true when the
+// pattern describes the disabled state, as disable.* and *.disabled do.
+func (v *Validator) MigratedFeatureFlagName(key string) (name string, inverted bool) {
+	for _, pattern := range v.rules.patterns {
+		match := pattern.re.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		if len(match) != 2 {
+			return key, pattern.inverted
+		}
+		if match[1] != "" {
+			return match[1], pattern.inverted
+		}
+	}
+
+	return key, false
+}
+
+// PlanFeatureFlagMigration finds the heuristic boolean flags in a flat configuration and
+// plans their conversion into real feature flags with the same effective state. Settings
+// whose flag name is invalid, already taken by a real flag, or claimed by another setting
+// are skipped.
+func (v *Validator) PlanFeatureFlagMigration(config map[string]string) ([]FeatureFlagMigration, []FeatureFlagMigrationSkip) {
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var migrations []FeatureFlagMigration
+	var skipped []FeatureFlagMigrationSkip
+	claimedBy := make(map[string]string)
+
+	for _, key := range keys {
+		heuristic, ok := v.HeuristicFeatureFlag(key, config[key])
+		if !ok {
+			continue
+		}
+
+		name, inverted := v.MigratedFeatureFlagName(key)
+		if strings.ContainsAny(name, "%:") {
+			skipped = append(skipped, FeatureFlagMigrationSkip{Key: key, Reason: fmt.Sprintf("flag name %q cannot contain '%%' or ':'", name)})
+			continue
+		}
+		if _, exists := config[FeatureFlagKey(name)]; exists {
+			skipped = append(skipped, FeatureFlagMigrationSkip{Key: key, Reason: fmt.Sprintf("feature flag %s already exists", name)})
+			continue
+		}
+		if other, claimed := claimedBy[name]; claimed {
+			skipped = append(skipped, FeatureFlagMigrationSkip{Key: key, Reason: fmt.Sprintf("feature flag %s is already migrated from %s", name, other)})
+			continue
+		}
+		claimedBy[name] = key
+
+		enabled := heuristic.Enabled
+		if inverted {
+			enabled = !enabled
+		}
+
+		migrations = append(migrations, FeatureFlagMigration{
+			Key:   key,
+			Value: config[key],
+			Flag: &FeatureFlag{
+				ID:          name,
+				Description: fmt.Sprintf("Migrated from %s", key),
+				Enabled:     enabled,
+			},
+			Inverted: inverted,
+		})
+	}
+
+	return migrations, skipped
+}
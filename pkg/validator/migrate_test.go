@@ -0,0 +1,105 @@
+package validator
+
+import (
+	"testing"
+)
+
+func TestMigratedFeatureFlagName(t *testing.T) {
+	tests := []struct {
+		key      string
+		name     string
+		inverted bool
+	}{
+		{"feature.NewUI", "NewUI", false},
+		{"Flag.Search", "Search", false},
+		{"enable.beta_features", "beta_features", false},
+		{"disable.Telemetry", "Telemetry", true},
+		{"checkout.enabled", "checkout", false},
+		{"payments.v2.disabled", "payments.v2", true},
+		{"search.feature", "search", false},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		name, inverted := v.MigratedFeatureFlagName(tt.key)
+		if name != tt.name || inverted != tt.inverted {
+			t.Errorf("MigratedFeatureFlagName(%q) = (%q, %v), expected (%q, %v)", tt.key, name, inverted, tt.name, tt.inverted)
+		}
+	}
+}
+
+func TestMigratedFeatureFlagName_CustomPatterns(t *testing.T) {
+	rules := DefaultFlagDetectionRules()
+	rules.Patterns = []string{"FF_*", "disabled_*", "*:toggle:*"}
+	v, err := NewValidatorWithRules(rules)
+	if err != nil {
+		t.Fatalf("NewValidatorWithRules() error = %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		name     string
+		inverted bool
+	}{
+		{"ff_Search", "Search", false},
+		{"disabled_Telemetry", "Telemetry", true},
+		{"web:toggle:dark", "web:toggle:dark", false},
+		{"feature.Beta", "feature.Beta", false},
+	}
+
+	for _, tt := range tests {
+		name, inverted := v.MigratedFeatureFlagName(tt.key)
+		if name != tt.name || inverted != tt.inverted {
+			t.Errorf("MigratedFeatureFlagName(%q) = (%q, %v), expected (%q, %v)", tt.key, name, inverted, tt.name, tt.inverted)
+		}
+	}
+}
+
+func TestPlanFeatureFlagMigration(t *testing.T) {
+	v := NewValidator()
+
+	config := map[string]string{
+		"database.host":            "localhost",
+		"feature.Beta":             "true",
+		"disable.Telemetry":        "yes",
+		"checkout.enabled":         "off",
+		"Beta.enabled":             "false",
+		"feature.Existing":         "true",
+		FeatureFlagKey("Existing"): `{"id":"Existing","description":"","enabled":true,"conditions":{"client_filters":[]}}`,
+		"feature.notes":            "see wiki",
+	}
+
+	migrations, skipped := v.PlanFeatureFlagMigration(config)
+
+	expected := map[string]bool{
+		"Beta":      false, // Beta.enabled sorts first and claims the name
+		"Telemetry": false,
+		"checkout":  false,
+	}
+	if len(migrations) != len(expected) {
+		t.Fatalf("expected %d migrations, got %d: %+v", len(expected), len(migrations), migrations)
+	}
+	for _, migration := range migrations {
+		enabled, ok := expected[migration.Flag.ID]
+		if !ok {
+			t.Errorf("unexpected migration of %s to %s", migration.Key, migration.Flag.ID)
+			continue
+		}
+		if migration.Flag.Enabled != enabled {
+			t.Errorf("flag %s: expected enabled=%v, got %v", migration.Flag.ID, enabled, migration.Flag.Enabled)
+		}
+		if migration.Flag.Description == "" {
+			t.Errorf("flag %s: expected a description", migration.Flag.ID)
+		}
+	}
+
+	skippedKeys := make(map[string]bool)
+	for _, skip := range skipped {
+		skippedKeys[skip.Key] = true
+	}
+	for _, key := range []string{"feature.Beta", "feature.Existing"} {
+		if !skippedKeys[key] {
+			t.Errorf("expected %s to be skipped, got %+v", key, skipped)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Recurrence repeats a time window on a daily or weekly pattern
+type Recurrence struct {
+	Pattern RecurrencePattern `json:"Pattern"`
+	Range   RecurrenceRange   `json:"Range"`
+}
+
+// RecurrencePattern describes how often a time window repeats
+type RecurrencePattern struct {
+	// Type is Daily or Weekly
+	Type string `json:"Type"`
+	// Interval is the number of days or weeks between occurrences; it defaults to 1
+	Interval int `json:"Interval,omitempty"`
+	// DaysOfWeek lists the days a weekly window occurs on
+	DaysOfWeek []string `json:"DaysOfWeek,omitempty"`
+	// FirstDayOfWeek starts the weeks counted by Interval; it defaults to Sunday
+	FirstDayOfWeek string `json:"FirstDayOfWeek,omitempty"`
+}
+
+// RecurrenceRange describes when a time window stops repeating
+type RecurrenceRange struct {
+	// Type is NoEnd, EndDate or Numbered; it defaults to NoEnd
+	Type                string `json:"Type,omitempty"`
+	EndDate             string `json:"EndDate,omitempty"`
+	NumberOfOccurrences int    `json:"NumberOfOccurrences,omitempty"`
+}
+
+// ActiveAt reports whether the time window, or one of its occurrences, is open at the given time
+func (w *TimeWindowFilter) ActiveAt(now time.Time) (bool, error) {
+	start, end, err := w.bounds()
+	if err != nil {
+		return false, err
+	}
+
+	if w.Recurrence == nil {
+		return (w.Start == "" || !now.Before(start)) && (w.End == "" || now.Before(end)), nil
+	}
+
+	last, found, _, err := w.Recurrence.occurrences(start, now)
+	if err != nil {
+		return false, err
+	}
+	return found && now.Before(last.Add(end.Sub(start))), nil
+}
+
+// EndedAt reports whether the time window, and every occurrence of it, has closed by the given time
+func (w *TimeWindowFilter) EndedAt(now time.Time) (bool, error) {
+	start, end, err := w.bounds()
+	if err != nil {
+		return false, err
+	}
+
+	if w.Recurrence == nil {
+		return w.End != "" && !now.Before(end), nil
+	}
+
+	last, found, more, err := w.Recurrence.occurrences(start, now)
+	if err != nil {
+		return false, err
+	}
+	return !more && (!found || !now.Before(last.Add(end.Sub(start)))), nil
+}
+
+// bounds parses the Start and End of the time window; a missing bound is the zero time
+func (w *TimeWindowFilter) bounds() (start, end time.Time, err error) {
+	if w.Start != "" {
+		if start, err = ParseFilterTime(w.Start); err != nil {
+			return start, end, fmt.Errorf("Start: %w", err)
+		}
+	}
+	if w.End != "" {
+		if end, err = ParseFilterTime(w.End); err != nil {
+			return start, end, fmt.Errorf("End: %w", err)
+		}
+	}
+	if w.Recurrence != nil && (w.Start == "" || w.End == "") {
+		return start, end, fmt.Errorf("a recurring time window needs a Start and an End")
+	}
+	return start, end, nil
+}
+
+// occurrences finds the start of the last occurrence that begins at or before until, whether
+// there is one, and whether more occurrences follow it
+func (r *Recurrence) occurrences(start, until time.Time) (last time.Time, found, more bool, err error) {
+	interval := r.Pattern.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+
+	var endDate time.Time
+	if strings.EqualFold(r.Range.Type, "EndDate") {
+		if endDate, err = ParseFilterTime(r.Range.EndDate); err != nil {
+			return last, false, false, fmt.Errorf("Recurrence.Range.EndDate: %w", err)
+		}
+	}
+	limit := 0
+	if strings.EqualFold(r.Range.Type, "Numbered") {
+		limit = r.Range.NumberOfOccurrences
+	}
+
+	weekly := strings.EqualFold(r.Pattern.Type, "Weekly")
+	days := make(map[time.Weekday]bool, len(r.Pattern.DaysOfWeek))
+	for _, name := range r.Pattern.DaysOfWeek {
+		if day, ok := parseWeekday(name); ok {
+			days[day] = true
+		}
+	}
+	if weekly && len(days) == 0 {
+		return last, false, false, nil
+	}
+
+	// Weeks are counted from the start of the week the window first opens in
+	firstDay := time.Sunday
+	if day, ok := parseWeekday(r.Pattern.FirstDayOfWeek); ok {
+		firstDay = day
+	}
+	weekOffset := (int(start.Weekday()) - int(firstDay) + 7) % 7
+
+	count := 0
+	for day := 0; ; day++ {
+		if weekly {
+			if !days[start.AddDate(0, 0, day).Weekday()] || ((day+weekOffset)/7)%interval != 0 {
+				continue
+			}
+		} else if day%interval != 0 {
+			continue
+		}
+
+		occurrence := start.AddDate(0, 0, day)
+		if !endDate.IsZero() && occurrence.After(endDate) {
+			return last, found, false, nil
+		}
+		if occurrence.After(until) {
+			return last, found, true, nil
+		}
+
+		last, found = occurrence, true
+		count++
+		if limit > 0 && count >= limit {
+			return last, found, false, nil
+		}
+	}
+}
+
+// validateRecurrence validates the recurrence of a time window that opens at start and closes at end
+func validateRecurrence(recurrence *Recurrence, start, end time.Time) error {
+	pattern := recurrence.Pattern
+	if pattern.Interval < 0 {
+		return fmt.Errorf("Recurrence.Pattern.Interval must be positive, got %d", pattern.Interval)
+	}
+	interval := pattern.Interval
+	if interval == 0 {
+		interval = 1
+	}
+
+	var period time.Duration
+	switch {
+	case strings.EqualFold(pattern.Type, "Daily"):
+		period = time.Duration(interval) * 24 * time.Hour
+	case strings.EqualFold(pattern.Type, "Weekly"):
+		period = time.Duration(interval) * 7 * 24 * time.Hour
+		if len(pattern.DaysOfWeek) == 0 {
+			return fmt.Errorf("Recurrence.Pattern.DaysOfWeek is required for a weekly recurrence")
+		}
+		for _, name := range pattern.DaysOfWeek {
+			if _, ok := parseWeekday(name); !ok {
+				return fmt.Errorf("Recurrence.Pattern.DaysOfWeek: %q is not a day of the week", name)
+			}
+		}
+		if _, ok := parseWeekday(pattern.FirstDayOfWeek); pattern.FirstDayOfWeek != "" && !ok {
+			return fmt.Errorf("Recurrence.Pattern.FirstDayOfWeek: %q is not a day of the week", pattern.FirstDayOfWeek)
+		}
+	default:
+		return fmt.Errorf("Recurrence.Pattern.Type must be Daily or Weekly, got %q", pattern.Type)
+	}
+	if end.Sub(start) > period {
+		return fmt.Errorf("time window cannot last longer than its recurrence interval")
+	}
+
+	recurrenceRange := recurrence.Range
+	switch {
+	case recurrenceRange.Type == "", strings.EqualFold(recurrenceRange.Type, "NoEnd"):
+	case strings.EqualFold(recurrenceRange.Type, "EndDate"):
+		endDate, err := ParseFilterTime(recurrenceRange.EndDate)
+		if err != nil {
+			return fmt.Errorf("Recurrence.Range.EndDate: %w", err)
+		}
+		if endDate.Before(start) {
+			return fmt.Errorf("Recurrence.Range.EndDate cannot be before Start")
+		}
+	case strings.EqualFold(recurrenceRange.Type, "Numbered"):
+		if recurrenceRange.NumberOfOccurrences <= 0 {
+			return fmt.Errorf("Recurrence.Range.NumberOfOccurrences must be positive, got %d", recurrenceRange.NumberOfOccurrences)
+		}
+	default:
+		return fmt.Errorf("Recurrence.Range.Type must be NoEnd, EndDate or Numbered, got %q", recurrenceRange.Type)
+	}
+
+	return nil
+}
+
+// describeRecurrence formats a recurrence for display, e.g. "every 2 weeks on Monday, Friday until ..."
+func describeRecurrence(recurrence *Recurrence) string {
+	pattern := recurrence.Pattern
+	unit := "day"
+	if strings.EqualFold(pattern.Type, "Weekly") {
+		unit = "week"
+	}
+
+	description := "every " + unit
+	if pattern.Interval > 1 {
+		description = fmt.Sprintf("every %d %ss", pattern.Interval, unit)
+	}
+	if len(pattern.DaysOfWeek) > 0 {
+		description += " on " + strings.Join(pattern.DaysOfWeek, ", ")
+	}
+
+	switch {
+	case strings.EqualFold(recurrence.Range.Type, "EndDate"):
+		description += " until " + recurrence.Range.EndDate
+	case strings.EqualFold(recurrence.Range.Type, "Numbered"):
+		description += fmt.Sprintf(", %d times", recurrence.Range.NumberOfOccurrences)
+	}
+	return description
+}
+
+// parseWeekday parses the English name of a day of the week
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, true
+		}
+	}
+	return 0, false
+}
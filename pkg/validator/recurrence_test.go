@@ -0,0 +1,91 @@
+package validator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindowFilter_ActiveAt(t *testing.T) {
+	// 1 January 2024 is a Monday
+	daily := &Recurrence{Pattern: RecurrencePattern{Type: "Daily", Interval: 2}, Range: RecurrenceRange{Type: "NoEnd"}}
+	weekly := &Recurrence{
+		Pattern: RecurrencePattern{Type: "Weekly", Interval: 2, DaysOfWeek: []string{"Monday", "Friday"}},
+		Range:   RecurrenceRange{Type: "Numbered", NumberOfOccurrences: 3},
+	}
+	untilDate := &Recurrence{Pattern: RecurrencePattern{Type: "Daily"}, Range: RecurrenceRange{Type: "EndDate", EndDate: "Wed, 03 Jan 2024 00:00:00 GMT"}}
+
+	tests := []struct {
+		name       string
+		recurrence *Recurrence
+		at         time.Time
+		expected   bool
+	}{
+		{"before the first occurrence", daily, time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), false},
+		{"inside the first occurrence", daily, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), true},
+		{"day between daily occurrences", daily, time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), false},
+		{"inside a later daily occurrence", daily, time.Date(2024, 1, 31, 16, 59, 0, 0, time.UTC), true},
+		{"after a daily occurrence", daily, time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC), false},
+		{"weekly on Friday", weekly, time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC), true},
+		{"weekly in the skipped week", weekly, time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC), false},
+		{"weekly third occurrence", weekly, time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC), true},
+		{"weekly after the last occurrence", weekly, time.Date(2024, 1, 19, 10, 0, 0, 0, time.UTC), false},
+		{"on the end date", untilDate, time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), true},
+		{"after the end date", untilDate, time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC), false},
+		{"without recurrence", nil, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window := &TimeWindowFilter{
+				Start:      "Mon, 01 Jan 2024 09:00:00 GMT",
+				End:        "Mon, 01 Jan 2024 17:00:00 GMT",
+				Recurrence: tt.recurrence,
+			}
+
+			result, err := window.ActiveAt(tt.at)
+			if err != nil {
+				t.Fatalf("ActiveAt() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("ActiveAt() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTimeWindowFilter_EndedAt(t *testing.T) {
+	at := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		window   TimeWindowFilter
+		expected bool
+	}{
+		{"past end", TimeWindowFilter{End: "Mon, 01 Jan 2024 17:00:00 GMT"}, true},
+		{"no end", TimeWindowFilter{Start: "Mon, 01 Jan 2024 09:00:00 GMT"}, false},
+		{"recurring without end", TimeWindowFilter{
+			Start: "Mon, 01 Jan 2024 09:00:00 GMT", End: "Mon, 01 Jan 2024 17:00:00 GMT",
+			Recurrence: &Recurrence{Pattern: RecurrencePattern{Type: "Daily"}},
+		}, false},
+		{"recurring past its end date", TimeWindowFilter{
+			Start: "Mon, 01 Jan 2024 09:00:00 GMT", End: "Mon, 01 Jan 2024 17:00:00 GMT",
+			Recurrence: &Recurrence{Pattern: RecurrencePattern{Type: "Daily"}, Range: RecurrenceRange{Type: "EndDate", EndDate: "Fri, 31 May 2024 00:00:00 GMT"}},
+		}, true},
+		{"recurring with occurrences left", TimeWindowFilter{
+			Start: "Mon, 01 Jan 2024 09:00:00 GMT", End: "Mon, 01 Jan 2024 17:00:00 GMT",
+			Recurrence: &Recurrence{Pattern: RecurrencePattern{Type: "Weekly", DaysOfWeek: []string{"Monday"}}, Range: RecurrenceRange{Type: "Numbered", NumberOfOccurrences: 100}},
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.window.EndedAt(at)
+			if err != nil {
+				t.Fatalf("EndedAt() error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("EndedAt() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FlagDetectionRules controls how boolean settings are recognised as feature flags by their key
+type FlagDetectionRules struct {
+	// Heuristics enables recognising flags by key pattern; real feature flags are always recognised
+	Heuristics bool
+	// Patterns are case-insensitive key globs, where * matches any characters
+	Patterns []string
+	// Truthy and Falsy are the case-insensitive values accepted as on and off
+	Truthy []string
+	Falsy  []string
+	// RequireDescription reports feature flags without a description
+	RequireDescription bool
+}
+
+// DefaultFlagDetectionRules returns the built-in flag detection rules
+func DefaultFlagDetectionRules() FlagDetectionRules {
+	return FlagDetectionRules{
+		Heuristics: true,
+		Patterns: []string{
+			"feature.*",
+			"flag.*",
+			"*.enabled",
+			"*.disabled",
+			"enable.*",
+			"disable.*",
+			"*.feature",
+			"*.flag",
+		},
+		Truthy:             []string{"true", "1", "yes", "on", "enabled"},
+		Falsy:              []string{"false", "0", "no", "off", "disabled"},
+		RequireDescription: true,
+	}
+}
+
+// compiledRules holds flag detection rules in the form used during validation
+type compiledRules struct {
+	heuristics         bool
+	patterns           []flagPattern
+	truthy             map[string]bool
+	falsy              map[string]bool
+	requireDescription bool
+}
+
+// flagPattern is a compiled key glob. Each * is a capture group, so the part of the key
+// it matched can become the flag name.
+type flagPattern struct {
+	re *regexp.Regexp
+	// inverted is true when the fixed part of the glob says the flag is disabled, as in disable.*
+	inverted bool
+}
+
+// compileRules compiles key globs and indexes the value vocabularies
+func compileRules(rules FlagDetectionRules) (*compiledRules, error) {
+	compiled := &compiledRules{
+		heuristics:         rules.Heuristics,
+		truthy:             make(map[string]bool, len(rules.Truthy)),
+		falsy:              make(map[string]bool, len(rules.Falsy)),
+		requireDescription: rules.RequireDescription,
+	}
+
+	for _, pattern := range rules.Patterns {
+		expr := "(?i)^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, "(.*)") + "$"
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feature flag pattern %q: %w", pattern, err)
+		}
+
+		affix := strings.ToLower(strings.Trim(strings.ReplaceAll(pattern, "*", ""), "._-:/"))
+		compiled.patterns = append(compiled.patterns, flagPattern{
+			re:       re,
+			inverted: affix == "disable" || affix == "disabled",
+		})
+	}
+
+	for _, value := range rules.Truthy {
+		compiled.truthy[strings.ToLower(value)] = true
+	}
+	for _, value := range rules.Falsy {
+		value = strings.ToLower(value)
+		if compiled.truthy[value] {
+			return nil, fmt.Errorf("feature flag value %q cannot be both truthy and falsy", value)
+		}
+		compiled.falsy[value] = true
+	}
+
+	return compiled, nil
+}
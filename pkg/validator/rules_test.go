@@ -0,0 +1,76 @@
+package validator
+
+import (
+	"testing"
+)
+
+func TestNewValidatorWithRules(t *testing.T) {
+	rules := DefaultFlagDetectionRules()
+	rules.Patterns = []string{"ff_*"}
+	rules.Truthy = []string{"on"}
+	rules.Falsy = []string{"off"}
+	rules.RequireDescription = false
+
+	v, err := NewValidatorWithRules(rules)
+	if err != nil {
+		t.Fatalf("NewValidatorWithRules() error = %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		value    string
+		expected ValueType
+	}{
+		{"ff_search", "ON", ValueTypeFeatureFlag},
+		{"FF_Checkout", "off", ValueTypeFeatureFlag},
+		{"ff_search", "true", ValueTypeRegular},
+		{"logging.enabled", "verbose", ValueTypeRegular},
+		{"feature.new_ui", "on", ValueTypeRegular},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			result, err := v.ValidateAndParseValue(tt.key, tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, result.Type)
+			}
+		})
+	}
+
+	// Without the description rule, real flags without a description are accepted
+	if _, err := v.ValidateAndParseValue(FeatureFlagKey("Beta"), `{"id":"Beta","enabled":true}`); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewValidatorWithRules_HeuristicsDisabled(t *testing.T) {
+	rules := DefaultFlagDetectionRules()
+	rules.Heuristics = false
+
+	v, err := NewValidatorWithRules(rules)
+	if err != nil {
+		t.Fatalf("NewValidatorWithRules() error = %v", err)
+	}
+
+	result, err := v.ValidateAndParseValue("feature.new_ui", "true")
+	if err != nil || result.Type != ValueTypeRegular {
+		t.Errorf("expected a regular value with heuristics disabled, got %v, %v", result, err)
+	}
+
+	result, err = v.ValidateAndParseValue(FeatureFlagKey("Beta"), `{"id":"Beta","description":"Beta","enabled":true}`)
+	if err != nil || result.Type != ValueTypeFeatureFlag {
+		t.Errorf("expected real feature flags to still be recognised, got %v, %v", result, err)
+	}
+}
+
+func TestNewValidatorWithRules_Conflict(t *testing.T) {
+	rules := DefaultFlagDetectionRules()
+	rules.Falsy = append(rules.Falsy, "yes")
+
+	if _, err := NewValidatorWithRules(rules); err == nil {
+		t.Errorf("expected an error for a value that is both truthy and falsy")
+	}
+}
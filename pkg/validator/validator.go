@@ -1,9 +1,9 @@
 package validator
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
-	"regexp"
 	"strings"
 )
 
@@ -23,11 +23,18 @@ type SpecialValue struct {
 	ParsedValue interface{}
 }
 
-// FeatureFlag represents a feature flag configuration
+// FeatureFlag represents a feature flag configuration in the feature management schema
 type FeatureFlag struct {
-	Description string `json:"description,omitempty"`
-	Enabled     bool   `json:"enabled"`
-	Conditions  map[string]interface{} `json:"conditions,omitempty"`
+	ID          string                 `json:"id"`
+	Description string                 `json:"description"`
+	Enabled     bool                   `json:"enabled"`
+	Conditions  FeatureFlagConditions  `json:"conditions"`
+	Variants    []Variant              `json:"variants,omitempty"`
+	Allocation  *Allocation            `json:"allocation,omitempty"`
+	Telemetry   *Telemetry             `json:"telemetry,omitempty"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // KeyVaultReference represents a Key Vault secret reference
@@ -37,19 +44,59 @@ type KeyVaultReference struct {
 	SecretVersion string
 }
 
+// SecretURI returns the canonical secret URI of the reference, the form App Configuration
+// stores as {"uri": ...}
+func (r *KeyVaultReference) SecretURI() string {
+	uri := fmt.Sprintf("%s/secrets/%s", r.VaultURL, r.SecretName)
+	if r.SecretVersion != "" {
+		uri += "/" + r.SecretVersion
+	}
+	return uri
+}
+
 // Validator handles validation of configuration values
-type Validator struct{}
+type Validator struct {
+	rules         *compiledRules
+	vaultSuffixes []string
+	pinning       VersionPinning
+}
 
-// NewValidator creates a new validator instance
+// NewValidator creates a new validator instance with the default flag detection rules
 func NewValidator() *Validator {
-	return &Validator{}
+	rules, _ := compileRules(DefaultFlagDetectionRules())
+	return &Validator{rules: rules, vaultSuffixes: DefaultVaultSuffixes}
+}
+
+// NewValidatorWithRules creates a new validator instance with custom flag detection rules
+func NewValidatorWithRules(rules FlagDetectionRules) (*Validator, error) {
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{rules: compiled, vaultSuffixes: DefaultVaultSuffixes}, nil
 }
 
 // ValidateAndParseValue analyzes a value and determines its type with validation
 func (v *Validator) ValidateAndParseValue(key, value string) (*SpecialValue, error) {
+	// Real feature flags are identified by their key prefix
+	if IsFeatureFlagSetting(key) {
+		ff, err := ParseFeatureFlagValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid feature flag: %w", err)
+		}
+		if validationErr := v.validateFeatureFlagSetting(key, ff); validationErr != nil {
+			return nil, fmt.Errorf("feature flag validation error: %w", validationErr)
+		}
+		return &SpecialValue{
+			Type:        ValueTypeFeatureFlag,
+			Original:    value,
+			ParsedValue: ff,
+		}, nil
+	}
+
 	// Check for Microsoft.KeyVault format first
 	if strings.HasPrefix(value, "@Microsoft.KeyVault(") {
-		kvRef, err := v.parseKeyVaultReference(value)
+		kvRef, err := v.ParseKeyVaultReference(value)
 		if err == nil {
 			// Additional validation for Key Vault reference
 			if validationErr := v.validateKeyVaultReference(kvRef); validationErr != nil {
@@ -66,9 +113,9 @@ func (v *Validator) ValidateAndParseValue(key, value string) (*SpecialValue, err
 		}
 	}
 
-	// Check for direct Key Vault URI format (must contain vault.azure.net)
-	if strings.HasPrefix(value, "https://") && strings.Contains(value, "vault.azure.net") {
-		kvRef, err := v.parseKeyVaultReference(value)
+	// Check for direct Key Vault URI format (must be on a vault host)
+	if IsVaultURI(value, v.vaultSuffixes) {
+		kvRef, err := v.ParseKeyVaultReference(value)
 		if err == nil {
 			// Additional validation for Key Vault reference
 			if validationErr := v.validateKeyVaultReference(kvRef); validationErr != nil {
@@ -106,33 +153,39 @@ func (v *Validator) ValidateAndParseValue(key, value string) (*SpecialValue, err
 	}, nil
 }
 
-// parseKeyVaultReference parses a Key Vault secret reference
-// Supports formats:
+// ParseKeyVaultReference parses a Key Vault reference in any of its documented forms:
 // - @Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/mysecret/version)
+// - @Microsoft.KeyVault(VaultName=myvault;SecretName=mysecret;SecretVersion=version)
 // - https://myvault.vault.azure.net/secrets/mysecret/version
-func (v *Validator) parseKeyVaultReference(value string) (*KeyVaultReference, error) {
+// Certificate URIs (/certificates/name) resolve to the secret that backs the certificate.
+func (v *Validator) ParseKeyVaultReference(value string) (*KeyVaultReference, error) {
 	// Check for Microsoft.KeyVault format
-	if strings.HasPrefix(value, "@Microsoft.KeyVault(") && strings.HasSuffix(value, ")") {
-		content := strings.TrimPrefix(strings.TrimSuffix(value, ")"), "@Microsoft.KeyVault(")
-		params := make(map[string]string)
-
-		// Parse parameters
-		for _, param := range strings.Split(content, ";") {
-			if parts := strings.SplitN(param, "=", 2); len(parts) == 2 {
-				params[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
-			}
+	if params, ok := keyVaultReferenceParams(value); ok {
+		if secretURI, ok := params["secreturi"]; ok {
+			return v.parseKeyVaultURI(secretURI)
 		}
 
-		secretURI, ok := params["SecretUri"]
-		if !ok {
-			return nil, fmt.Errorf("missing SecretUri in Key Vault reference")
+		vaultName, hasVault := params["vaultname"]
+		secretName, hasSecret := params["secretname"]
+		if !hasVault && !hasSecret {
+			return nil, fmt.Errorf("missing SecretUri or VaultName and SecretName in Key Vault reference")
+		}
+		if !isValidVaultName(vaultName) {
+			return nil, fmt.Errorf("invalid vault name: %s", vaultName)
+		}
+		if !v.isValidSecretName(secretName) {
+			return nil, fmt.Errorf("invalid secret name: %s", secretName)
 		}
 
-		return v.parseKeyVaultURI(secretURI)
+		return &KeyVaultReference{
+			VaultURL:      fmt.Sprintf("https://%s.%s", strings.ToLower(vaultName), v.vaultSuffixes[0]),
+			SecretName:    secretName,
+			SecretVersion: params["secretversion"],
+		}, nil
 	}
 
-	// Check for direct URI format - must be https and contain vault.azure.net
-	if strings.HasPrefix(value, "https://") && strings.Contains(value, "vault.azure.net") {
+	// Check for direct URI format - must be https on a vault host
+	if IsVaultURI(value, v.vaultSuffixes) {
 		return v.parseKeyVaultURI(value)
 	}
 
@@ -144,7 +197,25 @@ func (v *Validator) parseKeyVaultReference(value string) (*KeyVaultReference, er
 	return nil, fmt.Errorf("invalid Key Vault reference format")
 }
 
-// parseKeyVaultURI parses a Key Vault URI
+// keyVaultReferenceParams returns the parameters of an @Microsoft.KeyVault(...) reference,
+// keyed by their lower-cased names
+func keyVaultReferenceParams(value string) (map[string]string, bool) {
+	if !strings.HasPrefix(value, "@Microsoft.KeyVault(") || !strings.HasSuffix(value, ")") {
+		return nil, false
+	}
+
+	content := strings.TrimPrefix(strings.TrimSuffix(value, ")"), "@Microsoft.KeyVault(")
+	params := make(map[string]string)
+	for _, param := range strings.Split(content, ";") {
+		if parts := strings.SplitN(param, "=", 2); len(parts) == 2 {
+			params[strings.ToLower(strings.TrimSpace(parts[0]))] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return params, true
+}
+
+// parseKeyVaultURI parses a Key Vault secret or certificate URI
 func (v *Validator) parseKeyVaultURI(uri string) (*KeyVaultReference, error) {
 	parsedURL, err := url.Parse(uri)
 	if err != nil {
@@ -152,13 +223,20 @@ func (v *Validator) parseKeyVaultURI(uri string) (*KeyVaultReference, error) {
 	}
 
 	// Validate it's a Key Vault URL
-	if !strings.Contains(parsedURL.Host, "vault.azure.net") {
+	if !IsVaultURI(uri, v.vaultSuffixes) {
 		return nil, fmt.Errorf("not a valid Key Vault URI")
 	}
 
-	// Parse path: /secrets/{secret-name}/{secret-version}
+	// Parse path: /secrets/{secret-name}/{secret-version} or /certificates/{name}/{version}
 	pathParts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
-	if len(pathParts) < 2 || pathParts[0] != "secrets" {
+	if len(pathParts) < 2 {
+		return nil, fmt.Errorf("invalid Key Vault secret path")
+	}
+	switch pathParts[0] {
+	case "secrets", "certificates":
+	case "keys":
+		return nil, fmt.Errorf("Key Vault keys cannot be referenced; only secrets and certificates can")
+	default:
 		return nil, fmt.Errorf("invalid Key Vault secret path")
 	}
 
@@ -186,16 +264,17 @@ func (v *Validator) parseFeatureFlag(key, value string) (*FeatureFlag, error) {
 	if v.isFeatureFlagKey(key) {
 		// Parse boolean value
 		var enabled bool
-		switch strings.ToLower(value) {
-		case "true", "1", "yes", "on", "enabled":
+		switch normalized := strings.ToLower(value); {
+		case v.rules.truthy[normalized]:
 			enabled = true
-		case "false", "0", "no", "off", "disabled":
+		case v.rules.falsy[normalized]:
 			enabled = false
 		default:
 			return nil, fmt.Errorf("invalid feature flag value: %s", value)
 		}
 
 		return &FeatureFlag{
+			ID:          key,
 			Description: v.extractFeatureDescription(key),
 			Enabled:     enabled,
 		}, nil
@@ -206,20 +285,12 @@ func (v *Validator) parseFeatureFlag(key, value string) (*FeatureFlag, error) {
 
 // isFeatureFlagKey determines if a key represents a feature flag
 func (v *Validator) isFeatureFlagKey(key string) bool {
-	// Common feature flag patterns
-	patterns := []string{
-		`^feature\.`,
-		`^flag\.`,
-		`\.enabled$`,
-		`\.disabled$`,
-		`^enable\.`,
-		`^disable\.`,
-		`\.feature$`,
-		`\.flag$`,
+	if !v.rules.heuristics {
+		return false
 	}
 
-	for _, pattern := range patterns {
-		if matched, _ := regexp.MatchString(pattern, strings.ToLower(key)); matched {
+	for _, pattern := range v.rules.patterns {
+		if pattern.re.MatchString(key) {
 			return true
 		}
 	}
@@ -236,6 +307,25 @@ func (v *Validator) extractFeatureDescription(key string) string {
 	return description
 }
 
+// isValidVaultName validates a Key Vault name: 3-24 letters, digits and dashes, starting with a letter
+func isValidVaultName(name string) bool {
+	if len(name) < 3 || len(name) > 24 {
+		return false
+	}
+
+	for i, r := range name {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if i == 0 && !isLetter {
+			return false
+		}
+		if !isLetter && !(r >= '0' && r <= '9') && r != '-' {
+			return false
+		}
+	}
+
+	return !strings.HasSuffix(name, "-") && !strings.Contains(name, "--")
+}
+
 // isValidSecretName validates a Key Vault secret name
 func (v *Validator) isValidSecretName(name string) bool {
 	// Key Vault secret names must be 1-127 characters, alphanumeric and some special chars
@@ -282,6 +372,14 @@ func (v *Validator) ValidateConfiguration(config map[string]string) ([]Validatio
 						Type:    "keyvault_error",
 					})
 				}
+				if err := v.checkVersionPinning(kvRef); err != nil {
+					errors = append(errors, ValidationError{
+						Key:     key,
+						Value:   value,
+						Message: fmt.Sprintf("Key Vault version policy: %s", err.Error()),
+						Type:    ValidationTypeVersionPinning,
+					})
+				}
 			}
 		case ValueTypeFeatureFlag:
 			if ff, ok := specialValue.ParsedValue.(*FeatureFlag); ok {
@@ -303,7 +401,7 @@ func (v *Validator) ValidateConfiguration(config map[string]string) ([]Validatio
 // validateKeyVaultReference performs additional validation on Key Vault references
 func (v *Validator) validateKeyVaultReference(ref *KeyVaultReference) error {
 	// Validate vault URL format
-	if !strings.HasPrefix(ref.VaultURL, "https://") || !strings.Contains(ref.VaultURL, "vault.azure.net") {
+	if !IsVaultURI(ref.VaultURL, v.vaultSuffixes) {
 		return fmt.Errorf("invalid vault URL format")
 	}
 
@@ -318,7 +416,7 @@ func (v *Validator) validateKeyVaultReference(ref *KeyVaultReference) error {
 // validateFeatureFlag performs additional validation on feature flags
 func (v *Validator) validateFeatureFlag(ff *FeatureFlag) error {
 	// Feature flags should have a description
-	if ff.Description == "" {
+	if v.rules.requireDescription && ff.Description == "" {
 		return fmt.Errorf("feature flag should have a description")
 	}
 
@@ -331,9 +429,14 @@ type ValidationError struct {
 	Value   string
 	Message string
 	Type    string
+	// Location is where the value is defined in a local file, as file:line:column, when known
+	Location string
 }
 
 // Error implements the error interface
 func (e ValidationError) Error() string {
+	if e.Location != "" {
+		return fmt.Sprintf("%s: [%s] %s: %s", e.Location, e.Type, e.Key, e.Message)
+	}
 	return fmt.Sprintf("[%s] %s: %s", e.Type, e.Key, e.Message)
 }
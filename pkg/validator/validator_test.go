@@ -112,6 +112,45 @@ func TestParseKeyVaultReference(t *testing.T) {
 			expectedVersion: "v2",
 			hasError:        false,
 		},
+		{
+			name:            "VaultName format",
+			value:           "@Microsoft.KeyVault(VaultName=myvault;SecretName=mysecret;SecretVersion=v3)",
+			expectedVault:   "https://myvault.vault.azure.net",
+			expectedSecret:  "mysecret",
+			expectedVersion: "v3",
+			hasError:        false,
+		},
+		{
+			name:            "VaultName format without version, any case",
+			value:           "@Microsoft.KeyVault(vaultName=MyVault; secretName=mysecret)",
+			expectedVault:   "https://myvault.vault.azure.net",
+			expectedSecret:  "mysecret",
+			expectedVersion: "",
+			hasError:        false,
+		},
+		{
+			name:            "Certificate URI",
+			value:           "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/certificates/tls-cert/abc123)",
+			expectedVault:   "https://myvault.vault.azure.net",
+			expectedSecret:  "tls-cert",
+			expectedVersion: "abc123",
+			hasError:        false,
+		},
+		{
+			name:     "Key URI",
+			value:    "https://myvault.vault.azure.net/keys/signing-key",
+			hasError: true,
+		},
+		{
+			name:     "VaultName without SecretName",
+			value:    "@Microsoft.KeyVault(VaultName=myvault)",
+			hasError: true,
+		},
+		{
+			name:     "Invalid vault name",
+			value:    "@Microsoft.KeyVault(VaultName=my_vault;SecretName=mysecret)",
+			hasError: true,
+		},
 		{
 			name:     "Invalid format",
 			value:    "invalid-format",
@@ -126,7 +165,7 @@ func TestParseKeyVaultReference(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := v.parseKeyVaultReference(tt.value)
+			result, err := v.ParseKeyVaultReference(tt.value)
 
 			if tt.hasError {
 				if err == nil {
@@ -206,3 +245,116 @@ func TestValidateConfiguration(t *testing.T) {
 		t.Errorf("expected 0 validation errors, got %d: %v", len(errors), errors)
 	}
 }
+
+func TestValidateFeatureFlagSetting(t *testing.T) {
+	v := NewValidator()
+
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		hasError bool
+	}{
+		{
+			name:     "valid flag",
+			key:      ".appconfig.featureflag/Beta",
+			value:    `{"id":"Beta","description":"Beta features","enabled":true,"conditions":{"client_filters":[]}}`,
+			hasError: false,
+		},
+		{
+			name:     "flag without description",
+			key:      ".appconfig.featureflag/Beta",
+			value:    `{"id":"Beta","enabled":true}`,
+			hasError: false,
+		},
+		{
+			name:     "id does not match key",
+			key:      ".appconfig.featureflag/Beta",
+			value:    `{"id":"Other","description":"Beta features","enabled":true}`,
+			hasError: true,
+		},
+		{
+			name:     "invalid JSON",
+			key:      ".appconfig.featureflag/Beta",
+			value:    `true`,
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.ValidateAndParseValue(tt.key, tt.value)
+
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Type != ValueTypeFeatureFlag {
+				t.Errorf("expected type %s, got %s", ValueTypeFeatureFlag, result.Type)
+			}
+		})
+	}
+}
+
+func TestValidateConfiguration_ShorthandFeatureFlag(t *testing.T) {
+	v := NewValidator()
+
+	// {"featureFlags": {"Beta": true}} has no description, which is a warning and not a parsing error
+	ff, err := FeatureFlagFromLocal("Beta", true)
+	if err != nil {
+		t.Fatalf("FeatureFlagFromLocal() error = %v", err)
+	}
+	value, err := FormatFeatureFlagValue(ff)
+	if err != nil {
+		t.Fatalf("FormatFeatureFlagValue() error = %v", err)
+	}
+
+	errors, err := v.ValidateConfiguration(map[string]string{FeatureFlagPrefix + "Beta": value})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(errors) != 1 || errors[0].Type != "feature_flag_error" {
+		t.Errorf("ValidateConfiguration() = %v, expected a single feature_flag_error", errors)
+	}
+}
+
+func TestSameFeatureFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected bool
+	}{
+		{"different formatting", `{"id":"Beta","enabled":true}`, "{\n  \"enabled\": true,\n  \"id\": \"Beta\"\n}", true},
+		{"different content", `{"id":"Beta","enabled":true}`, `{"id":"Beta","enabled":false}`, false},
+		{"invalid values", `not a flag`, `not a flag`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := SameFeatureFlag(tt.a, tt.b); result != tt.expected {
+				t.Errorf("SameFeatureFlag() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeFeatureFlagValue(t *testing.T) {
+	stored := `{
+		"conditions": {"client_filters": []},
+		"enabled": true,
+		"id": "Beta",
+		"description": ""
+	}`
+	expected := `{"id":"Beta","description":"","enabled":true,"conditions":{"client_filters":[]}}`
+
+	if result := NormalizeFeatureFlagValue(stored); result != expected {
+		t.Errorf("NormalizeFeatureFlagValue() = %s, expected %s", result, expected)
+	}
+}
@@ -0,0 +1,577 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Variant is a named configuration that a feature flag serves to a cohort
+type Variant struct {
+	Name               string      `json:"name"`
+	ConfigurationValue interface{} `json:"configuration_value,omitempty"`
+	StatusOverride     string      `json:"status_override,omitempty"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Allocation decides which variant each user receives
+type Allocation struct {
+	DefaultWhenDisabled string                 `json:"default_when_disabled,omitempty"`
+	DefaultWhenEnabled  string                 `json:"default_when_enabled,omitempty"`
+	User                []UserAllocation       `json:"user,omitempty"`
+	Group               []GroupAllocation      `json:"group,omitempty"`
+	Percentile          []PercentileAllocation `json:"percentile,omitempty"`
+	Seed                string                 `json:"seed,omitempty"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UserAllocation assigns a variant to specific users
+type UserAllocation struct {
+	Variant string   `json:"variant"`
+	Users   []string `json:"users"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// GroupAllocation assigns a variant to members of groups
+type GroupAllocation struct {
+	Variant string   `json:"variant"`
+	Groups  []string `json:"groups"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// PercentileAllocation assigns a variant to the users whose percentile is in [From, To)
+type PercentileAllocation struct {
+	Variant string  `json:"variant"`
+	From    Percent `json:"from"`
+	To      Percent `json:"to"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// Telemetry controls whether evaluations of a flag are published
+type Telemetry struct {
+	Enabled  bool              `json:"enabled"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Extra holds fields this tool does not know about, so they survive a round-trip
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (ff *FeatureFlag) UnmarshalJSON(data []byte) error {
+	type fields FeatureFlag
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*ff = FeatureFlag(decoded)
+	ff.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (ff FeatureFlag) MarshalJSON() ([]byte, error) {
+	type fields FeatureFlag
+	return marshalWithExtra(fields(ff), ff.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (v *Variant) UnmarshalJSON(data []byte) error {
+	type fields Variant
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*v = Variant(decoded)
+	v.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (v Variant) MarshalJSON() ([]byte, error) {
+	type fields Variant
+	return marshalWithExtra(fields(v), v.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (a *Allocation) UnmarshalJSON(data []byte) error {
+	type fields Allocation
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*a = Allocation(decoded)
+	a.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (a Allocation) MarshalJSON() ([]byte, error) {
+	type fields Allocation
+	return marshalWithExtra(fields(a), a.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (u *UserAllocation) UnmarshalJSON(data []byte) error {
+	type fields UserAllocation
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*u = UserAllocation(decoded)
+	u.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (u UserAllocation) MarshalJSON() ([]byte, error) {
+	type fields UserAllocation
+	return marshalWithExtra(fields(u), u.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (g *GroupAllocation) UnmarshalJSON(data []byte) error {
+	type fields GroupAllocation
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*g = GroupAllocation(decoded)
+	g.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (g GroupAllocation) MarshalJSON() ([]byte, error) {
+	type fields GroupAllocation
+	return marshalWithExtra(fields(g), g.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (p *PercentileAllocation) UnmarshalJSON(data []byte) error {
+	type fields PercentileAllocation
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*p = PercentileAllocation(decoded)
+	p.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (p PercentileAllocation) MarshalJSON() ([]byte, error) {
+	type fields PercentileAllocation
+	return marshalWithExtra(fields(p), p.Extra)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, keeping unknown fields in Extra
+func (t *Telemetry) UnmarshalJSON(data []byte) error {
+	type fields Telemetry
+	var decoded fields
+	extra, err := unmarshalWithExtra(data, &decoded)
+	if err != nil {
+		return err
+	}
+	*t = Telemetry(decoded)
+	t.Extra = extra
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, writing Extra fields after the known ones in key order
+func (t Telemetry) MarshalJSON() ([]byte, error) {
+	type fields Telemetry
+	return marshalWithExtra(fields(t), t.Extra)
+}
+
+// unmarshalWithExtra decodes a JSON object into fields, a pointer to a struct, and returns the
+// members the struct has no field for
+func unmarshalWithExtra(data []byte, fields interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, fields); err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	// encoding/json matches member names to fields case-insensitively
+	known := make(map[string]bool)
+	structType := reflect.TypeOf(fields).Elem()
+	for i := 0; i < structType.NumField(); i++ {
+		name := structType.Field(i).Name
+		if tag := structType.Field(i).Tag.Get("json"); tag != "" {
+			name = strings.Split(tag, ",")[0]
+		}
+		known[strings.ToLower(name)] = true
+	}
+
+	var extra map[string]json.RawMessage
+	for name, value := range all {
+		if known[strings.ToLower(name)] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[name] = value
+	}
+	return extra, nil
+}
+
+// marshalWithExtra encodes fields, a struct, as a JSON object followed by the extra members in key order
+func marshalWithExtra(fields interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(fields)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buffer := strings.Builder{}
+	buffer.Write(data[:len(data)-1])
+	for i, name := range names {
+		nameJSON, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		valueJSON, err := json.Marshal(extra[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for field %s: %w", name, err)
+		}
+		if i > 0 || len(data) > 2 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(nameJSON)
+		buffer.WriteByte(':')
+		buffer.Write(valueJSON)
+	}
+	buffer.WriteByte('}')
+	return []byte(buffer.String()), nil
+}
+
+// validateVariants validates the variants, allocation and telemetry of a feature flag
+func (v *Validator) validateVariants(ff *FeatureFlag) error {
+	variants := make(map[string]bool, len(ff.Variants))
+	for _, variant := range ff.Variants {
+		if variant.Name == "" {
+			return fmt.Errorf("variant name cannot be empty")
+		}
+		if variants[variant.Name] {
+			return fmt.Errorf("variant %s is defined more than once", variant.Name)
+		}
+		variants[variant.Name] = true
+
+		switch strings.ToLower(variant.StatusOverride) {
+		case "", "none", "enabled", "disabled":
+		default:
+			return fmt.Errorf("variant %s: invalid status_override %q: expected None, Enabled or Disabled", variant.Name, variant.StatusOverride)
+		}
+	}
+
+	allocation := ff.Allocation
+	if allocation == nil {
+		return nil
+	}
+	if len(variants) == 0 {
+		return fmt.Errorf("allocation requires variants")
+	}
+
+	checkVariant := func(field, name string) error {
+		if name != "" && !variants[name] {
+			return fmt.Errorf("allocation %s refers to unknown variant %q", field, name)
+		}
+		return nil
+	}
+
+	if err := checkVariant("default_when_disabled", allocation.DefaultWhenDisabled); err != nil {
+		return err
+	}
+	if err := checkVariant("default_when_enabled", allocation.DefaultWhenEnabled); err != nil {
+		return err
+	}
+	for _, user := range allocation.User {
+		if err := checkVariant("user", user.Variant); err != nil {
+			return err
+		}
+	}
+	for _, group := range allocation.Group {
+		if err := checkVariant("group", group.Variant); err != nil {
+			return err
+		}
+	}
+
+	percentiles := append([]PercentileAllocation(nil), allocation.Percentile...)
+	sort.Slice(percentiles, func(i, j int) bool { return percentiles[i].From < percentiles[j].From })
+	for i, percentile := range percentiles {
+		if err := checkVariant("percentile", percentile.Variant); err != nil {
+			return err
+		}
+		if err := validatePercent("percentile from", percentile.From); err != nil {
+			return err
+		}
+		if err := validatePercent("percentile to", percentile.To); err != nil {
+			return err
+		}
+		if percentile.From >= percentile.To {
+			return fmt.Errorf("percentile %s: from must be below to", describePercentile(percentile))
+		}
+		if i > 0 && percentile.From < percentiles[i-1].To {
+			return fmt.Errorf("percentile %s overlaps %s", describePercentile(percentile), describePercentile(percentiles[i-1]))
+		}
+	}
+
+	return nil
+}
+
+// describePercentile formats a percentile allocation for display
+func describePercentile(percentile PercentileAllocation) string {
+	return fmt.Sprintf("%s–%s → %s", strings.TrimSuffix(percentile.From.String(), "%"), percentile.To, percentile.Variant)
+}
+
+// describeVariantChanges describes how the variants, allocation, telemetry and other fields of a flag changed
+func describeVariantChanges(oldFlag, newFlag *FeatureFlag) []string {
+	var changes []string
+
+	oldVariants := make(map[string]Variant, len(oldFlag.Variants))
+	for _, variant := range oldFlag.Variants {
+		oldVariants[variant.Name] = variant
+	}
+	newVariants := make(map[string]bool, len(newFlag.Variants))
+	for _, variant := range newFlag.Variants {
+		newVariants[variant.Name] = true
+		oldVariant, existed := oldVariants[variant.Name]
+		switch {
+		case !existed:
+			changes = append(changes, fmt.Sprintf("variant added: %s", variant.Name))
+		default:
+			if !reflect.DeepEqual(oldVariant.ConfigurationValue, variant.ConfigurationValue) {
+				changes = append(changes, fmt.Sprintf("variant %s: configuration value changed", variant.Name))
+			}
+			if !strings.EqualFold(oldVariant.StatusOverride, variant.StatusOverride) {
+				changes = append(changes, fmt.Sprintf("variant %s: status override: %s → %s",
+					variant.Name, displayStatusOverride(oldVariant.StatusOverride), displayStatusOverride(variant.StatusOverride)))
+			}
+			changes = append(changes, describeExtraChanges("variant "+variant.Name+": ", oldVariant.Extra, variant.Extra)...)
+		}
+	}
+	for _, variant := range oldFlag.Variants {
+		if !newVariants[variant.Name] {
+			changes = append(changes, fmt.Sprintf("variant removed: %s", variant.Name))
+		}
+	}
+
+	changes = append(changes, describeAllocationChanges(oldFlag.Allocation, newFlag.Allocation)...)
+	changes = append(changes, describeTelemetryChanges(oldFlag.Telemetry, newFlag.Telemetry)...)
+	changes = append(changes, describeExtraChanges("", oldFlag.Extra, newFlag.Extra)...)
+
+	return changes
+}
+
+// describeExtraChanges describes which fields unknown to this tool were added, removed or
+// changed, naming them after the given prefix
+func describeExtraChanges(prefix string, oldExtra, newExtra map[string]json.RawMessage) []string {
+	names := make([]string, 0, len(oldExtra)+len(newExtra))
+	for name := range oldExtra {
+		names = append(names, name)
+	}
+	for name := range newExtra {
+		if _, exists := oldExtra[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []string
+	for _, name := range names {
+		oldValue, hadOld := oldExtra[name]
+		newValue, hasNew := newExtra[name]
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("%s%s added", prefix, name))
+		case !hasNew:
+			changes = append(changes, fmt.Sprintf("%s%s removed", prefix, name))
+		case !jsonEqual(oldValue, newValue):
+			changes = append(changes, fmt.Sprintf("%s%s changed", prefix, name))
+		}
+	}
+	return changes
+}
+
+// describeAllocationChanges describes which users, groups and percentiles moved between variants
+func describeAllocationChanges(oldAllocation, newAllocation *Allocation) []string {
+	if oldAllocation == nil {
+		oldAllocation = &Allocation{}
+	}
+	if newAllocation == nil {
+		newAllocation = &Allocation{}
+	}
+
+	var changes []string
+	describe := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, fmt.Sprintf("allocation %s: %s → %s", field, displayVariant(oldValue), displayVariant(newValue)))
+		}
+	}
+
+	describe("default when disabled", oldAllocation.DefaultWhenDisabled, newAllocation.DefaultWhenDisabled)
+	describe("default when enabled", oldAllocation.DefaultWhenEnabled, newAllocation.DefaultWhenEnabled)
+
+	oldUsers, newUsers := make(map[string]string), make(map[string]string)
+	for _, user := range oldAllocation.User {
+		for _, name := range user.Users {
+			oldUsers[name] = user.Variant
+		}
+	}
+	for _, user := range newAllocation.User {
+		for _, name := range user.Users {
+			newUsers[name] = user.Variant
+		}
+	}
+	for _, name := range unionKeys(oldUsers, newUsers) {
+		describe("user "+name, oldUsers[name], newUsers[name])
+	}
+
+	oldGroups, newGroups := make(map[string]string), make(map[string]string)
+	for _, group := range oldAllocation.Group {
+		for _, name := range group.Groups {
+			oldGroups[name] = group.Variant
+		}
+	}
+	for _, group := range newAllocation.Group {
+		for _, name := range group.Groups {
+			newGroups[name] = group.Variant
+		}
+	}
+	for _, name := range unionKeys(oldGroups, newGroups) {
+		describe("group "+name, oldGroups[name], newGroups[name])
+	}
+
+	describePercentiles := func(percentiles []PercentileAllocation) string {
+		parts := make([]string, len(percentiles))
+		for i, percentile := range percentiles {
+			parts[i] = describePercentile(percentile)
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ", ")
+	}
+	if oldPercentiles, newPercentiles := describePercentiles(oldAllocation.Percentile), describePercentiles(newAllocation.Percentile); oldPercentiles != newPercentiles {
+		changes = append(changes, fmt.Sprintf("allocation percentiles: %s → %s", displayVariant(oldPercentiles), displayVariant(newPercentiles)))
+	}
+
+	if oldAllocation.Seed != newAllocation.Seed {
+		changes = append(changes, fmt.Sprintf("allocation seed: %q → %q", oldAllocation.Seed, newAllocation.Seed))
+	}
+
+	// Unknown fields of the user, group and percentile entries are compared entry by entry
+	for i := 0; i < len(oldAllocation.User) && i < len(newAllocation.User); i++ {
+		prefix := fmt.Sprintf("allocation user %s: ", newAllocation.User[i].Variant)
+		changes = append(changes, describeExtraChanges(prefix, oldAllocation.User[i].Extra, newAllocation.User[i].Extra)...)
+	}
+	for i := 0; i < len(oldAllocation.Group) && i < len(newAllocation.Group); i++ {
+		prefix := fmt.Sprintf("allocation group %s: ", newAllocation.Group[i].Variant)
+		changes = append(changes, describeExtraChanges(prefix, oldAllocation.Group[i].Extra, newAllocation.Group[i].Extra)...)
+	}
+	for i := 0; i < len(oldAllocation.Percentile) && i < len(newAllocation.Percentile); i++ {
+		prefix := fmt.Sprintf("allocation percentile %s: ", describePercentile(newAllocation.Percentile[i]))
+		changes = append(changes, describeExtraChanges(prefix, oldAllocation.Percentile[i].Extra, newAllocation.Percentile[i].Extra)...)
+	}
+
+	changes = append(changes, describeExtraChanges("allocation ", oldAllocation.Extra, newAllocation.Extra)...)
+
+	return changes
+}
+
+// describeTelemetryChanges describes changes to the telemetry settings of a flag
+func describeTelemetryChanges(oldTelemetry, newTelemetry *Telemetry) []string {
+	if oldTelemetry == nil {
+		oldTelemetry = &Telemetry{}
+	}
+	if newTelemetry == nil {
+		newTelemetry = &Telemetry{}
+	}
+
+	var changes []string
+	if oldTelemetry.Enabled != newTelemetry.Enabled {
+		changes = append(changes, fmt.Sprintf("telemetry: %t → %t", oldTelemetry.Enabled, newTelemetry.Enabled))
+	}
+
+	oldMetadata, newMetadata := oldTelemetry.Metadata, newTelemetry.Metadata
+	for _, name := range unionKeys(oldMetadata, newMetadata) {
+		oldValue, hadOld := oldMetadata[name]
+		newValue, hasNew := newMetadata[name]
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("telemetry metadata added: %s=%s", name, newValue))
+		case !hasNew:
+			changes = append(changes, fmt.Sprintf("telemetry metadata removed: %s", name))
+		case oldValue != newValue:
+			changes = append(changes, fmt.Sprintf("telemetry metadata %s: %q → %q", name, oldValue, newValue))
+		}
+	}
+	changes = append(changes, describeExtraChanges("telemetry ", oldTelemetry.Extra, newTelemetry.Extra)...)
+
+	return changes
+}
+
+// unionKeys returns the keys of both maps in sorted order
+func unionKeys(a, b map[string]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, exists := a[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonEqual compares two JSON values ignoring formatting
+func jsonEqual(a, b json.RawMessage) bool {
+	var aValue, bValue interface{}
+	if json.Unmarshal(a, &aValue) != nil || json.Unmarshal(b, &bValue) != nil {
+		return string(a) == string(b)
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// displayVariant formats an optional variant name for display
+func displayVariant(name string) string {
+	if name == "" {
+		return "(none)"
+	}
+	return name
+}
+
+// displayStatusOverride formats a status override, which defaults to None
+func displayStatusOverride(statusOverride string) string {
+	if statusOverride == "" {
+		return "None"
+	}
+	return statusOverride
+}
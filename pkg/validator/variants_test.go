@@ -0,0 +1,218 @@
+package validator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const variantFlag = `{"id":"Greeting","description":"Greeting copy","enabled":true,"conditions":{"client_filters":[]},` +
+	`"variants":[{"name":"Default","configuration_value":"Hello"},{"name":"Friendly","configuration_value":{"text":"Hi!"},"status_override":"Enabled"}],` +
+	`"allocation":{"default_when_enabled":"Default","user":[{"variant":"Friendly","users":["alice"]}],"group":[{"variant":"Friendly","groups":["beta"]}],` +
+	`"percentile":[{"variant":"Default","from":0,"to":50},{"variant":"Friendly","from":50,"to":100}],"seed":"greeting"},` +
+	`"telemetry":{"enabled":true,"metadata":{"owner":"web"}},"display_name":"Greeting text"}`
+
+func TestFeatureFlagVariantsRoundTrip(t *testing.T) {
+	ff, err := ParseFeatureFlagValue(variantFlag)
+	if err != nil {
+		t.Fatalf("ParseFeatureFlagValue() error = %v", err)
+	}
+
+	if len(ff.Variants) != 2 || ff.Allocation == nil || ff.Telemetry == nil {
+		t.Fatalf("expected variants, allocation and telemetry to be parsed, got %+v", ff)
+	}
+	if _, ok := ff.Extra["display_name"]; !ok {
+		t.Errorf("expected unknown field display_name to be kept, got %v", ff.Extra)
+	}
+
+	formatted, err := FormatFeatureFlagValue(ff)
+	if err != nil {
+		t.Fatalf("FormatFeatureFlagValue() error = %v", err)
+	}
+	if formatted != variantFlag {
+		t.Errorf("round-trip changed the flag:\n got %s\nwant %s", formatted, variantFlag)
+	}
+
+	// The local entry keeps everything but the id, and converts back to the same flag
+	local := FeatureFlagToLocal(ff)
+	entry, ok := local.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected an object entry, got %T", local)
+	}
+	for _, field := range []string{"variants", "allocation", "telemetry", "display_name"} {
+		if _, ok := entry[field]; !ok {
+			t.Errorf("expected local entry to keep %s", field)
+		}
+	}
+
+	back, err := FeatureFlagFromLocal("Greeting", entry)
+	if err != nil {
+		t.Fatalf("FeatureFlagFromLocal() error = %v", err)
+	}
+	if value, _ := FormatFeatureFlagValue(back); value != variantFlag {
+		t.Errorf("local round-trip changed the flag:\n got %s\nwant %s", value, variantFlag)
+	}
+}
+
+// nestedExtraFlag has a field unknown to this tool at every level of the flag
+const nestedExtraFlag = `{"id":"Checkout","description":"New checkout","enabled":true,` +
+	`"conditions":{"requirement_type":"All","client_filters":[` +
+	`{"name":"Microsoft.Percentage","parameters":{"Value":50,"Sticky":true},"note":"half"},` +
+	`{"name":"Microsoft.TimeWindow","parameters":{"Start":"Mon, 01 Jan 2024 00:00:00 GMT","TimeZone":"UTC"}},` +
+	`{"name":"Microsoft.Targeting","parameters":{"Audience":{"Users":["alice"],"DefaultRolloutPercentage":0,"Region":"eu"},"Priority":1}},` +
+	`{"name":"Custom.Region","parameters":{"Regions":["eu"]}}],"mode":"strict"},` +
+	`"variants":[{"name":"On","configuration_value":true,"weight":2}],` +
+	`"allocation":{"default_when_enabled":"On","user":[{"variant":"On","users":["alice"],"source":"crm"}],` +
+	`"group":[{"variant":"On","groups":["beta"],"source":"directory"}],` +
+	`"percentile":[{"variant":"On","from":0,"to":50,"bucket":"a"}],"strategy":"sticky"},` +
+	`"telemetry":{"enabled":true,"sink":"appinsights"},"display_name":"Checkout"}`
+
+func TestFeatureFlagNestedExtraRoundTrip(t *testing.T) {
+	ff, err := ParseFeatureFlagValue(nestedExtraFlag)
+	if err != nil {
+		t.Fatalf("ParseFeatureFlagValue() error = %v", err)
+	}
+
+	filters := ff.Conditions.ClientFilters
+	extras := map[string]map[string]json.RawMessage{
+		"conditions":         ff.Conditions.Extra,
+		"client filter":      filters[0].Extra,
+		"percentage filter":  filters[0].Percentage.Extra,
+		"time window filter": filters[1].TimeWindow.Extra,
+		"targeting filter":   filters[2].Targeting.Extra,
+		"targeting audience": filters[2].Targeting.Audience.Extra,
+		"variant":            ff.Variants[0].Extra,
+		"allocation":         ff.Allocation.Extra,
+		"user allocation":    ff.Allocation.User[0].Extra,
+		"group allocation":   ff.Allocation.Group[0].Extra,
+		"percentile":         ff.Allocation.Percentile[0].Extra,
+		"telemetry":          ff.Telemetry.Extra,
+		"flag":               ff.Extra,
+	}
+	for level, extra := range extras {
+		if len(extra) != 1 {
+			t.Errorf("%s extra = %v, expected the unknown field to be kept", level, extra)
+		}
+	}
+
+	formatted, err := FormatFeatureFlagValue(ff)
+	if err != nil {
+		t.Fatalf("FormatFeatureFlagValue() error = %v", err)
+	}
+	if formatted != nestedExtraFlag {
+		t.Errorf("round-trip changed the flag:\n got %s\nwant %s", formatted, nestedExtraFlag)
+	}
+
+	back, err := FeatureFlagFromLocal("Checkout", FeatureFlagToLocal(ff))
+	if err != nil {
+		t.Fatalf("FeatureFlagFromLocal() error = %v", err)
+	}
+	if value, _ := FormatFeatureFlagValue(back); value != nestedExtraFlag {
+		t.Errorf("local round-trip changed the flag:\n got %s\nwant %s", value, nestedExtraFlag)
+	}
+}
+
+func TestFeatureFlagChanges_NestedExtra(t *testing.T) {
+	newValue := strings.NewReplacer(`"Sticky":true`, `"Sticky":false`, `"Region":"eu"`, `"Region":"us"`,
+		`"weight":2`, `"weight":3`, `"source":"crm"`, `"source":"hr"`, `"bucket":"a"`, `"bucket":"b"`,
+		`"strategy":"sticky"`, `"strategy":"random"`, `"mode":"strict"`, `"mode":"loose"`).Replace(nestedExtraFlag)
+
+	expected := []string{
+		"Microsoft.Percentage: Sticky changed",
+		"Microsoft.Targeting: Audience.Region changed",
+		"conditions mode changed",
+		"variant On: weight changed",
+		"allocation user On: source changed",
+		"allocation percentile 0–50% → On: bucket changed",
+		"allocation strategy changed",
+	}
+
+	if changes := FeatureFlagChanges(nestedExtraFlag, newValue); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("FeatureFlagChanges() =\n%v\nexpected\n%v", changes, expected)
+	}
+}
+
+func TestValidateFeatureFlagVariants(t *testing.T) {
+	v := NewValidator()
+
+	tests := []struct {
+		name     string
+		fields   string
+		hasError bool
+	}{
+		{
+			name:   "variants with allocation",
+			fields: `"variants":[{"name":"A"},{"name":"B"}],"allocation":{"default_when_enabled":"A","percentile":[{"variant":"A","from":0,"to":50},{"variant":"B","from":50,"to":100}]}`,
+		},
+		{
+			name:     "duplicate variant",
+			fields:   `"variants":[{"name":"A"},{"name":"A"}]`,
+			hasError: true,
+		},
+		{
+			name:     "invalid status override",
+			fields:   `"variants":[{"name":"A","status_override":"Maybe"}]`,
+			hasError: true,
+		},
+		{
+			name:     "allocation without variants",
+			fields:   `"allocation":{"default_when_enabled":"A"}`,
+			hasError: true,
+		},
+		{
+			name:     "unknown variant",
+			fields:   `"variants":[{"name":"A"}],"allocation":{"user":[{"variant":"B","users":["alice"]}]}`,
+			hasError: true,
+		},
+		{
+			name:     "percentile out of range",
+			fields:   `"variants":[{"name":"A"}],"allocation":{"percentile":[{"variant":"A","from":0,"to":150}]}`,
+			hasError: true,
+		},
+		{
+			name:     "overlapping percentiles",
+			fields:   `"variants":[{"name":"A"},{"name":"B"}],"allocation":{"percentile":[{"variant":"A","from":0,"to":60},{"variant":"B","from":50,"to":100}]}`,
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := `{"id":"Flag","description":"A flag","enabled":true,"conditions":{"client_filters":[]},` + tt.fields + `}`
+			_, err := v.ValidateAndParseValue(FeatureFlagKey("Flag"), value)
+			if tt.hasError && err == nil {
+				t.Errorf("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagChanges_Variants(t *testing.T) {
+	oldValue := `{"id":"Greeting","enabled":true,"conditions":{"client_filters":[]},` +
+		`"variants":[{"name":"Default","configuration_value":"Hello"},{"name":"Old"}],` +
+		`"allocation":{"default_when_enabled":"Default","user":[{"variant":"Default","users":["alice"]}]},` +
+		`"telemetry":{"enabled":false},"display_name":"Greeting"}`
+	newValue := `{"id":"Greeting","enabled":true,"conditions":{"client_filters":[]},` +
+		`"variants":[{"name":"Default","configuration_value":"Hi"},{"name":"Friendly"}],` +
+		`"allocation":{"default_when_enabled":"Friendly","user":[{"variant":"Friendly","users":["alice","bob"]}]},` +
+		`"telemetry":{"enabled":true},"display_name":"Greeting text"}`
+
+	expected := []string{
+		"variant Default: configuration value changed",
+		"variant added: Friendly",
+		"variant removed: Old",
+		"allocation default when enabled: Default → Friendly",
+		"allocation user alice: Default → Friendly",
+		"allocation user bob: (none) → Friendly",
+		"telemetry: false → true",
+		"display_name changed",
+	}
+
+	if changes := FeatureFlagChanges(oldValue, newValue); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("FeatureFlagChanges() =\n%v\nexpected\n%v", changes, expected)
+	}
+}
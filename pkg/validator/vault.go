@@ -0,0 +1,150 @@
+package validator
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultVaultSuffixes are the Key Vault host suffixes of the public Azure cloud
+var DefaultVaultSuffixes = []string{"vault.azure.net"}
+
+// IsVaultURI reports whether a value is an https URI on a Key Vault host, that is a host
+// ending in one of the given suffixes, such as myvault.vault.azure.net
+func IsVaultURI(value string, suffixes []string) bool {
+	if !strings.HasPrefix(value, "https://") {
+		return false
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, suffix := range suffixes {
+		suffix = strings.ToLower(strings.TrimPrefix(suffix, "."))
+		if suffix != "" && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetVaultSuffixes sets the Key Vault host suffixes recognised in references, for vaults
+// outside the public Azure cloud
+func (v *Validator) SetVaultSuffixes(suffixes []string) {
+	v.vaultSuffixes = suffixes
+}
+
+// VersionPinning is the policy for pinning Key Vault references to a secret version
+type VersionPinning string
+
+const (
+	// PinningAllow accepts references with and without a version
+	PinningAllow VersionPinning = "allow"
+	// PinningRequired requires every reference to name a version, for reproducible deployments
+	PinningRequired VersionPinning = "require-pinned"
+	// PinningForbidden rejects versioned references, so that applications pick up rotated secrets
+	PinningForbidden VersionPinning = "forbid-pinned"
+)
+
+// ValidationTypeVersionPinning is the ValidationError type for version pinning policy violations
+const ValidationTypeVersionPinning = "keyvault_pinning"
+
+// ParseVersionPinning parses a version pinning policy; an empty policy allows both
+func ParseVersionPinning(policy string) (VersionPinning, error) {
+	switch VersionPinning(strings.ToLower(policy)) {
+	case "", PinningAllow:
+		return PinningAllow, nil
+	case PinningRequired:
+		return PinningRequired, nil
+	case PinningForbidden:
+		return PinningForbidden, nil
+	default:
+		return "", fmt.Errorf("invalid version pinning policy %q: expected allow, require-pinned or forbid-pinned", policy)
+	}
+}
+
+// SetVersionPinning sets the version pinning policy enforced by ValidateConfiguration
+func (v *Validator) SetVersionPinning(policy VersionPinning) {
+	v.pinning = policy
+}
+
+// VersionPinning returns the version pinning policy enforced by ValidateConfiguration
+func (v *Validator) VersionPinning() VersionPinning {
+	if v.pinning == "" {
+		return PinningAllow
+	}
+	return v.pinning
+}
+
+// checkVersionPinning enforces the version pinning policy on a Key Vault reference
+func (v *Validator) checkVersionPinning(ref *KeyVaultReference) error {
+	switch {
+	case v.pinning == PinningRequired && ref.SecretVersion == "":
+		return fmt.Errorf("secret %s must be pinned to a version", ref.SecretName)
+	case v.pinning == PinningForbidden && ref.SecretVersion != "":
+		return fmt.Errorf("secret %s must not be pinned to version %s, so that rotation takes effect", ref.SecretName, ref.SecretVersion)
+	default:
+		return nil
+	}
+}
+
+// SecretRotation reports whether two values reference the same Key Vault secret pinned to
+// different versions, and returns the secret name and both versions
+func SecretRotation(oldValue, newValue string) (secret, oldVersion, newVersion string, ok bool) {
+	oldVault, oldName, oldVersion, oldOK := splitSecretURI(oldValue)
+	newVault, newName, newVersion, newOK := splitSecretURI(newValue)
+	if !oldOK || !newOK || oldVersion == "" || newVersion == "" {
+		return "", "", "", false
+	}
+
+	if !strings.EqualFold(oldVault, newVault) || !strings.EqualFold(oldName, newName) || oldVersion == newVersion {
+		return "", "", "", false
+	}
+	return newName, oldVersion, newVersion, true
+}
+
+// SameSecretReference reports whether two values are Key Vault references, in any of the
+// documented forms, to the same secret and version
+func SameSecretReference(a, b string) bool {
+	aVault, aName, aVersion, aOK := splitSecretURI(a)
+	bVault, bName, bVersion, bOK := splitSecretURI(b)
+	return aOK && bOK && strings.EqualFold(aVault, bVault) && strings.EqualFold(aName, bName) && aVersion == bVersion
+}
+
+// splitSecretURI splits a Key Vault reference, in the @Microsoft.KeyVault(SecretUri=...),
+// @Microsoft.KeyVault(VaultName=...;SecretName=...) or direct URI format, into the vault
+// name, secret name and version
+func splitSecretURI(value string) (vault, name, version string, ok bool) {
+	uri := value
+	if params, isReference := keyVaultReferenceParams(value); isReference {
+		uri = params["secreturi"]
+		if uri == "" {
+			if params["vaultname"] == "" || params["secretname"] == "" {
+				return "", "", "", false
+			}
+			return params["vaultname"], params["secretname"], params["secretversion"], true
+		}
+	}
+
+	if !strings.HasPrefix(uri, "https://") {
+		return "", "", "", false
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", "", "", false
+	}
+
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(parts) < 2 || (parts[0] != "secrets" && parts[0] != "certificates") {
+		return "", "", "", false
+	}
+	if len(parts) > 2 {
+		version = parts[2]
+	}
+	vault, _, _ = strings.Cut(parsed.Host, ".")
+	return vault, parts[1], version, true
+}
@@ -0,0 +1,149 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsVaultURI(t *testing.T) {
+	tests := []struct {
+		value    string
+		suffixes []string
+		expected bool
+	}{
+		{"https://myvault.vault.azure.net/secrets/db", DefaultVaultSuffixes, true},
+		{"https://MyVault.Vault.Azure.Net/secrets/db", DefaultVaultSuffixes, true},
+		{"http://myvault.vault.azure.net/secrets/db", DefaultVaultSuffixes, false},
+		{"https://example.com/vault.azure.net", DefaultVaultSuffixes, false},
+		{"https://myvault.vault.azure.cn/secrets/db", DefaultVaultSuffixes, false},
+		{"https://myvault.vault.azure.cn/secrets/db", []string{"vault.azure.cn"}, true},
+		{"https://myvault.vault.usgovcloudapi.net", []string{"vault.azure.cn", ".vault.usgovcloudapi.net"}, true},
+	}
+
+	for _, tt := range tests {
+		if result := IsVaultURI(tt.value, tt.suffixes); result != tt.expected {
+			t.Errorf("IsVaultURI(%q, %v) = %v, expected %v", tt.value, tt.suffixes, result, tt.expected)
+		}
+	}
+}
+
+func TestValidator_SetVaultSuffixes(t *testing.T) {
+	v := NewValidator()
+	value := "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.cn/secrets/db-password)"
+
+	if _, err := v.ValidateAndParseValue("Db.Password", value); err == nil {
+		t.Fatal("expected Azure China vault to be rejected in the public cloud")
+	}
+
+	v.SetVaultSuffixes([]string{"vault.azure.cn"})
+	result, err := v.ValidateAndParseValue("Db.Password", value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Type != ValueTypeKeyVault {
+		t.Errorf("expected keyvault type, got %s", result.Type)
+	}
+
+	direct, err := v.ValidateAndParseValue("Db.Password", "https://myvault.vault.azure.cn/secrets/db-password")
+	if err != nil || direct.Type != ValueTypeKeyVault {
+		t.Errorf("expected direct Azure China URI to be a Key Vault reference, got %v, %v", direct, err)
+	}
+}
+
+func TestValidator_VersionPinning(t *testing.T) {
+	config := map[string]string{
+		"Db.Password": "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db-password/abc123)",
+		"Api.Key":     "https://myvault.vault.azure.net/secrets/api-key",
+	}
+
+	tests := []struct {
+		policy   string
+		expected []string
+	}{
+		{"", nil},
+		{"allow", nil},
+		{"require-pinned", []string{"Api.Key"}},
+		{"forbid-pinned", []string{"Db.Password"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			policy, err := ParseVersionPinning(tt.policy)
+			if err != nil {
+				t.Fatalf("ParseVersionPinning failed: %v", err)
+			}
+
+			v := NewValidator()
+			v.SetVersionPinning(policy)
+			errors, err := v.ValidateConfiguration(config)
+			if err != nil {
+				t.Fatalf("ValidateConfiguration failed: %v", err)
+			}
+
+			var violations []string
+			for _, validationErr := range errors {
+				if validationErr.Type == ValidationTypeVersionPinning {
+					violations = append(violations, validationErr.Key)
+				}
+			}
+			if !reflect.DeepEqual(violations, tt.expected) {
+				t.Errorf("expected violations %v, got %v", tt.expected, violations)
+			}
+		})
+	}
+
+	if _, err := ParseVersionPinning("sometimes"); err == nil {
+		t.Error("expected error for invalid policy")
+	}
+}
+
+func TestSecretRotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldValue string
+		newValue string
+		expected bool
+	}{
+		{"version change", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db/v1)", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db/v2)", true},
+		{"mixed formats", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db/v1)", "https://myvault.vault.azure.net/secrets/db/v2", true},
+		{"different secret", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db/v1)", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/api/v2)", false},
+		{"different vault", "@Microsoft.KeyVault(SecretUri=https://one.vault.azure.net/secrets/db/v1)", "@Microsoft.KeyVault(SecretUri=https://two.vault.azure.net/secrets/db/v2)", false},
+		{"pinning added", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db)", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db/v2)", false},
+		{"vault name format", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db/v1)", "@Microsoft.KeyVault(VaultName=myvault;SecretName=db;SecretVersion=v2)", true},
+		{"plain values", "one", "two", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret, oldVersion, newVersion, ok := SecretRotation(tt.oldValue, tt.newValue)
+			if ok != tt.expected {
+				t.Fatalf("SecretRotation() ok = %v, expected %v", ok, tt.expected)
+			}
+			if ok && (secret != "db" || oldVersion != "v1" || newVersion != "v2") {
+				t.Errorf("SecretRotation() = %s %s %s", secret, oldVersion, newVersion)
+			}
+		})
+	}
+}
+
+func TestSameSecretReference(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected bool
+	}{
+		{"vault name and secret uri", "@Microsoft.KeyVault(VaultName=MyVault;SecretName=db)", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db)", true},
+		{"certificate and secret uri", "https://myvault.vault.azure.net/certificates/tls/v1", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/tls/v1)", true},
+		{"different version", "@Microsoft.KeyVault(VaultName=myvault;SecretName=db;SecretVersion=v1)", "@Microsoft.KeyVault(SecretUri=https://myvault.vault.azure.net/secrets/db)", false},
+		{"plain values", "db", "db", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := SameSecretReference(tt.a, tt.b); result != tt.expected {
+				t.Errorf("SameSecretReference() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}